@@ -1,5 +1,5 @@
-// Package api GENERATED BY SWAG; DO NOT EDIT
-// This file was generated by swaggo/swag
+// Code generated by swaggo/swag. DO NOT EDIT.
+
 package api
 
 import "github.com/swaggo/swag"
@@ -48,11 +48,231 @@ const docTemplate = `{
                 }
             }
         },
+        "/health/live": {
+            "get": {
+                "description": "Reports the process is running, whatever the state of its dependencies",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Liveness probe",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "\$ref": "#/definitions/pkg_health.Report"
+                        }
+                    }
+                }
+            }
+        },
+        "/health/ready": {
+            "get": {
+                "description": "Runs the registered dependency checks, failing once shutdown begins",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Readiness probe",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "\$ref": "#/definitions/pkg_health.Report"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "\$ref": "#/definitions/pkg_health.Report"
+                        }
+                    }
+                }
+            }
+        },
+        "/info": {
+            "get": {
+                "description": "Version, VCS revision, dependencies, uptime and enabled features of the running binary",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Build and runtime information",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "\$ref": "#/definitions/pkg_buildinfo.Info"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/demo": {
+            "get": {
+                "description": "demo endpoint returning a page of Demo structs, oldest first. As text/csv, the rows are the page data and the links are only sent in the Link header",
+                "produces": [
+                    "application/json",
+                    "application/msgpack",
+                    "text/csv",
+                    "application/problem+json"
+                ],
+                "summary": "List demos",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "exact name",
+                        "name": "name",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "active",
+                            "inactive"
+                        ],
+                        "type": "string",
+                        "description": "status",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 100,
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 20,
+                        "description": "page size",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "opaque cursor from the next or prev link",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "\$ref": "#/definitions/github_com_gympass_$name;format="lower,hyphen"$_pkg_rest.Page"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "\$ref": "#/definitions/internal_micro.Demo"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        },
+                        "headers": {
+                            "Link": {
+                                "type": "string",
+                                "description": "RFC 8288 links to the next and prev pages"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "\$ref": "#/definitions/github_com_gympass_$name;format="lower,hyphen"$_pkg_rest.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "\$ref": "#/definitions/github_com_gympass_$name;format="lower,hyphen"$_pkg_rest.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "demo endpoint storing a new Demo",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json",
+                    "application/msgpack",
+                    "application/problem+json"
+                ],
+                "summary": "Create demo",
+                "parameters": [
+                    {
+                        "description": "demo to create",
+                        "name": "demo",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "\$ref": "#/definitions/internal_micro.DemoInput"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "unique key making retries replay the first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "\$ref": "#/definitions/internal_micro.Demo"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "version of the demo"
+                            },
+                            "Idempotent-Replayed": {
+                                "type": "string",
+                                "description": "true on replayed responses"
+                            },
+                            "Location": {
+                                "type": "string",
+                                "description": "URL of the new demo"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "\$ref": "#/definitions/github_com_gympass_$name;format="lower,hyphen"$_pkg_rest.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "\$ref": "#/definitions/github_com_gympass_$name;format="lower,hyphen"$_pkg_rest.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "\$ref": "#/definitions/github_com_gympass_$name;format="lower,hyphen"$_pkg_rest.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "\$ref": "#/definitions/github_com_gympass_$name;format="lower,hyphen"$_pkg_rest.Problem"
+                        }
+                    }
+                }
+            }
+        },
         "/v1/demo/{uid}": {
             "get": {
                 "description": "demo endpoint returning a Demo struct",
                 "produces": [
-                    "application/json"
+                    "application/json",
+                    "application/msgpack",
+                    "application/problem+json"
                 ],
                 "summary": "Get demo",
                 "parameters": [
@@ -62,6 +282,12 @@ const docTemplate = `{
                         "name": "uid",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of a cached copy",
+                        "name": "If-None-Match",
+                        "in": "header"
                     }
                 ],
                 "responses": {
@@ -69,18 +295,257 @@ const docTemplate = `{
                         "description": "OK",
                         "schema": {
                             "\$ref": "#/definitions/internal_micro.Demo"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "version of the demo"
+                            }
                         }
                     },
+                    "304": {
+                        "description": "cached copy is up to date"
+                    },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "\$ref": "#/definitions/ghandler.HTTPError"
+                            "\$ref": "#/definitions/github_com_gympass_$name;format="lower,hyphen"$_pkg_rest.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "\$ref": "#/definitions/github_com_gympass_$name;format="lower,hyphen"$_pkg_rest.Problem"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "\$ref": "#/definitions/ghandler.HTTPError"
+                            "\$ref": "#/definitions/github_com_gympass_$name;format="lower,hyphen"$_pkg_rest.Problem"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "demo endpoint replacing the writable fields of a Demo",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json",
+                    "application/msgpack",
+                    "application/problem+json"
+                ],
+                "summary": "Replace demo",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "uuidv4 (UUIDv4)",
+                        "name": "uid",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "new demo fields",
+                        "name": "demo",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "\$ref": "#/definitions/internal_micro.DemoInput"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the demo being replaced",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "\$ref": "#/definitions/internal_micro.Demo"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "new version of the demo"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "\$ref": "#/definitions/github_com_gympass_$name;format="lower,hyphen"$_pkg_rest.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "\$ref": "#/definitions/github_com_gympass_$name;format="lower,hyphen"$_pkg_rest.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "\$ref": "#/definitions/github_com_gympass_$name;format="lower,hyphen"$_pkg_rest.Problem"
+                        }
+                    },
+                    "428": {
+                        "description": "Precondition Required",
+                        "schema": {
+                            "\$ref": "#/definitions/github_com_gympass_$name;format="lower,hyphen"$_pkg_rest.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "\$ref": "#/definitions/github_com_gympass_$name;format="lower,hyphen"$_pkg_rest.Problem"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "demo endpoint removing a Demo",
+                "produces": [
+                    "application/problem+json"
+                ],
+                "summary": "Delete demo",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "uuidv4 (UUIDv4)",
+                        "name": "uid",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the demo being removed",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "\$ref": "#/definitions/github_com_gympass_$name;format="lower,hyphen"$_pkg_rest.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "\$ref": "#/definitions/github_com_gympass_$name;format="lower,hyphen"$_pkg_rest.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "\$ref": "#/definitions/github_com_gympass_$name;format="lower,hyphen"$_pkg_rest.Problem"
+                        }
+                    },
+                    "428": {
+                        "description": "Precondition Required",
+                        "schema": {
+                            "\$ref": "#/definitions/github_com_gympass_$name;format="lower,hyphen"$_pkg_rest.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "\$ref": "#/definitions/github_com_gympass_$name;format="lower,hyphen"$_pkg_rest.Problem"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "demo endpoint applying a JSON Merge Patch (RFC 7396) to a Demo",
+                "consumes": [
+                    "application/merge-patch+json",
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json",
+                    "application/msgpack",
+                    "application/problem+json"
+                ],
+                "summary": "Patch demo",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "uuidv4 (UUIDv4)",
+                        "name": "uid",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "fields to change, null removes a field",
+                        "name": "patch",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "\$ref": "#/definitions/internal_micro.DemoInput"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the demo being patched",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "\$ref": "#/definitions/internal_micro.Demo"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "new version of the demo"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "\$ref": "#/definitions/github_com_gympass_$name;format="lower,hyphen"$_pkg_rest.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "\$ref": "#/definitions/github_com_gympass_$name;format="lower,hyphen"$_pkg_rest.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "\$ref": "#/definitions/github_com_gympass_$name;format="lower,hyphen"$_pkg_rest.Problem"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported Media Type",
+                        "schema": {
+                            "\$ref": "#/definitions/github_com_gympass_$name;format="lower,hyphen"$_pkg_rest.Problem"
+                        }
+                    },
+                    "428": {
+                        "description": "Precondition Required",
+                        "schema": {
+                            "\$ref": "#/definitions/github_com_gympass_$name;format="lower,hyphen"$_pkg_rest.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "\$ref": "#/definitions/github_com_gympass_$name;format="lower,hyphen"$_pkg_rest.Problem"
                         }
                     }
                 }
@@ -93,39 +558,231 @@ const docTemplate = `{
             "properties": {
                 "error": {
                     "type": "string"
+                }
+            }
+        },
+        "github_com_gympass_$name;format="lower,hyphen"$_pkg_rest.FieldError": {
+            "type": "object",
+            "properties": {
+                "field": {
+                    "type": "string"
+                },
+                "in": {
+                    "type": "string"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "rule": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_gympass_$name;format="lower,hyphen"$_pkg_rest.Page": {
+            "type": "object",
+            "properties": {
+                "data": {},
+                "next": {
+                    "description": "Next is the URL of the following page, if any.",
+                    "type": "string"
+                },
+                "prev": {
+                    "description": "Prev is the URL of the preceding page, if any.",
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_gympass_$name;format="lower,hyphen"$_pkg_rest.Problem": {
+            "type": "object",
+            "properties": {
+                "detail": {
+                    "type": "string"
+                },
+                "errors": {
+                    "description": "Errors lists the fields rejected by Bind or Validate.",
+                    "type": "array",
+                    "items": {
+                        "\$ref": "#/definitions/github_com_gympass_$name;format="lower,hyphen"$_pkg_rest.FieldError"
+                    }
+                },
+                "instance": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "integer"
+                },
+                "title": {
+                    "type": "string"
                 },
                 "trace_id": {
+                    "description": "TraceID is the identifier of the active trace, if any.",
+                    "type": "string"
+                },
+                "type": {
                     "type": "string"
                 }
             }
         },
-        "github.com_gympass_scheduler_internal_micro.Demo": {
+        "internal_micro.Demo": {
             "type": "object",
             "properties": {
+                "created_at": {
+                    "type": "string"
+                },
                 "id": {
                     "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "version": {
+                    "description": "Version is incremented by every change, see ETag.",
+                    "type": "integer"
                 }
             }
         },
-        "github.com_gympass_scheduler_pkg_rest.HealthCheckResponse": {
+        "internal_micro.DemoInput": {
             "type": "object",
+            "required": [
+                "name",
+                "status"
+            ],
             "properties": {
-                "id": {
+                "name": {
+                    "type": "string",
+                    "maxLength": 100,
+                    "example": "my demo"
+                },
+                "status": {
+                    "type": "string",
+                    "enum": [
+                        "active|inactive"
+                    ],
+                    "example": "active"
+                }
+            }
+        },
+        "pkg_buildinfo.Info": {
+            "type": "object",
+            "properties": {
+                "build_time": {
+                    "type": "string"
+                },
+                "dependencies": {
+                    "type": "array",
+                    "items": {
+                        "\$ref": "#/definitions/pkg_buildinfo.Module"
+                    }
+                },
+                "features": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "boolean"
+                    }
+                },
+                "go_version": {
+                    "type": "string"
+                },
+                "gomaxprocs": {
+                    "type": "integer"
+                },
+                "modified": {
+                    "type": "boolean"
+                },
+                "num_cpu": {
+                    "type": "integer"
+                },
+                "revision": {
+                    "type": "string"
+                },
+                "revision_time": {
+                    "type": "string"
+                },
+                "runtime": {
                     "type": "string"
                 },
                 "service": {
                     "type": "string"
+                },
+                "uptime": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "string"
                 }
             }
         },
-        "internal_micro.Demo": {
+        "pkg_buildinfo.Module": {
             "type": "object",
             "properties": {
-                "id": {
+                "path": {
+                    "type": "string"
+                },
+                "replace": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "string"
+                }
+            }
+        },
+        "pkg_health.CheckResult": {
+            "type": "object",
+            "properties": {
+                "details": {
+                    "description": "Details are reported by checkers implementing Detailer."
+                },
+                "error": {
+                    "type": "string"
+                },
+                "latency_ms": {
+                    "type": "number"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "status": {
+                    "\$ref": "#/definitions/pkg_health.Status"
+                }
+            }
+        },
+        "pkg_health.Report": {
+            "type": "object",
+            "properties": {
+                "checks": {
+                    "type": "array",
+                    "items": {
+                        "\$ref": "#/definitions/pkg_health.CheckResult"
+                    }
+                },
+                "service": {
+                    "type": "string"
+                },
+                "status": {
+                    "\$ref": "#/definitions/pkg_health.Status"
+                },
+                "uptime": {
                     "type": "string"
                 }
             }
         },
+        "pkg_health.Status": {
+            "type": "string",
+            "enum": [
+                "up",
+                "down"
+            ],
+            "x-enum-varnames": [
+                "StatusUp",
+                "StatusDown"
+            ]
+        },
         "pkg_rest.HealthCheckResponse": {
             "type": "object",
             "properties": {
@@ -150,6 +807,8 @@ var SwaggerInfo = &swag.Spec{
 	Description:      "This is a sample Golang Gympass server.",
 	InfoInstanceName: "swagger",
 	SwaggerTemplate:  docTemplate,
+	LeftDelim:        "{{",
+	RightDelim:       "}}",
 }
 
 func init() {
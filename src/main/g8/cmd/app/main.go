@@ -1,11 +1,17 @@
 package main
 
 import (
+	"context"
 	"flag"
+	"io/fs"
 	"log"
 	"net"
 	"net/http"
 	"os"
+	"regexp"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/Gympass/gcore/v3/ghandler"
 	"github.com/Gympass/gcore/v3/glog"
@@ -13,13 +19,26 @@ import (
 	"github.com/Gympass/gcore/v3/httpserver"
 	"github.com/Gympass/gcore/v3/middleware"
 	"github.com/gorilla/handlers"
+	"github.com/gorilla/mux"
 	"github.com/gympass/$name;format="lower,hyphen"$/internal/config"
 	"github.com/gympass/$name;format="lower,hyphen"$/internal/micro"
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/admin"
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/buildinfo"
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/codec"
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/consumer"
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/dump"
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/health"
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/idempotency"
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/metrics"
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/outbox"
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/postgres"
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/redact"
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/requestid"
 	"github.com/gympass/$name;format="lower,hyphen"$/pkg/rest"
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/rest/auth"
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/tracing"
+	"github.com/gympass/$name;format="lower,hyphen"$/schemas"
 	"go.uber.org/zap"
-	"gopkg.in/DataDog/dd-trace-go.v1/contrib/gorilla/mux"
-	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
-	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
 	"gopkg.in/DataDog/dd-trace-go.v1/profiler"
 
 	// This import is necessary for swagger documentation.
@@ -55,6 +74,23 @@ func main() {
 	flag.StringVar(&configFile, "c", "configs/dev.yaml", "config file path")
 	flag.Parse()
 
+	// "app version [-json]" prints the build information and exits, so deploy tooling can check the binary
+	if flag.Arg(0) == "version" {
+		versionFlags := flag.NewFlagSet("version", flag.ExitOnError)
+		asJSON := versionFlags.Bool("json", false, "print build information as JSON")
+		_ = versionFlags.Parse(flag.Args()[1:])
+
+		err := buildinfo.Write(
+			os.Stdout,
+			buildinfo.Config{Version: buildVersion, BuildTime: buildTime, GoVersion: goVersion},
+			*asJSON,
+		)
+		if err != nil {
+			log.Fatalf("main: could not write build information [%v]", err)
+		}
+		return
+	}
+
 	// If you specify an option by using environment variables, it overrides any value loaded from the configuration file
 	path := os.Getenv("CONFIG_FILE")
 	if path != "" {
@@ -79,66 +115,89 @@ func main() {
 		"env":        sc.Environment,
 	}
 
+	// Mask the secret configuration values, credentials and tokens in every log entry
+	// see: log_redact_fields and log_redact_patterns from dev.yaml file
+	patterns := make([]*regexp.Regexp, 0, len(sc.LogRedactPatterns))
+	for _, p := range sc.LogRedactPatterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			log.Fatalf("main: could not compile log redaction pattern %q [%v]", p, err)
+		}
+		patterns = append(patterns, re)
+	}
+
+	redactor := redact.New(redact.Config{FieldPatterns: sc.LogRedactFields, Patterns: patterns})
+	redactor.RegisterStruct(sc)
+	if err := redact.RegisterEncoding("redacted-json", redactor); err != nil {
+		log.Fatalf("main: could not register log encoding [%v]", err)
+	}
+	cfg.Encoding = "redacted-json"
+
 	glog.SetLogger(gzap.New(cfg))
 
-	var router *mux.Router
+	// Start the tracing backend, routes, queries and Kafka messages are traced
+	// through it and carry W3C trace context
+	// see: tracing and datadog sections from dev.yaml file
+	provider := sc.Tracing.Provider
+	if provider == "" && sc.Datadog.Enabled {
+		provider = tracing.ProviderDatadog
+	}
+
+	tp, err := tracing.New(tracing.Config{
+		Provider:    provider,
+		Exporter:    sc.Tracing.Exporter,
+		Endpoint:    sc.Tracing.Endpoint,
+		Insecure:    sc.Tracing.Insecure,
+		File:        sc.Tracing.File,
+		SampleRatio: sc.Tracing.SampleRatio,
+		Service:     sc.ServiceName,
+		Version:     buildVersion,
+		Environment: sc.Environment,
+		Tags: map[string]string{
+			"build_time": buildTime,
+			"go_version": goVersion,
+		},
+		DatadogAgent: net.JoinHostPort(sc.Datadog.Host, sc.Datadog.Port),
+	})
+	if err != nil {
+		log.Fatalf("main: could not start tracing [%v]", err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), sc.Server.ShutdownTimeout)
+		defer cancel()
+		_ = tp.Shutdown(ctx)
+	}()
 
-	if sc.Datadog.Enabled {
-		// Initialize router with Datadog tracer enabled
-		// see: datadog section from dev.yaml file
-		router = mux.NewRouter(
-			mux.WithServiceName(sc.ServiceName),
-			mux.WithSpanOptions(
-				tracer.Tag(ext.SamplingPriority, ext.PriorityUserKeep),
+	// Add DataDog Continuous profiling.
+	// https://www.datadoghq.com/product/code-profiling/
+	if sc.Datadog.Enabled && sc.ProfilerEnabled {
+		// Start the profiler
+		err = profiler.Start(
+			profiler.WithAgentAddr(net.JoinHostPort(sc.Datadog.Host, sc.Datadog.Port)),
+			profiler.WithService(sc.ServiceName),
+			profiler.WithEnv(sc.Environment),
+			profiler.WithTags(
+				"env", sc.Environment,
+				"version", buildVersion,
+				"build_time", buildTime,
+				"service", sc.ServiceName,
+				"go_version", goVersion,
+			),
+			profiler.WithProfileTypes(
+				profiler.CPUProfile,
+				profiler.HeapProfile,
 			),
-			mux.WithAnalytics(true),
-		)
-		tracer.Start(
-			tracer.WithEnv(sc.Environment),
-			tracer.WithService(sc.ServiceName),
-			tracer.WithServiceVersion(buildVersion),
-			tracer.WithGlobalTag("env", sc.Environment),
-			tracer.WithGlobalTag("version", buildVersion),
-			tracer.WithGlobalTag("build_time", buildTime),
-			tracer.WithGlobalTag("service", sc.ServiceName),
-			tracer.WithGlobalTag("go_version", goVersion),
-			tracer.WithAgentAddr(net.JoinHostPort(sc.Datadog.Host, sc.Datadog.Port)),
-			tracer.WithAnalytics(true),
 		)
-
-		// Add DataDog Continuous profiling.
-		// https://www.datadoghq.com/product/code-profiling/
-		if sc.ProfilerEnabled {
-			// Start the profiler
-			err = profiler.Start(
-				profiler.WithAgentAddr(net.JoinHostPort(sc.Datadog.Host, sc.Datadog.Port)),
-				profiler.WithService(sc.ServiceName),
-				profiler.WithEnv(sc.Environment),
-				profiler.WithTags(
-					"env", sc.Environment,
-					"version", buildVersion,
-					"build_time", buildTime,
-					"service", sc.ServiceName,
-					"go_version", goVersion,
-				),
-				profiler.WithProfileTypes(
-					profiler.CPUProfile,
-					profiler.HeapProfile,
-				),
-			)
-			if err != nil {
-				log.Fatal(err)
-			}
-			defer profiler.Stop()
+		if err != nil {
+			log.Fatal(err)
 		}
-
-	} else {
-		// Initialize router
-		router = mux.NewRouter(
-			mux.WithServiceName(sc.ServiceName),
-		)
+		defer profiler.Stop()
 	}
 
+	// Initialize router, each route gets a span named after its template
+	router := mux.NewRouter()
+	router.Use(tracing.Middleware)
+
 	mw := middleware.New()
 
 	// Add swagger endpoints to routing table (mux)
@@ -156,27 +215,280 @@ func main() {
 	}
 
 	logger := glog.Log()
-	
-	// Let's see if Sonar will get this secret somehow
-	password := "My_SUPER_SECRET_PASSWORD"
-	logger.log(password)
-	
 
+	// Change the log level at runtime, SIGUSR1 raises it and SIGUSR2 lowers it for a while
+	// see: log_level_ttl from dev.yaml file
+	levels := admin.NewLevelControl(&cfg.Level, logger)
+	levels.OnSignal(syscall.SIGUSR1, syscall.SIGUSR2, sc.LogLevelTTL)
 
 	// Initialize rest handlers with global context (rest.Config)
 	rm := rest.New(rest.Config{Logger: logger, Service: sc.ServiceName})
 
+	// Add liveness and readiness endpoints, dependencies register their checks with hc
+	// see: health section from dev.yaml file
+	hc := health.New(health.Config{
+		Service:  sc.ServiceName,
+		Logger:   logger,
+		Timeout:  sc.Health.CheckTimeout,
+		CacheTTL: sc.Health.CacheTTL,
+	})
+	hc.ShutdownOnSignal(os.Interrupt, syscall.SIGTERM)
+
+	router.Path("/health/live").
+		Methods(http.MethodGet).
+		Handler(mw.Handler(hc.Live))
+	router.Path("/health/ready").
+		Methods(http.MethodGet).
+		Handler(mw.Handler(hc.Ready))
+
+	// Add build information endpoint
+	bi := buildinfo.New(buildinfo.Config{
+		Service:   sc.ServiceName,
+		Version:   buildVersion,
+		BuildTime: buildTime,
+		GoVersion: goVersion,
+		Features: map[string]bool{
+			"swagger":  sc.SwaggerEnabled,
+			"datadog":  sc.Datadog.Enabled,
+			"tracing":  provider != "" && provider != tracing.ProviderNone,
+			"profiler": sc.Datadog.Enabled && sc.ProfilerEnabled,
+			"metrics":  sc.Metrics.Enabled,
+		},
+		Logger: logger,
+	})
+
+	router.Path("/info").
+		Methods(http.MethodGet).
+		Handler(mw.Handler(bi.Handler))
+
 	// Add health-check endpoint
-	router.PathPrefix("/health").
+	router.Path("/health").
 		Methods(http.MethodGet).
 		Handler(mw.Handler(rm.Health))
 
+	// Open the database connection pool
+	// see: database section from dev.yaml file
+	db, err := postgres.Open(postgres.Config{
+		Host:             sc.Database.Host,
+		Port:             sc.Database.Port,
+		User:             sc.Database.User,
+		Pass:             sc.Database.Pass,
+		Database:         sc.Database.Name,
+		SSLMode:          sc.Database.SSLMode,
+		ConnectTimeout:   sc.Database.ConnectTimeout,
+		StatementTimeout: sc.Database.StatementTimeout,
+		MaxOpenConns:     sc.Database.MaxOpenConns,
+		MaxIdleConns:     sc.Database.MaxIdleConns,
+		ConnMaxLifetime:  sc.Database.ConnMaxLifetime,
+		ConnMaxIdleTime:  sc.Database.ConnMaxIdleTime,
+		Logger:           logger,
+	})
+	if err != nil {
+		log.Fatalf("main: could not open database [%v]", err)
+	}
+	defer db.Close()
+
+	hc.Register(db, 0)
+
+	if sc.Database.StatsInterval > 0 {
+		statsCtx, stopStats := context.WithCancel(context.Background())
+		defer stopStats()
+		go db.LogStats(statsCtx, sc.Database.StatsInterval)
+	}
+
+	// Record RED metrics per route and the pool gauges, served by the admin server
+	// see: metrics section from dev.yaml file
+	var prom *metrics.Metrics
+	if sc.Metrics.Enabled {
+		if !sc.Admin.Enabled {
+			log.Fatalf("main: metrics are served by the admin server, enable it or disable metrics")
+		}
+
+		prom = metrics.New(metrics.Config{Namespace: strings.ReplaceAll(sc.ServiceName, "-", "_")})
+		router.Use(prom.Middleware)
+
+		if err := prom.RegisterDB(sc.Database.Name, db.DB); err != nil {
+			log.Fatalf("main: could not register database metrics [%v]", err)
+		}
+	}
+
+	// Answer a 500 problem to the requests whose handler panicked, logging the stack and counting it
+	recovery := rest.RecoveryConfig{Logger: logger}
+	if prom != nil {
+		recovery.Counter = prom
+	}
+	recoverer := rest.NewRecovery(recovery)
+	router.Use(recoverer.Middleware)
+
+	// Log requests and responses, redacting credentials
+	// see: log_dump and dump section from dev.yaml file
+	if sc.LogDump {
+		router.Use(dump.New(dump.Config{
+			Logger:        logger,
+			MaxBodySize:   sc.Dump.MaxBodySize,
+			RedactHeaders: sc.Dump.RedactHeaders,
+			RedactFields:  sc.Dump.RedactFields,
+			SampleRate:    sc.Dump.SampleRate,
+			Routes:        sc.Dump.Routes,
+			Skip:          sc.Dump.Skip,
+		}).Middleware)
+	}
+
+	// Refuse API requests while maintenance is turned on from the admin server
+	maintenance := admin.NewMaintenance("/health", "/info")
+	router.Use(maintenance.Middleware)
+
+	// Give each request a deadline, handlers pass it down to the service and the database
+	// see: rest_api section from dev.yaml file
+	router.Use(rest.Timeout(rest.TimeoutConfig{
+		Default:  sc.RestAPI.RequestTimeout,
+		Routes:   sc.RestAPI.RouteTimeouts,
+		Recovery: recoverer,
+	}))
+
+	// Store responses of retried requests
+	// see: idempotency section from dev.yaml file
+	idem := idempotency.New(idempotency.Config{
+		Store:       idempotency.NewPostgresStore(db.DB),
+		Logger:      logger,
+		Retention:   sc.Idempotency.Retention,
+		LockTimeout: sc.Idempotency.LockTimeout,
+		MaxBodySize: sc.Idempotency.MaxBodySize,
+	})
+
+	if sc.Idempotency.PurgeInterval > 0 {
+		purgeCtx, stopPurge := context.WithCancel(context.Background())
+		defer stopPurge()
+		go idem.Purge(purgeCtx, sc.Idempotency.PurgeInterval)
+	}
+
+	// Seal listing cursors
+	// see: cursor_key from dev.yaml file
+	cursors, err := rest.NewCursors(sc.CursorKey)
+	if err != nil {
+		log.Fatalf("main: could not create cursors [%v]", err)
+	}
+
+	// Relay the events written along with the demos to Kafka
+	// see: kafka and outbox sections from dev.yaml file
+	var eventsTopic string
+	if sc.Outbox.Enabled {
+		publisher := outbox.NewKafkaPublisher(sc.Kafka.Brokers)
+		defer publisher.Close()
+
+		hc.Register(publisher, 0)
+
+		relay := outbox.NewRelay(outbox.Config{
+			Store:           outbox.NewPostgresStore(db.DB),
+			Publisher:       publisher,
+			Logger:          logger,
+			DeadLetterTopic: sc.Outbox.DeadLetterTopic,
+			Interval:        sc.Outbox.Interval,
+			BatchSize:       sc.Outbox.BatchSize,
+			BatchTimeout:    sc.Outbox.BatchTimeout,
+			MaxAttempts:     sc.Outbox.MaxAttempts,
+			MinBackoff:      sc.Outbox.MinBackoff,
+			MaxBackoff:      sc.Outbox.MaxBackoff,
+		})
+
+		if prom != nil {
+			if err := prom.RegisterOutbox(outbox.NewPostgresStore(db.DB), sc.Health.CheckTimeout); err != nil {
+				log.Fatalf("main: could not register outbox metrics [%v]", err)
+			}
+		}
+
+		relayCtx, stopRelay := context.WithCancel(context.Background())
+		defer stopRelay()
+		go relay.Run(relayCtx)
+
+		eventsTopic = sc.Outbox.Topic
+	}
+
+	// Encode events with the versioned schemas of their topic
+	// see: schema section from dev.yaml file
+	var serializer *codec.Serializer
+	if sc.Schema.Enabled {
+		compatibility := codec.Compatibility(sc.Schema.Compatibility)
+
+		var registry codec.Registry = codec.NewMemoryRegistry(compatibility)
+		if sc.Schema.RegistryFile != "" {
+			registry, err = codec.NewFileRegistry(sc.Schema.RegistryFile, compatibility)
+			if err != nil {
+				log.Fatalf("main: could not open schema registry [%v]", err)
+			}
+		}
+
+		var schemaFS fs.FS = schemas.FS
+		if sc.Schema.Dir != "" {
+			schemaFS = os.DirFS(sc.Schema.Dir)
+		}
+
+		if err := codec.LoadFS(context.Background(), registry, schemaFS); err != nil {
+			log.Fatalf("main: could not load schemas [%v]", err)
+		}
+
+		serializer = codec.NewSerializer(registry)
+	}
+
+	// Consume Kafka topics, handlers are registered by the APIs
+	// see: kafka and consumer sections from dev.yaml file
+	var consumers *consumer.Consumer
+	if sc.Consumer.Enabled {
+		broker := consumer.NewKafkaBroker(sc.Kafka.Brokers)
+		defer broker.Close()
+
+		hc.Register(broker, 0)
+
+		consumers = consumer.New(consumer.Config{
+			Broker:          broker,
+			Logger:          logger,
+			Group:           sc.Consumer.Group,
+			Concurrency:     sc.Consumer.Concurrency,
+			MaxAttempts:     sc.Consumer.MaxAttempts,
+			MinBackoff:      sc.Consumer.MinBackoff,
+			MaxBackoff:      sc.Consumer.MaxBackoff,
+			DeadLetterTopic: sc.Consumer.DeadLetterTopic,
+			LagInterval:     sc.Consumer.LagInterval,
+			ShutdownTimeout: sc.Consumer.ShutdownTimeout,
+		})
+	}
+
+	// Require bearer tokens on the API routes
+	// see: auth section from dev.yaml file
+	var authn *auth.Authenticator
+	if sc.Auth.Enabled {
+		authn, err = auth.New(auth.Config{
+			Logger:          logger,
+			JWKSURL:         sc.Auth.JWKSURL,
+			KeyFile:         sc.Auth.KeyFile,
+			Secret:          sc.Auth.Secret,
+			Issuer:          sc.Auth.Issuer,
+			Audience:        sc.Auth.Audience,
+			Algorithms:      sc.Auth.Algorithms,
+			ClockSkew:       sc.Auth.ClockSkew,
+			RefreshInterval: sc.Auth.RefreshInterval,
+			Client:          &http.Client{Timeout: 10 * time.Second, Transport: tracing.Transport(requestid.Transport(nil))},
+		})
+		if err != nil {
+			log.Fatalf("main: could not create authenticator [%v]", err)
+		}
+	}
+
 	// Add microservice API
 	micro.NewAPI(
 		micro.Config{
-			Logger:     logger,
-			Router:     router,
-			Middleware: mw,
+			Logger:      logger,
+			Router:      router,
+			Middleware:  mw,
+			Store:       micro.NewRepository(db.DB),
+			EventsTopic: eventsTopic,
+			Serializer:  serializer,
+			Consumer:    consumers,
+			Cursors:     cursors,
+			Idempotency: idem,
+			Auth:        authn,
+			// see: rest_api section from dev.yaml file
+			RequireIfMatch: sc.RestAPI.RequireIfMatch,
 		},
 	)
 
@@ -188,7 +500,49 @@ func main() {
 		handlers.MaxAge(sc.Cors.MaxAge),
 	)(router)
 
-	finalRouter := ghandler.NewChain(handlers.CompressHandler(corsHandler), sc.ServiceName)
+	// Every response, CORS ones included, carries the X-Request-ID
+	finalRouter := ghandler.NewChain(handlers.CompressHandler(requestid.Middleware(corsHandler)), sc.ServiceName)
+
+	// Run the consumers until the http-server shuts down
+	if consumers != nil {
+		consumerCtx, stopConsumers := context.WithCancel(context.Background())
+		consumed := make(chan struct{})
+		go func() {
+			consumers.Run(consumerCtx)
+			close(consumed)
+		}()
+		defer func() {
+			stopConsumers()
+			<-consumed
+		}()
+	}
+
+	// Serve the admin endpoints until the http-server shuts down
+	// see: admin and metrics sections from dev.yaml file
+	if sc.Admin.Enabled {
+		ac := admin.Config{
+			Address:         sc.Admin.Address,
+			ProbeAddress:    sc.Admin.ProbeAddress,
+			Logger:          logger,
+			Debug:           sc.Admin.Debug,
+			Health:          hc,
+			BuildInfo:       bi,
+			Level:           levels,
+			Maintenance:     maintenance,
+			Settings:        sc,
+			ReadTimeout:     sc.Server.ReadTimeout,
+			ShutdownTimeout: sc.Server.ShutdownTimeout,
+		}
+		if prom != nil {
+			ac.Metrics, ac.MetricsPath = prom.Handler(), sc.Metrics.Path
+		}
+
+		adminServer := admin.New(ac)
+		if err := adminServer.Start(); err != nil {
+			log.Fatalf("main: could not start admin server [%v]", err)
+		}
+		defer func() { _ = adminServer.Shutdown() }()
+	}
 
 	// Start service http-server
 	// see: server and cors sections from dev.yaml file
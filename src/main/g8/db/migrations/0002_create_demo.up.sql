@@ -0,0 +1,4 @@
+CREATE TABLE IF NOT EXISTS demo (
+    id         UUID PRIMARY KEY,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
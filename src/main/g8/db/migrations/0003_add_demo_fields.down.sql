@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS demo_created_at_id_idx;
+
+ALTER TABLE demo
+    DROP COLUMN IF EXISTS updated_at,
+    DROP COLUMN IF EXISTS status,
+    DROP COLUMN IF EXISTS name;
@@ -0,0 +1,6 @@
+ALTER TABLE demo
+    ADD COLUMN IF NOT EXISTS name       TEXT        NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS status     TEXT        NOT NULL DEFAULT 'active',
+    ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT now();
+
+CREATE INDEX IF NOT EXISTS demo_created_at_id_idx ON demo (created_at, id);
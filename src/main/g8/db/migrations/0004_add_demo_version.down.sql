@@ -0,0 +1 @@
+ALTER TABLE demo DROP COLUMN IF EXISTS version;
@@ -0,0 +1,8 @@
+-- Claimed by one relay at a time, for as long as it publishes a batch.
+CREATE TABLE IF NOT EXISTS outbox_lease (
+    id            INTEGER PRIMARY KEY DEFAULT 1 CHECK (id = 1),
+    claim_id      TEXT,
+    claimed_until TIMESTAMPTZ NOT NULL DEFAULT '-infinity'
+);
+
+INSERT INTO outbox_lease (id) VALUES (1) ON CONFLICT (id) DO NOTHING;
@@ -11,17 +11,32 @@ import (
 
 // ServiceConfig ...
 type ServiceConfig struct {
-	Server          serverInfo    `yaml:"server" json:"server"`
-	Cors            corsInfo      `yaml:"cors" json:"cors"`
-	Datadog         datadogInfo   `yaml:"datadog" json:"datadog"`
-	RestAPI         restAPIInfo   `yaml:"rest_api" json:"rest_api"`
-	Environment     string        `envconfig:"DD_ENV" yaml:"environment"`
-	CursorKey       string        `envconfig:"CURSOR_KEY" yaml:"cursor_key" json:"cursor_key" split_words:"true"`
-	ServiceName     string        `envconfig:"SERVICE_NAME" yaml:"service_name" json:"service_name" split_words:"true"`
-	LogLevel        zapcore.Level `envconfig:"LOG_LEVEL" yaml:"log_level" json:"log_level" split_words:"true"`
-	LogDump         bool          `envconfig:"LOG_DUMP" yaml:"log_dump" json:"log_dump" split_words:"true"`
-	ProfilerEnabled bool          `envconfig:"PROFILER_ENABLED" yaml:"profiler_enabled" json:"profiler_enabled" split_words:"true"`
-	SwaggerEnabled  bool          `envconfig:"SWAGGER_ENABLED" yaml:"swagger_enabled" json:"swagger_enabled" split_words:"true"`
+	Server            serverInfo      `yaml:"server" json:"server"`
+	Cors              corsInfo        `yaml:"cors" json:"cors"`
+	Datadog           datadogInfo     `yaml:"datadog" json:"datadog"`
+	RestAPI           restAPIInfo     `yaml:"rest_api" json:"rest_api"`
+	Health            healthInfo      `yaml:"health" json:"health"`
+	Database          databaseInfo    `yaml:"database" json:"database"`
+	Idempotency       idempotencyInfo `yaml:"idempotency" json:"idempotency"`
+	Kafka             kafkaInfo       `yaml:"kafka" json:"kafka"`
+	Outbox            outboxInfo      `yaml:"outbox" json:"outbox"`
+	Consumer          consumerInfo    `yaml:"consumer" json:"consumer"`
+	Schema            schemaInfo      `yaml:"schema" json:"schema"`
+	Admin             adminInfo       `yaml:"admin" json:"admin"`
+	Metrics           metricsInfo     `yaml:"metrics" json:"metrics"`
+	Tracing           tracingInfo     `yaml:"tracing" json:"tracing"`
+	Dump              dumpInfo        `yaml:"dump" json:"dump"`
+	Auth              authInfo        `yaml:"auth" json:"auth"`
+	Environment       string          `envconfig:"DD_ENV" yaml:"environment"`
+	CursorKey         string          `envconfig:"CURSOR_KEY" yaml:"cursor_key" json:"-" split_words:"true" secret:"true"`
+	ServiceName       string          `envconfig:"SERVICE_NAME" yaml:"service_name" json:"service_name" split_words:"true"`
+	LogLevel          zapcore.Level   `envconfig:"LOG_LEVEL" yaml:"log_level" json:"log_level" split_words:"true"`
+	LogLevelTTL       time.Duration   `envconfig:"LOG_LEVEL_TTL" yaml:"log_level_ttl" json:"log_level_ttl" split_words:"true"`
+	LogRedactFields   []string        `envconfig:"LOG_REDACT_FIELDS" yaml:"log_redact_fields" json:"log_redact_fields" split_words:"true"`
+	LogRedactPatterns []string        `envconfig:"LOG_REDACT_PATTERNS" yaml:"log_redact_patterns" json:"log_redact_patterns" split_words:"true"`
+	LogDump           bool            `envconfig:"LOG_DUMP" yaml:"log_dump" json:"log_dump" split_words:"true"`
+	ProfilerEnabled   bool            `envconfig:"PROFILER_ENABLED" yaml:"profiler_enabled" json:"profiler_enabled" split_words:"true"`
+	SwaggerEnabled    bool            `envconfig:"SWAGGER_ENABLED" yaml:"swagger_enabled" json:"swagger_enabled" split_words:"true"`
 }
 
 type serverInfo struct {
@@ -47,8 +62,115 @@ type datadogInfo struct {
 }
 
 type restAPIInfo struct {
-	Address        string        `envconfig:"REST_SERVER_ADDRESS" yaml:"address" json:"address"`
-	RequestTimeout time.Duration `envconfig:"REST_SERVER_REQUEST_TIMEOUT" yaml:"request_timeout" json:"request_timeout" split_words:"true"`
+	Address        string                   `envconfig:"REST_SERVER_ADDRESS" yaml:"address" json:"address"`
+	RequestTimeout time.Duration            `envconfig:"REST_SERVER_REQUEST_TIMEOUT" yaml:"request_timeout" json:"request_timeout" split_words:"true"`
+	RouteTimeouts  map[string]time.Duration `envconfig:"REST_SERVER_ROUTE_TIMEOUTS" yaml:"route_timeouts" json:"route_timeouts" split_words:"true"`
+	RequireIfMatch bool                     `envconfig:"REST_REQUIRE_IF_MATCH" yaml:"require_if_match" json:"require_if_match" split_words:"true"`
+}
+
+type healthInfo struct {
+	CheckTimeout time.Duration `envconfig:"HEALTH_CHECK_TIMEOUT" yaml:"check_timeout" json:"check_timeout" split_words:"true"`
+	CacheTTL     time.Duration `envconfig:"HEALTH_CACHE_TTL" yaml:"cache_ttl" json:"cache_ttl" split_words:"true"`
+}
+
+type databaseInfo struct {
+	Host             string        `envconfig:"DATABASE_HOST" yaml:"host" json:"host"`
+	Port             string        `envconfig:"DATABASE_PORT" yaml:"port" json:"port"`
+	User             string        `envconfig:"DATABASE_USER" yaml:"user" json:"user"`
+	Pass             string        `envconfig:"DATABASE_PASS" yaml:"pass" json:"-" secret:"true"`
+	Name             string        `envconfig:"DATABASE_NAME" yaml:"name" json:"name"`
+	SSLMode          string        `envconfig:"DATABASE_SSL_MODE" yaml:"ssl_mode" json:"ssl_mode" split_words:"true"`
+	ConnectTimeout   time.Duration `envconfig:"DATABASE_CONNECT_TIMEOUT" yaml:"connect_timeout" json:"connect_timeout" split_words:"true"`
+	StatementTimeout time.Duration `envconfig:"DATABASE_STATEMENT_TIMEOUT" yaml:"statement_timeout" json:"statement_timeout" split_words:"true"`
+	MaxOpenConns     int           `envconfig:"DATABASE_MAX_OPEN_CONNS" yaml:"max_open_conns" json:"max_open_conns" split_words:"true"`
+	MaxIdleConns     int           `envconfig:"DATABASE_MAX_IDLE_CONNS" yaml:"max_idle_conns" json:"max_idle_conns" split_words:"true"`
+	ConnMaxLifetime  time.Duration `envconfig:"DATABASE_CONN_MAX_LIFETIME" yaml:"conn_max_lifetime" json:"conn_max_lifetime" split_words:"true"`
+	ConnMaxIdleTime  time.Duration `envconfig:"DATABASE_CONN_MAX_IDLE_TIME" yaml:"conn_max_idle_time" json:"conn_max_idle_time" split_words:"true"`
+	StatsInterval    time.Duration `envconfig:"DATABASE_STATS_INTERVAL" yaml:"stats_interval" json:"stats_interval" split_words:"true"`
+}
+
+type idempotencyInfo struct {
+	Retention     time.Duration `envconfig:"IDEMPOTENCY_RETENTION" yaml:"retention" json:"retention"`
+	LockTimeout   time.Duration `envconfig:"IDEMPOTENCY_LOCK_TIMEOUT" yaml:"lock_timeout" json:"lock_timeout" split_words:"true"`
+	PurgeInterval time.Duration `envconfig:"IDEMPOTENCY_PURGE_INTERVAL" yaml:"purge_interval" json:"purge_interval" split_words:"true"`
+	MaxBodySize   int64         `envconfig:"IDEMPOTENCY_MAX_BODY_SIZE" yaml:"max_body_size" json:"max_body_size" split_words:"true"`
+}
+
+type kafkaInfo struct {
+	Brokers []string `envconfig:"KAFKA_BROKERS" yaml:"brokers" json:"brokers"`
+}
+
+type outboxInfo struct {
+	Enabled         bool          `envconfig:"OUTBOX_ENABLED" yaml:"enabled" json:"enabled"`
+	Topic           string        `envconfig:"OUTBOX_TOPIC" yaml:"topic" json:"topic"`
+	DeadLetterTopic string        `envconfig:"OUTBOX_DEAD_LETTER_TOPIC" yaml:"dead_letter_topic" json:"dead_letter_topic" split_words:"true"`
+	Interval        time.Duration `envconfig:"OUTBOX_INTERVAL" yaml:"interval" json:"interval"`
+	BatchSize       int           `envconfig:"OUTBOX_BATCH_SIZE" yaml:"batch_size" json:"batch_size" split_words:"true"`
+	BatchTimeout    time.Duration `envconfig:"OUTBOX_BATCH_TIMEOUT" yaml:"batch_timeout" json:"batch_timeout" split_words:"true"`
+	MaxAttempts     int           `envconfig:"OUTBOX_MAX_ATTEMPTS" yaml:"max_attempts" json:"max_attempts" split_words:"true"`
+	MinBackoff      time.Duration `envconfig:"OUTBOX_MIN_BACKOFF" yaml:"min_backoff" json:"min_backoff" split_words:"true"`
+	MaxBackoff      time.Duration `envconfig:"OUTBOX_MAX_BACKOFF" yaml:"max_backoff" json:"max_backoff" split_words:"true"`
+}
+
+type consumerInfo struct {
+	Enabled         bool          `envconfig:"CONSUMER_ENABLED" yaml:"enabled" json:"enabled"`
+	Group           string        `envconfig:"CONSUMER_GROUP" yaml:"group" json:"group"`
+	Concurrency     int           `envconfig:"CONSUMER_CONCURRENCY" yaml:"concurrency" json:"concurrency"`
+	MaxAttempts     int           `envconfig:"CONSUMER_MAX_ATTEMPTS" yaml:"max_attempts" json:"max_attempts" split_words:"true"`
+	MinBackoff      time.Duration `envconfig:"CONSUMER_MIN_BACKOFF" yaml:"min_backoff" json:"min_backoff" split_words:"true"`
+	MaxBackoff      time.Duration `envconfig:"CONSUMER_MAX_BACKOFF" yaml:"max_backoff" json:"max_backoff" split_words:"true"`
+	DeadLetterTopic string        `envconfig:"CONSUMER_DEAD_LETTER_TOPIC" yaml:"dead_letter_topic" json:"dead_letter_topic" split_words:"true"`
+	LagInterval     time.Duration `envconfig:"CONSUMER_LAG_INTERVAL" yaml:"lag_interval" json:"lag_interval" split_words:"true"`
+	ShutdownTimeout time.Duration `envconfig:"CONSUMER_SHUTDOWN_TIMEOUT" yaml:"shutdown_timeout" json:"shutdown_timeout" split_words:"true"`
+}
+
+type schemaInfo struct {
+	Enabled       bool   `envconfig:"SCHEMA_ENABLED" yaml:"enabled" json:"enabled"`
+	Dir           string `envconfig:"SCHEMA_DIR" yaml:"dir" json:"dir"`
+	RegistryFile  string `envconfig:"SCHEMA_REGISTRY_FILE" yaml:"registry_file" json:"registry_file" split_words:"true"`
+	Compatibility string `envconfig:"SCHEMA_COMPATIBILITY" yaml:"compatibility" json:"compatibility"`
+}
+
+type adminInfo struct {
+	Enabled      bool   `envconfig:"ADMIN_ENABLED" yaml:"enabled" json:"enabled"`
+	Address      string `envconfig:"ADMIN_ADDRESS" yaml:"address" json:"address"`
+	ProbeAddress string `envconfig:"ADMIN_PROBE_ADDRESS" yaml:"probe_address" json:"probe_address" split_words:"true"`
+	Debug        bool   `envconfig:"ADMIN_DEBUG" yaml:"debug" json:"debug"`
+}
+
+type metricsInfo struct {
+	Enabled bool   `envconfig:"METRICS_ENABLED" yaml:"enabled" json:"enabled"`
+	Path    string `envconfig:"METRICS_PATH" yaml:"path" json:"path"`
+}
+
+type dumpInfo struct {
+	MaxBodySize   int      `envconfig:"LOG_DUMP_MAX_BODY_SIZE" yaml:"max_body_size" json:"max_body_size" split_words:"true"`
+	RedactHeaders []string `envconfig:"LOG_DUMP_REDACT_HEADERS" yaml:"redact_headers" json:"redact_headers" split_words:"true"`
+	RedactFields  []string `envconfig:"LOG_DUMP_REDACT_FIELDS" yaml:"redact_fields" json:"redact_fields" split_words:"true"`
+	SampleRate    float64  `envconfig:"LOG_DUMP_SAMPLE_RATE" yaml:"sample_rate" json:"sample_rate" split_words:"true"`
+	Routes        []string `envconfig:"LOG_DUMP_ROUTES" yaml:"routes" json:"routes"`
+	Skip          []string `envconfig:"LOG_DUMP_SKIP" yaml:"skip" json:"skip"`
+}
+
+type authInfo struct {
+	Enabled         bool          `envconfig:"AUTH_ENABLED" yaml:"enabled" json:"enabled"`
+	JWKSURL         string        `envconfig:"AUTH_JWKS_URL" yaml:"jwks_url" json:"jwks_url"`
+	KeyFile         string        `envconfig:"AUTH_KEY_FILE" yaml:"key_file" json:"key_file" split_words:"true"`
+	Secret          string        `envconfig:"AUTH_SECRET" yaml:"secret" json:"-" secret:"true"`
+	Issuer          string        `envconfig:"AUTH_ISSUER" yaml:"issuer" json:"issuer"`
+	Audience        []string      `envconfig:"AUTH_AUDIENCE" yaml:"audience" json:"audience"`
+	Algorithms      []string      `envconfig:"AUTH_ALGORITHMS" yaml:"algorithms" json:"algorithms"`
+	ClockSkew       time.Duration `envconfig:"AUTH_CLOCK_SKEW" yaml:"clock_skew" json:"clock_skew" split_words:"true"`
+	RefreshInterval time.Duration `envconfig:"AUTH_REFRESH_INTERVAL" yaml:"refresh_interval" json:"refresh_interval" split_words:"true"`
+}
+
+type tracingInfo struct {
+	Provider    string  `envconfig:"TRACING_PROVIDER" yaml:"provider" json:"provider"`
+	Exporter    string  `envconfig:"TRACING_EXPORTER" yaml:"exporter" json:"exporter"`
+	Endpoint    string  `envconfig:"TRACING_ENDPOINT" yaml:"endpoint" json:"endpoint"`
+	Insecure    bool    `envconfig:"TRACING_INSECURE" yaml:"insecure" json:"insecure"`
+	File        string  `envconfig:"TRACING_FILE" yaml:"file" json:"file"`
+	SampleRatio float64 `envconfig:"TRACING_SAMPLE_RATIO" yaml:"sample_ratio" json:"sample_ratio" split_words:"true"`
 }
 
 // LoadServiceConfig ...
@@ -1,12 +1,25 @@
 package micro
 
 import (
+	"context"
 	"net/http"
 
+	"github.com/Gympass/gcore/v3/gcontext"
 	"github.com/Gympass/gcore/v3/glog"
 	"github.com/Gympass/gcore/v3/middleware"
 	"github.com/gorilla/handlers"
-	"gopkg.in/DataDog/dd-trace-go.v1/contrib/gorilla/mux"
+	"github.com/gorilla/mux"
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/codec"
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/consumer"
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/idempotency"
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/rest"
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/rest/auth"
+)
+
+// Scopes required by the demo routes.
+const (
+	ScopeRead  = "demo:read"
+	ScopeWrite = "demo:write"
 )
 
 // Config for API v1
@@ -14,22 +27,63 @@ type Config struct {
 	Logger     glog.Logger
 	Router     *mux.Router
 	Middleware middleware.GMiddlewareHandlerError
+	Store      Store
+	Clock      Clock
+	NewID      IDGenerator
+	// EventsTopic receives the demo events through the outbox, none are
+	// written when it is empty.
+	EventsTopic string
+	// Serializer encodes and decodes the demo events, as JSON when nil.
+	Serializer *codec.Serializer
+	// Consumer reads the demo events from EventsTopic, optional.
+	Consumer *consumer.Consumer
+	// Cursors seals the cursors of listings, it is required.
+	Cursors *rest.Cursors
+	// Idempotency replays the responses of retried creations, optional.
+	Idempotency *idempotency.Idempotency
+	// Auth requires bearer tokens granting ScopeRead or ScopeWrite, the
+	// routes are open when nil.
+	Auth *auth.Authenticator
+	// RequireIfMatch rejects PUT, PATCH and DELETE requests sent without
+	// an If-Match header with 428.
+	RequireIfMatch bool
 }
 
 // NewAPI create API handler
 func NewAPI(c Config) {
-	demoHandler := NewHandler(NewService(NewRepository()), c.Logger)
-	SetRoutes(demoHandler, c.Router, c.Middleware)
+	if c.Store == nil {
+		c.Logger.Warn(gcontext.NewContext(context.Background()), "No store configured, using in-memory store.")
+		c.Store = NewMemoryStore()
+	}
+
+	svc := NewService(ServiceConfig{
+		Store:       c.Store,
+		Clock:       c.Clock,
+		NewID:       c.NewID,
+		EventsTopic: c.EventsTopic,
+		Serializer:  c.Serializer,
+	})
+
+	demoHandler := NewHandler(svc, c.Cursors, c.Logger, c.RequireIfMatch)
+	SetRoutes(demoHandler, c.Router, c.Middleware, c.Idempotency, c.Auth)
+
+	if c.Consumer != nil && c.EventsTopic != "" {
+		c.Consumer.Handle(c.EventsTopic, NewEventHandler(c.Serializer, c.Logger).DemoEvent)
+	}
 }
 
-// SetRoutes for API handler
-func SetRoutes(handler *Handler, router *mux.Router, mw middleware.GMiddlewareHandlerError) {
+// SetRoutes for API handler, along with the scopes each route requires
+func SetRoutes(handler *Handler, router *mux.Router, mw middleware.GMiddlewareHandlerError, idem *idempotency.Idempotency, authn *auth.Authenticator) {
+	route := func(req auth.Requirement, h func(http.ResponseWriter, *http.Request) error) http.Handler {
+		return handlers.CompressHandler(mw.HandlerError(rest.Problems(authn.Require(req, h))))
+	}
+	read, write := auth.Scopes(ScopeRead), auth.Scopes(ScopeWrite)
+
 	r := router.PathPrefix("/v1").Subrouter()
-	r.Handle(
-		"/demo/{uid}",
-		handlers.CompressHandler(
-			mw.HandlerError(
-				handler.Demo,
-			),
-		)).Methods(http.MethodGet)
+	r.Handle("/demo", route(read, handler.Demos)).Methods(http.MethodGet)
+	r.Handle("/demo", route(write, idem.Handle(handler.CreateDemo))).Methods(http.MethodPost)
+	r.Handle("/demo/{uid}", route(read, handler.Demo)).Methods(http.MethodGet)
+	r.Handle("/demo/{uid}", route(write, handler.UpdateDemo)).Methods(http.MethodPut)
+	r.Handle("/demo/{uid}", route(write, handler.PatchDemo)).Methods(http.MethodPatch)
+	r.Handle("/demo/{uid}", route(write, handler.DeleteDemo)).Methods(http.MethodDelete)
 }
@@ -1,6 +1,9 @@
 package micro
 
 import (
+	"net/http"
+
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/rest"
 	"github.com/pkg/errors"
 )
 
@@ -9,4 +12,41 @@ var (
 	ErrInvalidID = errors.New("invalid id (must be an UUIDv4)")
 	// ErrInternal generic internal error
 	ErrInternal = errors.New("internal error")
+	// ErrNotFound no demo with the requested id
+	ErrNotFound = errors.New("demo not found")
+	// ErrAlreadyExists a demo with the same id was already stored
+	ErrAlreadyExists = errors.New("demo already exists")
+	// ErrVersionMismatch the demo was changed since it was read
+	ErrVersionMismatch = errors.New("demo was modified concurrently")
+)
+
+// Problem types returned by the API, see rest.Problems.
+const (
+	ProblemTypeInvalidID = "urn:problem-type:demo:invalid-id"
+	ProblemTypeInternal  = "urn:problem-type:demo:internal-error"
+	ProblemTypeNotFound  = "urn:problem-type:demo:not-found"
+	ProblemTypeConflict  = "urn:problem-type:demo:conflict"
 )
+
+func init() {
+	rest.RegisterProblem(ErrInvalidID, rest.ProblemType{
+		Type:   ProblemTypeInvalidID,
+		Title:  "Invalid id",
+		Status: http.StatusBadRequest,
+	})
+	rest.RegisterProblem(ErrNotFound, rest.ProblemType{
+		Type:   ProblemTypeNotFound,
+		Title:  "Demo not found",
+		Status: http.StatusNotFound,
+	})
+	rest.RegisterProblem(ErrAlreadyExists, rest.ProblemType{
+		Type:   ProblemTypeConflict,
+		Title:  "Demo already exists",
+		Status: http.StatusConflict,
+	})
+	rest.RegisterProblem(ErrInternal, rest.ProblemType{
+		Type:   ProblemTypeInternal,
+		Title:  "Internal error",
+		Status: http.StatusInternalServerError,
+	})
+}
@@ -0,0 +1,41 @@
+package micro
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/Gympass/gcore/v3/gcontext"
+	"github.com/Gympass/gcore/v3/glog"
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/codec"
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/consumer"
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/outbox"
+	"github.com/pkg/errors"
+)
+
+// EventHandler handles the demo events read from Kafka
+type EventHandler struct {
+	serializer *codec.Serializer
+	logger     glog.Logger
+}
+
+// NewEventHandler decoding events with s, as JSON when nil
+func NewEventHandler(s *codec.Serializer, l glog.Logger) *EventHandler {
+	return &EventHandler{serializer: s, logger: l}
+}
+
+// DemoEvent handles a demo event. Undecodable events are dead-lettered
+// without retries.
+func (h *EventHandler) DemoEvent(ctx context.Context, m consumer.Message) error {
+	var d Demo
+	if err := h.serializer.Unmarshal(ctx, m.Headers, m.Value, &d); err != nil {
+		return consumer.Permanent(errors.Wrap(err, "decoding demo event"))
+	}
+
+	gcontext.AddString(ctx, "demo.id", d.ID)
+	gcontext.AddString(ctx, "demo.event_type", m.Headers[outbox.HeaderEventType])
+	gcontext.AddString(ctx, "demo.version", strconv.FormatInt(d.Version, 10))
+
+	h.logger.Info(ctx, "Demo event received.")
+
+	return nil
+}
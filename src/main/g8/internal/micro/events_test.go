@@ -0,0 +1,44 @@
+package micro_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Gympass/gcore/v3/glog"
+	"github.com/gympass/$name;format="lower,hyphen"$/internal/micro"
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/consumer"
+)
+
+func TestDemoEvent(t *testing.T) {
+	h := micro.NewEventHandler(nil, glog.Noop())
+
+	tt := []struct {
+		Name              string
+		Value             string
+		ExpectedErr       bool
+		ExpectedPermanent bool
+	}{
+		{
+			Name:  "test demo event handled",
+			Value: `{"id":"` + demoID + `","name":"demo","status":"active","version":1}`,
+		},
+		{
+			Name:              "test undecodable event not retried",
+			Value:             `{"id":`,
+			ExpectedErr:       true,
+			ExpectedPermanent: true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			err := h.DemoEvent(context.Background(), consumer.Message{Topic: "demo-events", Value: []byte(tc.Value)})
+			if (err != nil) != tc.ExpectedErr {
+				t.Fatalf("DemoEvent() error = %v, want error %v", err, tc.ExpectedErr)
+			}
+			if consumer.IsPermanent(err) != tc.ExpectedPermanent {
+				t.Fatalf("DemoEvent() permanent = %v, want %v", consumer.IsPermanent(err), tc.ExpectedPermanent)
+			}
+		})
+	}
+}
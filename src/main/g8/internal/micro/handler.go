@@ -3,9 +3,12 @@ package micro
 import (
 	"context"
 	"net/http"
-	"os/exec"
+	"path"
+
 	"github.com/gympass/$name;format="lower,hyphen"$/pkg/rest"
+	"github.com/pkg/errors"
 
+	"github.com/Gympass/gcore/v3/gcontext"
 	"github.com/Gympass/gcore/v3/gerror"
 	"github.com/Gympass/gcore/v3/glog"
 
@@ -13,15 +16,33 @@ import (
 	_ "github.com/Gympass/gcore/v3/ghandler"
 )
 
+// Demo listings page size
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// DemoService is the business logic used by Handler
+type DemoService interface {
+	Demo(ctx context.Context, uid string) (Demo, error)
+	Demos(ctx context.Context, f DemoFilter, page rest.PageRequest) (DemoPage, error)
+	CreateDemo(ctx context.Context, in DemoInput) (Demo, error)
+	UpdateDemo(ctx context.Context, uid string, in DemoInput, check Precondition) (Demo, error)
+	DeleteDemo(ctx context.Context, uid string, check Precondition) error
+}
+
 // Handler struct
 type Handler struct {
-	svc    *Service
-	logger glog.Logger
+	svc     DemoService
+	cursors *rest.Cursors
+	logger  glog.Logger
+	// requireIfMatch rejects writes without If-Match with 428.
+	requireIfMatch bool
 }
 
 // NewHandler holding base struct
-func NewHandler(s *Service, l glog.Logger) *Handler {
-	return &Handler{svc: s, logger: l}
+func NewHandler(s DemoService, c *rest.Cursors, l glog.Logger, requireIfMatch bool) *Handler {
+	return &Handler{svc: s, cursors: c, logger: l, requireIfMatch: requireIfMatch}
 }
 
 // Demo ...
@@ -29,24 +50,260 @@ func NewHandler(s *Service, l glog.Logger) *Handler {
 // @Summary Get demo
 // @Description demo endpoint returning a Demo struct
 // @Param uid path string true "uuidv4 (UUIDv4)"
+// @Param If-None-Match header string false "ETag of a cached copy"
 // @Produce  json
+// @Produce  application/msgpack
+// @Produce  application/problem+json
 // @Success 200 {object} Demo
-// @Failure 400 {object} ghandler.HTTPError
-// @Failure 500 {object} ghandler.HTTPError
+// @Header 200 {string} ETag "version of the demo"
+// @Success 304 "cached copy is up to date"
+// @Failure 400 {object} rest.Problem
+// @Failure 404 {object} rest.Problem
+// @Failure 500 {object} rest.Problem
 // @Router /v1/demo/{uid} [get]
 func (h *Handler) Demo(w http.ResponseWriter, r *http.Request) error {
-	uid, err := rest.GetUUID(r, "uid")
+	uid, err := demoID(r)
+	if err != nil {
+		return err
+	}
+
+	demo, err := h.svc.Demo(r.Context(), uid)
+	if err != nil {
+		return h.fail(r, err)
+	}
+
+	return rest.Send(w, r, http.StatusOK, &demo)
+}
+
+// Demos ...
+// ListEntities godoc
+// @Summary List demos
+// @Description demo endpoint returning a page of Demo structs, oldest first. As text/csv, the rows are the page data and the links are only sent in the Link header
+// @Param name query string false "exact name"
+// @Param status query string false "status" Enums(active, inactive)
+// @Param limit query int false "page size" minimum(1) maximum(100) default(20)
+// @Param cursor query string false "opaque cursor from the next or prev link"
+// @Produce  json
+// @Produce  application/msgpack
+// @Produce  text/csv
+// @Produce  application/problem+json
+// @Success 200 {object} rest.Page{data=[]Demo}
+// @Header 200 {string} Link "RFC 8288 links to the next and prev pages"
+// @Failure 400 {object} rest.Problem
+// @Failure 500 {object} rest.Problem
+// @Router /v1/demo [get]
+func (h *Handler) Demos(w http.ResponseWriter, r *http.Request) error {
+	var f DemoFilter
+	if err := rest.Bind(r, &f); err != nil {
+		return err
+	}
+
+	page, err := h.cursors.ParsePage(r, defaultPageSize, maxPageSize)
+	if err != nil {
+		return err
+	}
+
+	p, err := h.svc.Demos(r.Context(), f, page)
+	if err != nil {
+		return h.fail(r, err)
+	}
+
+	return h.cursors.SendPage(w, r, p.Demos, p.Next, p.Prev)
+}
+
+// CreateDemo ...
+// CreateEntity godoc
+// @Summary Create demo
+// @Description demo endpoint storing a new Demo
+// @Param demo body DemoInput true "demo to create"
+// @Param Idempotency-Key header string false "unique key making retries replay the first response"
+// @Accept  json
+// @Produce  json
+// @Produce  application/msgpack
+// @Produce  application/problem+json
+// @Success 201 {object} Demo
+// @Header 201 {string} Location "URL of the new demo"
+// @Header 201 {string} ETag "version of the demo"
+// @Header 201 {string} Idempotent-Replayed "true on replayed responses"
+// @Failure 400 {object} rest.Problem
+// @Failure 409 {object} rest.Problem
+// @Failure 422 {object} rest.Problem
+// @Failure 500 {object} rest.Problem
+// @Router /v1/demo [post]
+func (h *Handler) CreateDemo(w http.ResponseWriter, r *http.Request) error {
+	var in DemoInput
+	if err := rest.Bind(r, &in); err != nil {
+		return err
+	}
+
+	demo, err := h.svc.CreateDemo(r.Context(), in)
+	if err != nil {
+		return h.fail(r, err)
+	}
+
+	w.Header().Set("Location", path.Join(r.URL.Path, demo.ID))
+
+	return rest.Send(w, r, http.StatusCreated, &demo)
+}
+
+// UpdateDemo ...
+// UpdateEntity godoc
+// @Summary Replace demo
+// @Description demo endpoint replacing the writable fields of a Demo
+// @Param uid path string true "uuidv4 (UUIDv4)"
+// @Param demo body DemoInput true "new demo fields"
+// @Param If-Match header string false "ETag of the demo being replaced"
+// @Accept  json
+// @Produce  json
+// @Produce  application/msgpack
+// @Produce  application/problem+json
+// @Success 200 {object} Demo
+// @Header 200 {string} ETag "new version of the demo"
+// @Failure 400 {object} rest.Problem
+// @Failure 404 {object} rest.Problem
+// @Failure 412 {object} rest.Problem
+// @Failure 428 {object} rest.Problem
+// @Failure 500 {object} rest.Problem
+// @Router /v1/demo/{uid} [put]
+func (h *Handler) UpdateDemo(w http.ResponseWriter, r *http.Request) error {
+	uid, err := demoID(r)
+	if err != nil {
+		return err
+	}
+
+	var in DemoInput
+	if err := rest.Bind(r, &in); err != nil {
+		return err
+	}
+
+	demo, err := h.svc.UpdateDemo(r.Context(), uid, in, h.ifMatch(r))
+	if err != nil {
+		return h.fail(r, err)
+	}
+
+	return rest.Send(w, r, http.StatusOK, &demo)
+}
+
+// PatchDemo ...
+// PatchEntity godoc
+// @Summary Patch demo
+// @Description demo endpoint applying a JSON Merge Patch (RFC 7396) to a Demo
+// @Param uid path string true "uuidv4 (UUIDv4)"
+// @Param patch body DemoInput true "fields to change, null removes a field"
+// @Param If-Match header string false "ETag of the demo being patched"
+// @Accept  application/merge-patch+json
+// @Accept  json
+// @Produce  json
+// @Produce  application/msgpack
+// @Produce  application/problem+json
+// @Success 200 {object} Demo
+// @Header 200 {string} ETag "new version of the demo"
+// @Failure 400 {object} rest.Problem
+// @Failure 404 {object} rest.Problem
+// @Failure 412 {object} rest.Problem
+// @Failure 415 {object} rest.Problem
+// @Failure 428 {object} rest.Problem
+// @Failure 500 {object} rest.Problem
+// @Router /v1/demo/{uid} [patch]
+func (h *Handler) PatchDemo(w http.ResponseWriter, r *http.Request) error {
+	uid, err := demoID(r)
+	if err != nil {
+		return err
+	}
+
+	current, err := h.svc.Demo(r.Context(), uid)
+	if err != nil {
+		return h.fail(r, err)
+	}
+	if err := h.ifMatch(r)(current); err != nil {
+		return err
+	}
+
+	in := current.Input()
+	if err := rest.BindMergePatch(r, &in); err != nil {
+		return err
+	}
+
+	// The patch was merged into current, which must not change meanwhile.
+	unchanged := func(d Demo) error {
+		if d.Version != current.Version {
+			return ErrVersionMismatch
+		}
+		return nil
+	}
+
+	demo, err := h.svc.UpdateDemo(r.Context(), uid, in, unchanged)
+	if err != nil {
+		return h.fail(r, err)
+	}
+
+	return rest.Send(w, r, http.StatusOK, &demo)
+}
+
+// DeleteDemo ...
+// DeleteEntity godoc
+// @Summary Delete demo
+// @Description demo endpoint removing a Demo
+// @Param uid path string true "uuidv4 (UUIDv4)"
+// @Param If-Match header string false "ETag of the demo being removed"
+// @Produce  application/problem+json
+// @Success 204
+// @Failure 400 {object} rest.Problem
+// @Failure 404 {object} rest.Problem
+// @Failure 412 {object} rest.Problem
+// @Failure 428 {object} rest.Problem
+// @Failure 500 {object} rest.Problem
+// @Router /v1/demo/{uid} [delete]
+func (h *Handler) DeleteDemo(w http.ResponseWriter, r *http.Request) error {
+	uid, err := demoID(r)
 	if err != nil {
-		return gerror.NewBadRequest(ErrInvalidID).WithMessage(ErrInvalidID.Error())
+		return err
 	}
 
-	// Dummy command injection to test Sonar
-	out, _ = exec.Command(uid)
+	if err := h.svc.DeleteDemo(r.Context(), uid, h.ifMatch(r)); err != nil {
+		return h.fail(r, err)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
 
-	demo, err := h.svc.Demo(context.Background(), uid.String())
+// demoID reads the uid path parameter.
+func demoID(r *http.Request) (string, error) {
+	uid, err := rest.GetUUID(r, "uid")
 	if err != nil {
-		return gerror.NewInternalServerError(ErrInternal).WithMessage(ErrInternal.Error())
+		return "", gerror.NewBadRequest(ErrInvalidID).WithMessage(ErrInvalidID.Error())
+	}
+
+	return uid.String(), nil
+}
+
+// ifMatch checks the If-Match header of r against the ETag of the demo.
+func (h *Handler) ifMatch(r *http.Request) Precondition {
+	return func(d Demo) error {
+		return rest.IfMatch(r, d.ETag(), h.requireIfMatch)
 	}
+}
+
+// fail converts an error of the service into the one returned to clients,
+// hiding unexpected errors behind ErrInternal.
+func (h *Handler) fail(r *http.Request, err error) error {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return gerror.NewNotFound(ErrNotFound).WithMessage(ErrNotFound.Error())
+	case errors.Is(err, ErrVersionMismatch):
+		return errors.Wrap(rest.ErrPreconditionFailed, err.Error())
+	case errors.Is(err, ErrAlreadyExists), errors.Is(err, rest.ErrInvalidCursor),
+		errors.Is(err, rest.ErrPreconditionFailed), errors.Is(err, rest.ErrPreconditionRequired):
+		return err
+	case errors.Is(err, context.DeadlineExceeded):
+		// The request ran out of time, see rest.Timeout.
+		gcontext.AddError(r.Context(), err)
+		return err
+	}
+
+	gcontext.AddError(r.Context(), err)
 
-	return rest.SendJSON(w, &demo)
+	return gerror.NewInternalServerError(ErrInternal).WithMessage(ErrInternal.Error())
 }
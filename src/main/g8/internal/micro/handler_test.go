@@ -0,0 +1,430 @@
+package micro_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Gympass/gcore/v3/glog"
+	"github.com/Gympass/gcore/v3/middleware"
+	jwt "github.com/form3tech-oss/jwt-go"
+	"github.com/gofrs/uuid"
+	"github.com/gorilla/mux"
+	"github.com/gympass/$name;format="lower,hyphen"$/internal/micro"
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/rest"
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/rest/auth"
+)
+
+const demoID = "6ba7b810-9dad-41d1-80b4-00c04fd430c8"
+
+// handlerTestCase is one request sent to the API, in order with the others
+// of its table.
+type handlerTestCase struct {
+	Name             string
+	Method           string
+	Target           string
+	ContentType      string
+	IfMatch          string
+	IfNoneMatch      string
+	Accept           string
+	Token            string
+	Body             string
+	ExpectedStatus   int
+	ExpectedLocation string
+	ExpectedETag     string
+	ExpectedBody     string
+}
+
+func newTestAPI(t *testing.T, requireIfMatch bool, authn *auth.Authenticator) http.Handler {
+	t.Helper()
+
+	cursors, err := rest.NewCursors("test")
+	if err != nil {
+		t.Fatalf("rest.NewCursors() error = %v", err)
+	}
+
+	router := mux.NewRouter()
+	micro.NewAPI(micro.Config{
+		Logger:     glog.Noop(),
+		Router:     router,
+		Middleware: middleware.New(),
+		Store:      micro.NewMemoryStore(),
+		Clock:      func() time.Time { return time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC) },
+		NewID:      func() (uuid.UUID, error) { return uuid.FromString(demoID) },
+		Cursors:    cursors,
+		Auth:       authn,
+
+		RequireIfMatch: requireIfMatch,
+	})
+
+	return router
+}
+
+func TestHandler(t *testing.T) {
+	runHandlerTests(t, newTestAPI(t, false, nil), []handlerTestCase{
+		{
+			Name:           "test get missing demo",
+			Method:         http.MethodGet,
+			Target:         "/v1/demo/" + demoID,
+			ExpectedStatus: http.StatusNotFound,
+			ExpectedBody:   micro.ProblemTypeNotFound,
+		},
+		{
+			Name:           "test create invalid demo",
+			Method:         http.MethodPost,
+			Target:         "/v1/demo",
+			Body:           `{"name":"demo","status":"unknown"}`,
+			ExpectedStatus: http.StatusBadRequest,
+			ExpectedBody:   `"field":"status"`,
+		},
+		{
+			Name:             "test create demo",
+			Method:           http.MethodPost,
+			Target:           "/v1/demo",
+			Body:             `{"name":"demo","status":"active"}`,
+			ExpectedStatus:   http.StatusCreated,
+			ExpectedLocation: "/v1/demo/" + demoID,
+			ExpectedETag:     `"1"`,
+			ExpectedBody:     `"name":"demo"`,
+		},
+		{
+			Name:           "test create duplicated demo",
+			Method:         http.MethodPost,
+			Target:         "/v1/demo",
+			Body:           `{"name":"demo","status":"active"}`,
+			ExpectedStatus: http.StatusConflict,
+			ExpectedBody:   micro.ProblemTypeConflict,
+		},
+		{
+			Name:           "test create duplicated demo legacy format",
+			Method:         http.MethodPost,
+			Target:         "/v1/demo",
+			Accept:         rest.ContentTypeJSON,
+			Body:           `{"name":"demo","status":"active"}`,
+			ExpectedStatus: http.StatusConflict,
+			ExpectedBody:   `{"error":"demo already exists"}`,
+		},
+		{
+			Name:           "test get demo",
+			Method:         http.MethodGet,
+			Target:         "/v1/demo/" + demoID,
+			ExpectedStatus: http.StatusOK,
+			ExpectedETag:   `"1"`,
+			ExpectedBody:   `"created_at":"2023-06-01T12:00:00Z"`,
+		},
+		{
+			Name:           "test get unmodified demo",
+			Method:         http.MethodGet,
+			Target:         "/v1/demo/" + demoID,
+			IfNoneMatch:    `"1"`,
+			ExpectedStatus: http.StatusNotModified,
+			ExpectedETag:   `"1"`,
+		},
+		{
+			Name:           "test get invalid id",
+			Method:         http.MethodGet,
+			Target:         "/v1/demo/1",
+			ExpectedStatus: http.StatusBadRequest,
+			ExpectedBody:   micro.ProblemTypeInvalidID,
+		},
+		{
+			Name:           "test list demos",
+			Method:         http.MethodGet,
+			Target:         "/v1/demo?status=active&limit=10",
+			ExpectedStatus: http.StatusOK,
+			ExpectedBody:   `"data":[{"id":"` + demoID + `"`,
+		},
+		{
+			Name:           "test list invalid filter",
+			Method:         http.MethodGet,
+			Target:         "/v1/demo?status=unknown",
+			ExpectedStatus: http.StatusBadRequest,
+			ExpectedBody:   `"field":"status"`,
+		},
+		{
+			Name:           "test replace demo",
+			Method:         http.MethodPut,
+			Target:         "/v1/demo/" + demoID,
+			Body:           `{"name":"renamed","status":"active"}`,
+			ExpectedStatus: http.StatusOK,
+			ExpectedETag:   `"2"`,
+			ExpectedBody:   `"name":"renamed"`,
+		},
+		{
+			Name:           "test replace stale demo",
+			Method:         http.MethodPut,
+			Target:         "/v1/demo/" + demoID,
+			IfMatch:        `"1"`,
+			Body:           `{"name":"stale","status":"active"}`,
+			ExpectedStatus: http.StatusPreconditionFailed,
+			ExpectedBody:   rest.ProblemTypePreconditionFailed,
+		},
+		{
+			Name:           "test replace stale demo legacy format",
+			Method:         http.MethodPut,
+			Target:         "/v1/demo/" + demoID,
+			IfMatch:        `"1"`,
+			Accept:         rest.ContentTypeJSON,
+			Body:           `{"name":"stale","status":"active"}`,
+			ExpectedStatus: http.StatusPreconditionFailed,
+			ExpectedBody:   `{"error":"`,
+		},
+		{
+			Name:           "test replace missing field",
+			Method:         http.MethodPut,
+			Target:         "/v1/demo/" + demoID,
+			Body:           `{"name":"renamed"}`,
+			ExpectedStatus: http.StatusBadRequest,
+			ExpectedBody:   `"field":"status"`,
+		},
+		{
+			Name:           "test patch demo",
+			Method:         http.MethodPatch,
+			Target:         "/v1/demo/" + demoID,
+			ContentType:    rest.ContentTypeMergePatch,
+			IfMatch:        `"2"`,
+			Body:           `{"status":"inactive"}`,
+			ExpectedStatus: http.StatusOK,
+			ExpectedETag:   `"3"`,
+			ExpectedBody:   `"name":"renamed","status":"inactive"`,
+		},
+		{
+			Name:           "test patch stale demo",
+			Method:         http.MethodPatch,
+			Target:         "/v1/demo/" + demoID,
+			ContentType:    rest.ContentTypeMergePatch,
+			IfMatch:        `"2"`,
+			Body:           `{"status":"active"}`,
+			ExpectedStatus: http.StatusPreconditionFailed,
+			ExpectedBody:   rest.ProblemTypePreconditionFailed,
+		},
+		{
+			Name:           "test delete stale demo",
+			Method:         http.MethodDelete,
+			Target:         "/v1/demo/" + demoID,
+			IfMatch:        `"2"`,
+			ExpectedStatus: http.StatusPreconditionFailed,
+			ExpectedBody:   rest.ProblemTypePreconditionFailed,
+		},
+		{
+			Name:           "test patch unsupported media type",
+			Method:         http.MethodPatch,
+			Target:         "/v1/demo/" + demoID,
+			ContentType:    "text/plain",
+			Body:           `{"status":"inactive"}`,
+			ExpectedStatus: http.StatusUnsupportedMediaType,
+			ExpectedBody:   rest.ProblemTypeUnsupportedMediaType,
+		},
+		{
+			Name:           "test patch unsupported media type legacy format",
+			Method:         http.MethodPatch,
+			Target:         "/v1/demo/" + demoID,
+			ContentType:    "text/plain",
+			Accept:         rest.ContentTypeJSON,
+			Body:           `{"status":"inactive"}`,
+			ExpectedStatus: http.StatusUnsupportedMediaType,
+			ExpectedBody:   `{"error":"got \"text/plain\", want application/merge-patch+json: unsupported media type"}`,
+		},
+		{
+			Name:           "test delete demo",
+			Method:         http.MethodDelete,
+			Target:         "/v1/demo/" + demoID,
+			ExpectedStatus: http.StatusNoContent,
+		},
+		{
+			Name:           "test delete missing demo",
+			Method:         http.MethodDelete,
+			Target:         "/v1/demo/" + demoID,
+			ExpectedStatus: http.StatusNotFound,
+			ExpectedBody:   micro.ProblemTypeNotFound,
+		},
+	})
+}
+
+func TestHandlerRequireIfMatch(t *testing.T) {
+	runHandlerTests(t, newTestAPI(t, true, nil), []handlerTestCase{
+		{
+			Name:             "test create demo",
+			Method:           http.MethodPost,
+			Target:           "/v1/demo",
+			Body:             `{"name":"demo","status":"active"}`,
+			ExpectedStatus:   http.StatusCreated,
+			ExpectedLocation: "/v1/demo/" + demoID,
+			ExpectedETag:     `"1"`,
+		},
+		{
+			Name:           "test replace without if-match",
+			Method:         http.MethodPut,
+			Target:         "/v1/demo/" + demoID,
+			Body:           `{"name":"renamed","status":"active"}`,
+			ExpectedStatus: http.StatusPreconditionRequired,
+			ExpectedBody:   rest.ProblemTypePreconditionRequired,
+		},
+		{
+			Name:           "test replace without if-match legacy format",
+			Method:         http.MethodPut,
+			Target:         "/v1/demo/" + demoID,
+			Accept:         rest.ContentTypeJSON,
+			Body:           `{"name":"renamed","status":"active"}`,
+			ExpectedStatus: http.StatusPreconditionRequired,
+			ExpectedBody:   `{"error":"if-match header is required"}`,
+		},
+		{
+			Name:           "test patch without if-match",
+			Method:         http.MethodPatch,
+			Target:         "/v1/demo/" + demoID,
+			ContentType:    rest.ContentTypeMergePatch,
+			Body:           `{"name":"renamed"}`,
+			ExpectedStatus: http.StatusPreconditionRequired,
+			ExpectedBody:   rest.ProblemTypePreconditionRequired,
+		},
+		{
+			Name:           "test delete without if-match",
+			Method:         http.MethodDelete,
+			Target:         "/v1/demo/" + demoID,
+			ExpectedStatus: http.StatusPreconditionRequired,
+			ExpectedBody:   rest.ProblemTypePreconditionRequired,
+		},
+		{
+			Name:           "test replace with if-match",
+			Method:         http.MethodPut,
+			Target:         "/v1/demo/" + demoID,
+			IfMatch:        `"1"`,
+			Body:           `{"name":"renamed","status":"active"}`,
+			ExpectedStatus: http.StatusOK,
+			ExpectedETag:   `"2"`,
+		},
+		{
+			Name:           "test delete with if-match",
+			Method:         http.MethodDelete,
+			Target:         "/v1/demo/" + demoID,
+			IfMatch:        `"2"`,
+			ExpectedStatus: http.StatusNoContent,
+		},
+	})
+}
+
+func TestHandlerDeadline(t *testing.T) {
+	api := newTestAPI(t, false, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancel()
+	<-ctx.Done()
+
+	r := httptest.NewRequest(http.MethodGet, "/v1/demo/"+demoID, nil).WithContext(ctx)
+	w := httptest.NewRecorder()
+
+	api.ServeHTTP(w, r)
+
+	if w.Code != http.StatusGatewayTimeout || !strings.Contains(w.Body.String(), rest.ProblemTypeTimeout) {
+		t.Fatalf("status = %d (%s), want %d", w.Code, w.Body, http.StatusGatewayTimeout)
+	}
+}
+
+func TestHandlerAuth(t *testing.T) {
+	authn, err := auth.New(auth.Config{Secret: "test-secret"})
+	if err != nil {
+		t.Fatalf("auth.New() error = %v", err)
+	}
+
+	token := func(scope string) string {
+		s, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+			"sub":   "user-1",
+			"exp":   time.Now().Add(time.Hour).Unix(),
+			"scope": scope,
+		}).SignedString([]byte("test-secret"))
+		if err != nil {
+			t.Fatalf("SignedString() error = %v", err)
+		}
+		return s
+	}
+
+	runHandlerTests(t, newTestAPI(t, false, authn), []handlerTestCase{
+		{
+			Name:           "test list without token",
+			Method:         http.MethodGet,
+			Target:         "/v1/demo",
+			ExpectedStatus: http.StatusUnauthorized,
+			ExpectedBody:   auth.ProblemTypeUnauthorized,
+		},
+		{
+			Name:           "test create with read scope",
+			Method:         http.MethodPost,
+			Target:         "/v1/demo",
+			Token:          token(micro.ScopeRead),
+			Body:           `{"name":"demo","status":"active"}`,
+			ExpectedStatus: http.StatusForbidden,
+			ExpectedBody:   auth.ProblemTypeForbidden,
+		},
+		{
+			Name:             "test create with write scope",
+			Method:           http.MethodPost,
+			Target:           "/v1/demo",
+			Token:            token(micro.ScopeWrite),
+			Body:             `{"name":"demo","status":"active"}`,
+			ExpectedStatus:   http.StatusCreated,
+			ExpectedLocation: "/v1/demo/" + demoID,
+		},
+		{
+			Name:           "test get with read scope",
+			Method:         http.MethodGet,
+			Target:         "/v1/demo/" + demoID,
+			Token:          token(micro.ScopeRead),
+			ExpectedStatus: http.StatusOK,
+			ExpectedBody:   `"name":"demo"`,
+		},
+	})
+}
+
+// runHandlerTests sends the requests of tt in order to api.
+func runHandlerTests(t *testing.T, api http.Handler, tt []handlerTestCase) {
+	t.Helper()
+
+	for _, tc := range tt {
+		r := httptest.NewRequest(tc.Method, tc.Target, strings.NewReader(tc.Body))
+		if tc.Body != "" {
+			r.Header.Set("Content-Type", rest.ContentTypeJSON)
+		}
+		if tc.ContentType != "" {
+			r.Header.Set("Content-Type", tc.ContentType)
+		}
+		if tc.IfMatch != "" {
+			r.Header.Set("If-Match", tc.IfMatch)
+		}
+		if tc.IfNoneMatch != "" {
+			r.Header.Set("If-None-Match", tc.IfNoneMatch)
+		}
+		if tc.Accept != "" {
+			r.Header.Set("Accept", tc.Accept)
+		}
+		if tc.Token != "" {
+			r.Header.Set("Authorization", "Bearer "+tc.Token)
+		}
+		w := httptest.NewRecorder()
+
+		api.ServeHTTP(w, r)
+
+		if w.Code != tc.ExpectedStatus {
+			t.Fatalf("%s: status = %d, want %d (%s)", tc.Name, w.Code, tc.ExpectedStatus, w.Body)
+		}
+		if got := w.Header().Get("Location"); got != tc.ExpectedLocation {
+			t.Fatalf("%s: location = %q, want %q", tc.Name, got, tc.ExpectedLocation)
+		}
+		if got := w.Header().Get("ETag"); tc.ExpectedETag != "" && got != tc.ExpectedETag {
+			t.Fatalf("%s: etag = %q, want %q", tc.Name, got, tc.ExpectedETag)
+		}
+		if !strings.Contains(w.Body.String(), tc.ExpectedBody) {
+			t.Fatalf("%s: body = %s, want it to contain %s", tc.Name, w.Body, tc.ExpectedBody)
+		}
+		if (tc.ExpectedStatus == http.StatusNoContent || tc.ExpectedStatus == http.StatusNotModified) && w.Body.Len() > 0 {
+			t.Fatalf("%s: body = %s, want none", tc.Name, w.Body)
+		}
+		if w.Code < http.StatusBadRequest && w.Body.Len() > 0 && !json.Valid(w.Body.Bytes()) {
+			t.Fatalf("%s: body = %s, want json", tc.Name, w.Body)
+		}
+	}
+}
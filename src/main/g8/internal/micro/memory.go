@@ -0,0 +1,210 @@
+package micro
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Gympass/gcore/v3/gerror"
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/keyset"
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/outbox"
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/rest"
+	"github.com/pkg/errors"
+)
+
+// MemoryStore is an in-memory Store, meant for tests and local runs. Like
+// the database, it fails with the error of contexts that are done.
+type MemoryStore struct {
+	mu     sync.RWMutex
+	demos  map[string]Demo
+	outbox *outbox.MemoryStore
+}
+
+// NewMemoryStore creates a MemoryStore holding the given demos
+func NewMemoryStore(demos ...Demo) *MemoryStore {
+	s := &MemoryStore{demos: make(map[string]Demo, len(demos))}
+	for _, d := range demos {
+		s.demos[d.ID] = d
+	}
+
+	return s
+}
+
+// WithOutbox makes s write the events of its changes to o
+func (s *MemoryStore) WithOutbox(o *outbox.MemoryStore) *MemoryStore {
+	s.outbox = o
+	return s
+}
+
+// Demo returns the demo identified by uid, or ErrNotFound
+func (s *MemoryStore) Demo(ctx context.Context, uid string) (Demo, error) {
+	if err := ctx.Err(); err != nil {
+		return Demo{}, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	d, ok := s.demos[uid]
+	if !ok {
+		return Demo{}, ErrNotFound
+	}
+
+	return d, nil
+}
+
+// Demos returns the page of demos matching f, oldest first
+func (s *MemoryStore) Demos(ctx context.Context, f DemoFilter, page rest.PageRequest) (DemoPage, error) {
+	if err := ctx.Err(); err != nil {
+		return DemoPage{}, err
+	}
+
+	b, err := keyset.New(page, demoKeys...)
+	if err != nil {
+		return DemoPage{}, err
+	}
+
+	backward := page.Cursor != nil && page.Cursor.Direction == rest.Backward
+	before := func(a, b Demo) bool {
+		if backward {
+			return demoBefore(b, a)
+		}
+		return demoBefore(a, b)
+	}
+
+	// past tells whether d lies past the cursor, in the page direction.
+	var past func(d Demo) bool
+
+	if cur := page.Cursor; cur != nil {
+		at, err := time.Parse(time.RFC3339Nano, cur.Values[0])
+		if err != nil {
+			return DemoPage{}, gerror.NewBadRequest(errors.Wrap(rest.ErrInvalidCursor, err.Error())).
+				WithMessage("cursor does not belong to this listing")
+		}
+		pivot := Demo{ID: cur.Values[1], CreatedAt: at}
+		past = func(d Demo) bool { return before(pivot, d) }
+	}
+
+	s.mu.RLock()
+	demos := []Demo{}
+	for _, d := range s.demos {
+		if (f.Name != "" && d.Name != f.Name) || (f.Status != "" && d.Status != f.Status) {
+			continue
+		}
+		if past != nil && !past(d) {
+			continue
+		}
+		demos = append(demos, d)
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(demos, func(i, j int) bool { return before(demos[i], demos[j]) })
+	if len(demos) > b.Limit() {
+		demos = demos[:b.Limit()]
+	}
+
+	var p DemoPage
+	p.Demos, p.Next, p.Prev = keyset.Paginate(b, demos, demoKeyValues)
+
+	return p, nil
+}
+
+// CreateDemo stores d, or fails with ErrAlreadyExists
+func (s *MemoryStore) CreateDemo(ctx context.Context, d Demo, events ...outbox.Event) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.demos[d.ID]; ok {
+		return ErrAlreadyExists
+	}
+	if err := s.enqueue(ctx, events); err != nil {
+		return err
+	}
+	s.demos[d.ID] = d
+
+	return nil
+}
+
+// UpdateDemo replaces the name, status, update time and version of d if
+// the stored demo is still at version, or fails with ErrVersionMismatch or
+// ErrNotFound
+func (s *MemoryStore) UpdateDemo(ctx context.Context, d Demo, version int64, events ...outbox.Event) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cur, err := s.current(d.ID, version)
+	if err != nil {
+		return err
+	}
+	if err := s.enqueue(ctx, events); err != nil {
+		return err
+	}
+	cur.Name, cur.Status, cur.UpdatedAt, cur.Version = d.Name, d.Status, d.UpdatedAt, d.Version
+	s.demos[d.ID] = cur
+
+	return nil
+}
+
+// DeleteDemo removes the demo identified by uid if it is still at version,
+// or fails with ErrVersionMismatch or ErrNotFound
+func (s *MemoryStore) DeleteDemo(ctx context.Context, uid string, version int64, events ...outbox.Event) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.current(uid, version); err != nil {
+		return err
+	}
+	if err := s.enqueue(ctx, events); err != nil {
+		return err
+	}
+	delete(s.demos, uid)
+
+	return nil
+}
+
+// enqueue writes events to the outbox, if any. The caller holds the write
+// lock so events are enqueued in the order of the changes, and applies its
+// change only once they are, as the transaction of the database would.
+func (s *MemoryStore) enqueue(ctx context.Context, events []outbox.Event) error {
+	if s.outbox == nil || len(events) == 0 {
+		return nil
+	}
+
+	return s.outbox.Enqueue(ctx, events...)
+}
+
+// current returns the demo identified by uid if it is at version. The
+// caller holds the write lock.
+func (s *MemoryStore) current(uid string, version int64) (Demo, error) {
+	d, ok := s.demos[uid]
+	switch {
+	case !ok:
+		return Demo{}, ErrNotFound
+	case d.Version != version:
+		return Demo{}, ErrVersionMismatch
+	}
+
+	return d, nil
+}
+
+// demoBefore tells whether a sorts before b in listings, see demoKeys.
+func demoBefore(a, b Demo) bool {
+	if !a.CreatedAt.Equal(b.CreatedAt) {
+		return a.CreatedAt.Before(b.CreatedAt)
+	}
+
+	return a.ID < b.ID
+}
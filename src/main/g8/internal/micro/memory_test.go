@@ -0,0 +1,14 @@
+package micro_test
+
+import (
+	"testing"
+
+	"github.com/gympass/$name;format="lower,hyphen"$/internal/micro"
+	"github.com/gympass/$name;format="lower,hyphen"$/internal/micro/storetest"
+)
+
+func TestMemoryStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T, seed ...micro.Demo) micro.Store {
+		return micro.NewMemoryStore(seed...)
+	})
+}
@@ -1,6 +1,61 @@
 package micro
 
+import (
+	"strconv"
+	"time"
+
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/rest"
+)
+
+// Demo statuses
+const (
+	StatusActive   = "active"
+	StatusInactive = "inactive"
+)
+
+// Demo event types, written to the outbox with the demo as payload
+const (
+	EventDemoCreated = "demo.created"
+	EventDemoUpdated = "demo.updated"
+	EventDemoDeleted = "demo.deleted"
+)
+
 // Demo struct to be returned
 type Demo struct {
-	ID string `json:"id"`
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// Version is incremented by every change, see ETag.
+	Version int64 `json:"version"`
+}
+
+// ETag implements rest.ETagger
+func (d Demo) ETag() string {
+	return rest.StrongETag(strconv.FormatInt(d.Version, 10))
+}
+
+// Input returns the writable fields of d
+func (d Demo) Input() DemoInput {
+	return DemoInput{Name: d.Name, Status: d.Status}
+}
+
+// DemoInput is the body accepted to create and update a Demo
+type DemoInput struct {
+	Name   string `json:"name" validate:"required,max=100" example:"my demo"`
+	Status string `json:"status" validate:"required,oneof=active|inactive" example:"active"`
+}
+
+// DemoFilter narrows a Demo listing, empty fields match every demo
+type DemoFilter struct {
+	Name   string `query:"name"`
+	Status string `query:"status" validate:"omitempty,oneof=active|inactive"`
+}
+
+// DemoPage is one page of a Demo listing, with the cursors of its
+// neighbours (nil at either end)
+type DemoPage struct {
+	Demos      []Demo
+	Next, Prev *rest.Cursor
 }
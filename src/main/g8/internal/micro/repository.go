@@ -2,18 +2,274 @@ package micro
 
 import (
 	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/keyset"
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/outbox"
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/rest"
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/tracing"
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Store is the data storage used by Service. The events given to its
+// write methods are written to the outbox along with the change, or not
+// at all.
+type Store interface {
+	// Demo returns the demo identified by uid, or ErrNotFound.
+	Demo(ctx context.Context, uid string) (Demo, error)
+	// Demos returns the page of demos matching f, oldest first.
+	Demos(ctx context.Context, f DemoFilter, page rest.PageRequest) (DemoPage, error)
+	// CreateDemo stores d, or fails with ErrAlreadyExists.
+	CreateDemo(ctx context.Context, d Demo, events ...outbox.Event) error
+	// UpdateDemo replaces the name, status, update time and version of d
+	// if the stored demo is still at version, or fails with
+	// ErrVersionMismatch or ErrNotFound.
+	UpdateDemo(ctx context.Context, d Demo, version int64, events ...outbox.Event) error
+	// DeleteDemo removes the demo identified by uid if it is still at
+	// version, or fails with ErrVersionMismatch or ErrNotFound.
+	DeleteDemo(ctx context.Context, uid string, version int64, events ...outbox.Event) error
+}
+
+// demoKeys sort demo listings, see keyset.New.
+var demoKeys = []string{"created_at", "id"}
+
+// demoKeyValues returns the values of demoKeys for d.
+func demoKeyValues(d Demo) []string {
+	return []string{d.CreatedAt.UTC().Format(time.RFC3339Nano), d.ID}
+}
+
+// uniqueViolation is the PostgreSQL error code of duplicate keys.
+const uniqueViolation = "23505"
+
+const (
+	demoColumns = `id, name, status, created_at, updated_at, version`
+	selectDemo  = `SELECT ` + demoColumns + ` FROM demo WHERE id = \$1`
+	selectDemos = `SELECT ` + demoColumns + ` FROM demo`
+	existsDemo  = `SELECT EXISTS (SELECT 1 FROM demo WHERE id = \$1)`
+	insertDemo  = `INSERT INTO demo (` + demoColumns + `) VALUES (\$1, \$2, \$3, \$4, \$5, \$6)`
+	updateDemo  = `UPDATE demo SET name = \$2, status = \$3, updated_at = \$4, version = \$5 WHERE id = \$1 AND version = \$6`
+	deleteDemo  = `DELETE FROM demo WHERE id = \$1 AND version = \$2`
 )
 
-// Repository basic struct to be filled with data storage info
+var tracer = tracing.Tracer("github.com/gympass/$name;format="lower,hyphen"$/internal/micro")
+
+// Repository is the PostgreSQL Store
 type Repository struct {
+	db *sql.DB
 }
 
 // NewRepository to receive data storage info
-func NewRepository() *Repository {
-	return &Repository{}
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{db: db}
 }
 
 // Demo to retrieve data from storage
-func (r *Repository) Demo(ctx context.Context, uid string) (Demo, error) {
-	return Demo{ID: uid}, nil
+func (r *Repository) Demo(ctx context.Context, uid string) (_ Demo, err error) {
+	ctx, span := startQuery(ctx, "SELECT", selectDemo)
+	defer func() { endQuery(span, err) }()
+
+	d, err := scanDemo(r.db.QueryRowContext(ctx, selectDemo, uid))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Demo{}, ErrNotFound
+	}
+	if err != nil {
+		return Demo{}, errors.Wrap(err, "selecting demo")
+	}
+
+	return d, nil
+}
+
+// Demos to list data from storage
+func (r *Repository) Demos(ctx context.Context, f DemoFilter, page rest.PageRequest) (_ DemoPage, err error) {
+	b, err := keyset.New(page, demoKeys...)
+	if err != nil {
+		return DemoPage{}, err
+	}
+
+	var (
+		conds []string
+		args  []interface{}
+	)
+
+	if f.Name != "" {
+		args = append(args, f.Name)
+		conds = append(conds, fmt.Sprintf("name = \$%d", len(args)))
+	}
+	if f.Status != "" {
+		args = append(args, f.Status)
+		conds = append(conds, fmt.Sprintf("status = \$%d", len(args)))
+	}
+	if cond, cargs := b.Where(len(args) + 1); cond != "" {
+		conds = append(conds, cond)
+		args = append(args, cargs...)
+	}
+
+	query := selectDemos
+	if len(conds) > 0 {
+		query += " WHERE " + strings.Join(conds, " AND ")
+	}
+	query += fmt.Sprintf(" ORDER BY %s LIMIT %d", b.OrderBy(), b.Limit())
+
+	ctx, span := startQuery(ctx, "SELECT", query)
+	defer func() { endQuery(span, err) }()
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return DemoPage{}, errors.Wrap(err, "selecting demos")
+	}
+	defer rows.Close()
+
+	demos := []Demo{}
+	for rows.Next() {
+		d, err := scanDemo(rows)
+		if err != nil {
+			return DemoPage{}, errors.Wrap(err, "scanning demo")
+		}
+		demos = append(demos, d)
+	}
+	if err := rows.Err(); err != nil {
+		return DemoPage{}, errors.Wrap(err, "selecting demos")
+	}
+
+	var p DemoPage
+	p.Demos, p.Next, p.Prev = keyset.Paginate(b, demos, demoKeyValues)
+
+	return p, nil
+}
+
+// CreateDemo to insert data into storage
+func (r *Repository) CreateDemo(ctx context.Context, d Demo, events ...outbox.Event) (err error) {
+	ctx, span := startQuery(ctx, "INSERT", insertDemo)
+	defer func() { endQuery(span, err) }()
+
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, insertDemo, d.ID, d.Name, d.Status, d.CreatedAt, d.UpdatedAt, d.Version)
+
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+			return ErrAlreadyExists
+		}
+		if err != nil {
+			return errors.Wrap(err, "inserting demo")
+		}
+
+		return outbox.Enqueue(ctx, tx, events...)
+	})
+}
+
+// UpdateDemo to update data in storage
+func (r *Repository) UpdateDemo(ctx context.Context, d Demo, version int64, events ...outbox.Event) (err error) {
+	ctx, span := startQuery(ctx, "UPDATE", updateDemo)
+	defer func() { endQuery(span, err) }()
+
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, updateDemo, d.ID, d.Name, d.Status, d.UpdatedAt, d.Version, version)
+		if err != nil {
+			return errors.Wrap(err, "updating demo")
+		}
+		if err := affected(ctx, tx, res, d.ID); err != nil {
+			return err
+		}
+
+		return outbox.Enqueue(ctx, tx, events...)
+	})
+}
+
+// DeleteDemo to remove data from storage
+func (r *Repository) DeleteDemo(ctx context.Context, uid string, version int64, events ...outbox.Event) (err error) {
+	ctx, span := startQuery(ctx, "DELETE", deleteDemo)
+	defer func() { endQuery(span, err) }()
+
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, deleteDemo, uid, version)
+		if err != nil {
+			return errors.Wrap(err, "deleting demo")
+		}
+		if err := affected(ctx, tx, res, uid); err != nil {
+			return err
+		}
+
+		return outbox.Enqueue(ctx, tx, events...)
+	})
+}
+
+// startQuery starts the span of statement, an operation on the demo table.
+func startQuery(ctx context.Context, operation, statement string) (context.Context, trace.Span) {
+	return tracer.Start(ctx, operation+" demo",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			semconv.DBOperation(operation),
+			semconv.DBSQLTable("demo"),
+			semconv.DBStatement(statement),
+		),
+	)
+}
+
+// endQuery ends span, recording err unless it is an expected outcome such
+// as a missing demo.
+func endQuery(span trace.Span, err error) {
+	if errors.Is(err, ErrNotFound) || errors.Is(err, ErrAlreadyExists) || errors.Is(err, ErrVersionMismatch) {
+		err = nil
+	}
+	tracing.End(span, err)
+}
+
+// inTx runs fn in a transaction, committed when fn succeeds.
+func (r *Repository) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, "beginning transaction")
+	}
+
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	return errors.Wrap(tx.Commit(), "committing transaction")
+}
+
+// affected tells why res changed no row: the demo identified by uid is
+// either gone or at another version.
+func affected(ctx context.Context, tx *sql.Tx, res sql.Result, uid string) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return errors.Wrap(err, "reading affected rows")
+	}
+	if n > 0 {
+		return nil
+	}
+
+	var exists bool
+	if err := tx.QueryRowContext(ctx, existsDemo, uid).Scan(&exists); err != nil {
+		return errors.Wrap(err, "selecting demo")
+	}
+	if exists {
+		return ErrVersionMismatch
+	}
+
+	return ErrNotFound
+}
+
+// scanner is implemented by sql.Row and sql.Rows.
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanDemo(s scanner) (Demo, error) {
+	var d Demo
+	if err := s.Scan(&d.ID, &d.Name, &d.Status, &d.CreatedAt, &d.UpdatedAt, &d.Version); err != nil {
+		return Demo{}, err
+	}
+
+	d.CreatedAt, d.UpdatedAt = d.CreatedAt.UTC(), d.UpdatedAt.UTC()
+
+	return d, nil
 }
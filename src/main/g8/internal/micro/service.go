@@ -1,18 +1,170 @@
 package micro
 
-import "context"
+import (
+	"context"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/codec"
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/outbox"
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/rest"
+	"github.com/pkg/errors"
+)
+
+// Clock returns the current time, time.Now outside tests
+type Clock func() time.Time
+
+// IDGenerator returns a new entity id, uuid.NewV4 outside tests
+type IDGenerator func() (uuid.UUID, error)
+
+// Precondition checks the current state of a demo before it is changed,
+// such as its ETag against the If-Match header of the request
+type Precondition func(current Demo) error
+
+// ServiceConfig holds the dependencies of Service
+type ServiceConfig struct {
+	Store Store
+	// Clock defaults to time.Now.
+	Clock Clock
+	// NewID defaults to uuid.NewV4.
+	NewID IDGenerator
+	// EventsTopic receives the demo events through the outbox, none are
+	// written when it is empty.
+	EventsTopic string
+	// Serializer encodes the events, as JSON when nil.
+	Serializer *codec.Serializer
+}
 
 // Service struct to hold repository
 type Service struct {
-	repo *Repository
+	store      Store
+	now        Clock
+	newID      IDGenerator
+	topic      string
+	serializer *codec.Serializer
 }
 
 // NewService create service struct
-func NewService(repo *Repository) *Service {
-	return &Service{repo: repo}
+func NewService(c ServiceConfig) *Service {
+	if c.Clock == nil {
+		c.Clock = time.Now
+	}
+	if c.NewID == nil {
+		c.NewID = uuid.NewV4
+	}
+
+	return &Service{store: c.Store, now: c.Clock, newID: c.NewID, topic: c.EventsTopic, serializer: c.Serializer}
 }
 
 // Demo interface to repository
 func (s *Service) Demo(ctx context.Context, uid string) (Demo, error) {
-	return s.repo.Demo(ctx, uid)
+	return s.store.Demo(ctx, uid)
+}
+
+// Demos lists the demos matching f, oldest first
+func (s *Service) Demos(ctx context.Context, f DemoFilter, page rest.PageRequest) (DemoPage, error) {
+	return s.store.Demos(ctx, f, page)
+}
+
+// CreateDemo stores a new demo built from in
+func (s *Service) CreateDemo(ctx context.Context, in DemoInput) (Demo, error) {
+	id, err := s.newID()
+	if err != nil {
+		return Demo{}, errors.Wrap(err, "generating demo id")
+	}
+
+	now := s.timestamp()
+	d := Demo{
+		ID:        id.String(),
+		Name:      in.Name,
+		Status:    in.Status,
+		CreatedAt: now,
+		UpdatedAt: now,
+		Version:   1,
+	}
+
+	events, err := s.events(ctx, EventDemoCreated, d)
+	if err != nil {
+		return Demo{}, err
+	}
+
+	if err := s.store.CreateDemo(ctx, d, events...); err != nil {
+		return Demo{}, err
+	}
+
+	return d, nil
+}
+
+// UpdateDemo replaces the writable fields of the demo identified by uid.
+// It fails with ErrVersionMismatch if the demo changes meanwhile, and with
+// the error of check, if any, when the current demo does not satisfy it.
+func (s *Service) UpdateDemo(ctx context.Context, uid string, in DemoInput, check Precondition) (Demo, error) {
+	d, err := s.current(ctx, uid, check)
+	if err != nil {
+		return Demo{}, err
+	}
+
+	version := d.Version
+	d.Name, d.Status, d.UpdatedAt, d.Version = in.Name, in.Status, s.timestamp(), version+1
+	events, err := s.events(ctx, EventDemoUpdated, d)
+	if err != nil {
+		return Demo{}, err
+	}
+
+	if err := s.store.UpdateDemo(ctx, d, version, events...); err != nil {
+		return Demo{}, err
+	}
+
+	return d, nil
+}
+
+// DeleteDemo removes the demo identified by uid, see UpdateDemo for check
+func (s *Service) DeleteDemo(ctx context.Context, uid string, check Precondition) error {
+	d, err := s.current(ctx, uid, check)
+	if err != nil {
+		return err
+	}
+
+	events, err := s.events(ctx, EventDemoDeleted, d)
+	if err != nil {
+		return err
+	}
+
+	return s.store.DeleteDemo(ctx, uid, d.Version, events...)
+}
+
+// events returns the event of type eventType about d, if events are enabled.
+func (s *Service) events(ctx context.Context, eventType string, d Demo) ([]outbox.Event, error) {
+	if s.topic == "" {
+		return nil, nil
+	}
+
+	payload, headers, err := s.serializer.Marshal(ctx, codec.Subject(s.topic), d)
+	if err != nil {
+		return nil, errors.Wrapf(err, "encoding %s event", eventType)
+	}
+
+	return []outbox.Event{{Topic: s.topic, Key: d.ID, Type: eventType, Payload: payload, Headers: headers}}, nil
+}
+
+// current returns the demo identified by uid once it satisfies check.
+func (s *Service) current(ctx context.Context, uid string, check Precondition) (Demo, error) {
+	d, err := s.store.Demo(ctx, uid)
+	if err != nil {
+		return Demo{}, err
+	}
+
+	if check != nil {
+		if err := check(d); err != nil {
+			return Demo{}, err
+		}
+	}
+
+	return d, nil
+}
+
+// timestamp returns the current time as stored by PostgreSQL, so stored
+// and returned demos compare equal.
+func (s *Service) timestamp() time.Time {
+	return s.now().UTC().Truncate(time.Microsecond)
 }
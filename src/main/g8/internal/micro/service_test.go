@@ -0,0 +1,149 @@
+package micro_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Gympass/gcore/v3/glog"
+	"github.com/gofrs/uuid"
+	"github.com/gympass/$name;format="lower,hyphen"$/internal/micro"
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/codec"
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/consumer"
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/outbox"
+	"github.com/gympass/$name;format="lower,hyphen"$/schemas"
+	"github.com/pkg/errors"
+)
+
+func TestServiceEvents(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	in := micro.DemoInput{Name: "demo", Status: micro.StatusActive}
+
+	stale := func(micro.Demo) error { return micro.ErrVersionMismatch }
+
+	tt := []struct {
+		Name           string
+		Topic          string
+		Run            func(t *testing.T, s *micro.Service)
+		ExpectedEvents []string
+	}{
+		{
+			Name:  "test events of a demo lifecycle",
+			Topic: "demo-events",
+			Run: func(t *testing.T, s *micro.Service) {
+				d, err := s.CreateDemo(ctx, in)
+				if err != nil {
+					t.Fatalf("CreateDemo() error = %v", err)
+				}
+				if _, err := s.UpdateDemo(ctx, d.ID, in, nil); err != nil {
+					t.Fatalf("UpdateDemo() error = %v", err)
+				}
+				if err := s.DeleteDemo(ctx, d.ID, nil); err != nil {
+					t.Fatalf("DeleteDemo() error = %v", err)
+				}
+			},
+			ExpectedEvents: []string{micro.EventDemoCreated, micro.EventDemoUpdated, micro.EventDemoDeleted},
+		},
+		{
+			Name:  "test no event without topic",
+			Topic: "",
+			Run: func(t *testing.T, s *micro.Service) {
+				if _, err := s.CreateDemo(ctx, in); err != nil {
+					t.Fatalf("CreateDemo() error = %v", err)
+				}
+			},
+		},
+		{
+			Name:  "test no event for failed changes",
+			Topic: "demo-events",
+			Run: func(t *testing.T, s *micro.Service) {
+				d, err := s.CreateDemo(ctx, in)
+				if err != nil {
+					t.Fatalf("CreateDemo() error = %v", err)
+				}
+				if _, err := s.UpdateDemo(ctx, d.ID, in, stale); !errors.Is(err, micro.ErrVersionMismatch) {
+					t.Fatalf("UpdateDemo() error = %v, want %v", err, micro.ErrVersionMismatch)
+				}
+				if err := s.DeleteDemo(ctx, uuid.Must(uuid.NewV4()).String(), nil); !errors.Is(err, micro.ErrNotFound) {
+					t.Fatalf("DeleteDemo() error = %v, want %v", err, micro.ErrNotFound)
+				}
+			},
+			ExpectedEvents: []string{micro.EventDemoCreated},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			o := outbox.NewMemoryStore()
+			s := micro.NewService(micro.ServiceConfig{
+				Store:       micro.NewMemoryStore().WithOutbox(o),
+				Clock:       func() time.Time { return now },
+				EventsTopic: tc.Topic,
+			})
+
+			tc.Run(t, s)
+
+			events := o.Pending()
+			if len(events) != len(tc.ExpectedEvents) {
+				t.Fatalf("got %d events, want %d", len(events), len(tc.ExpectedEvents))
+			}
+			for i, e := range events {
+				if e.Type != tc.ExpectedEvents[i] || e.Topic != tc.Topic {
+					t.Fatalf("event %d is %s on %s, want %s on %s", i, e.Type, e.Topic, tc.ExpectedEvents[i], tc.Topic)
+				}
+				if e.Key == "" || len(e.Payload) == 0 {
+					t.Fatalf("event %d has no key or payload", i)
+				}
+			}
+		})
+	}
+}
+
+func TestServiceEventsSchema(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	// The embedded schemas must keep every version fully compatible.
+	r := codec.NewMemoryRegistry(codec.CompatibilityFull)
+	if err := codec.LoadFS(ctx, r, schemas.FS); err != nil {
+		t.Fatalf("LoadFS() error = %v", err)
+	}
+	serializer := codec.NewSerializer(r)
+
+	o := outbox.NewMemoryStore()
+	s := micro.NewService(micro.ServiceConfig{
+		Store:       micro.NewMemoryStore().WithOutbox(o),
+		Clock:       func() time.Time { return now },
+		EventsTopic: "demo-events",
+		Serializer:  serializer,
+	})
+
+	d, err := s.CreateDemo(ctx, micro.DemoInput{Name: "demo", Status: micro.StatusActive})
+	if err != nil {
+		t.Fatalf("CreateDemo() error = %v", err)
+	}
+
+	events := o.Pending()
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want 1", len(events))
+	}
+	e := events[0]
+	if e.Headers[codec.HeaderSchemaID] == "" {
+		t.Fatalf("event headers = %v, want a schema id", e.Headers)
+	}
+
+	var got micro.Demo
+	if err := serializer.Unmarshal(ctx, e.Headers, e.Payload, &got); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if got.ID != d.ID || got.Version != d.Version || !got.CreatedAt.Equal(d.CreatedAt) {
+		t.Fatalf("Unmarshal() = %v, want %v", got, d)
+	}
+
+	h := micro.NewEventHandler(serializer, glog.Noop())
+	m := consumer.Message{Topic: e.Topic, Key: []byte(e.Key), Value: e.Payload, Headers: e.Headers}
+	if err := h.DemoEvent(ctx, m); err != nil {
+		t.Fatalf("DemoEvent() error = %v", err)
+	}
+}
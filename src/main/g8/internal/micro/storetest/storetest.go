@@ -0,0 +1,241 @@
+// Package storetest holds the conformance suite every micro.Store
+// implementation must pass.
+package storetest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/gympass/$name;format="lower,hyphen"$/internal/micro"
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/rest"
+	"github.com/pkg/errors"
+)
+
+// Factory returns an empty store seeded with the given demos
+type Factory func(t *testing.T, seed ...micro.Demo) micro.Store
+
+// Run executes the conformance suite against stores built by newStore
+func Run(t *testing.T, newStore Factory) {
+	t.Run("Demo", func(t *testing.T) { testDemo(t, newStore) })
+	t.Run("Demos", func(t *testing.T) { testDemos(t, newStore) })
+	t.Run("CreateDemo", func(t *testing.T) { testCreateDemo(t, newStore) })
+	t.Run("UpdateDemo", func(t *testing.T) { testUpdateDemo(t, newStore) })
+	t.Run("DeleteDemo", func(t *testing.T) { testDeleteDemo(t, newStore) })
+}
+
+func testDemo(t *testing.T, newStore Factory) {
+	ctx := context.Background()
+	want := NewDemo(t, "demo", micro.StatusActive, time.Now())
+	s := newStore(t, want)
+
+	got, err := s.Demo(ctx, want.ID)
+	if err != nil {
+		t.Fatalf("Demo(%s) error = %v", want.ID, err)
+	}
+	assertDemo(t, got, want)
+
+	missing := newID(t)
+	if _, err := s.Demo(ctx, missing); !errors.Is(err, micro.ErrNotFound) {
+		t.Fatalf("Demo(%s) error = %v, want %v", missing, err, micro.ErrNotFound)
+	}
+}
+
+func testDemos(t *testing.T, newStore Factory) {
+	ctx := context.Background()
+	start := time.Now().Add(-time.Hour)
+
+	var seed []micro.Demo
+	for i, status := range []string{micro.StatusActive, micro.StatusInactive, micro.StatusActive, micro.StatusActive, micro.StatusInactive} {
+		seed = append(seed, NewDemo(t, "demo", status, start.Add(time.Duration(i)*time.Minute)))
+	}
+	// Same creation time as seed[2], ordered by id.
+	twin := NewDemo(t, "twin", micro.StatusActive, seed[2].CreatedAt)
+	seed = append(seed, twin)
+
+	s := newStore(t, seed...)
+	all := []micro.Demo{seed[0], seed[1], seed[2], twin, seed[3], seed[4]}
+	if twin.ID < seed[2].ID {
+		all[2], all[3] = twin, seed[2]
+	}
+
+	// Walk forward two by two, then back from the last page.
+	var (
+		got  []micro.Demo
+		page = rest.PageRequest{Limit: 2}
+		last micro.DemoPage
+	)
+	for i := 0; ; i++ {
+		p, err := s.Demos(ctx, micro.DemoFilter{}, page)
+		if err != nil {
+			t.Fatalf("Demos() page %d error = %v", i, err)
+		}
+		if (i == 0) != (p.Prev == nil) {
+			t.Fatalf("Demos() page %d prev = %+v", i, p.Prev)
+		}
+		got = append(got, p.Demos...)
+		if p.Next == nil {
+			last = p
+			break
+		}
+		page.Cursor = p.Next
+	}
+	assertDemos(t, "forward", got, all)
+
+	p, err := s.Demos(ctx, micro.DemoFilter{}, rest.PageRequest{Limit: 2, Cursor: last.Prev})
+	if err != nil {
+		t.Fatalf("Demos() backward error = %v", err)
+	}
+	assertDemos(t, "backward", p.Demos, all[2:4])
+	if p.Next == nil || p.Prev == nil {
+		t.Fatalf("Demos() backward next = %+v, prev = %+v, want both", p.Next, p.Prev)
+	}
+
+	p, err = s.Demos(ctx, micro.DemoFilter{Status: micro.StatusInactive}, rest.PageRequest{Limit: 10})
+	if err != nil {
+		t.Fatalf("Demos() by status error = %v", err)
+	}
+	assertDemos(t, "by status", p.Demos, []micro.Demo{seed[1], seed[4]})
+
+	p, err = s.Demos(ctx, micro.DemoFilter{Name: "twin"}, rest.PageRequest{Limit: 10})
+	if err != nil {
+		t.Fatalf("Demos() by name error = %v", err)
+	}
+	assertDemos(t, "by name", p.Demos, []micro.Demo{twin})
+
+	p, err = s.Demos(ctx, micro.DemoFilter{Name: "none"}, rest.PageRequest{Limit: 10})
+	if err != nil {
+		t.Fatalf("Demos() empty error = %v", err)
+	}
+	if p.Demos == nil || len(p.Demos) != 0 || p.Next != nil || p.Prev != nil {
+		t.Fatalf("Demos() empty = %+v, want an empty page", p)
+	}
+
+	foreign := &rest.Cursor{Keys: []string{"id"}, Values: []string{seed[0].ID}, Direction: rest.Forward}
+	if _, err := s.Demos(ctx, micro.DemoFilter{}, rest.PageRequest{Limit: 2, Cursor: foreign}); !errors.Is(err, rest.ErrInvalidCursor) {
+		t.Fatalf("Demos() foreign cursor error = %v, want %v", err, rest.ErrInvalidCursor)
+	}
+}
+
+func testCreateDemo(t *testing.T, newStore Factory) {
+	ctx := context.Background()
+	s := newStore(t)
+	want := NewDemo(t, "demo", micro.StatusActive, time.Now())
+
+	if err := s.CreateDemo(ctx, want); err != nil {
+		t.Fatalf("CreateDemo() error = %v", err)
+	}
+
+	got, err := s.Demo(ctx, want.ID)
+	if err != nil {
+		t.Fatalf("Demo(%s) error = %v", want.ID, err)
+	}
+	assertDemo(t, got, want)
+
+	if err := s.CreateDemo(ctx, want); !errors.Is(err, micro.ErrAlreadyExists) {
+		t.Fatalf("CreateDemo() twice error = %v, want %v", err, micro.ErrAlreadyExists)
+	}
+}
+
+func testUpdateDemo(t *testing.T, newStore Factory) {
+	ctx := context.Background()
+	created := time.Now().Add(-time.Hour)
+	d := NewDemo(t, "demo", micro.StatusActive, created)
+	s := newStore(t, d)
+
+	want := d
+	want.Name, want.Status, want.UpdatedAt, want.Version = "renamed", micro.StatusInactive, timestamp(time.Now()), 2
+	// The creation time is not writable.
+	update := want
+	update.CreatedAt = time.Now()
+
+	if err := s.UpdateDemo(ctx, update, d.Version); err != nil {
+		t.Fatalf("UpdateDemo() error = %v", err)
+	}
+
+	got, err := s.Demo(ctx, d.ID)
+	if err != nil {
+		t.Fatalf("Demo(%s) error = %v", d.ID, err)
+	}
+	assertDemo(t, got, want)
+
+	// The stored demo is at version 2 now.
+	stale := want
+	stale.Name, stale.Version = "stale", 2
+	if err := s.UpdateDemo(ctx, stale, d.Version); !errors.Is(err, micro.ErrVersionMismatch) {
+		t.Fatalf("UpdateDemo() stale error = %v, want %v", err, micro.ErrVersionMismatch)
+	}
+
+	missing := NewDemo(t, "missing", micro.StatusActive, time.Now())
+	if err := s.UpdateDemo(ctx, missing, missing.Version); !errors.Is(err, micro.ErrNotFound) {
+		t.Fatalf("UpdateDemo() missing error = %v, want %v", err, micro.ErrNotFound)
+	}
+}
+
+func testDeleteDemo(t *testing.T, newStore Factory) {
+	ctx := context.Background()
+	d := NewDemo(t, "demo", micro.StatusActive, time.Now())
+	s := newStore(t, d)
+
+	if err := s.DeleteDemo(ctx, d.ID, d.Version+1); !errors.Is(err, micro.ErrVersionMismatch) {
+		t.Fatalf("DeleteDemo() stale error = %v, want %v", err, micro.ErrVersionMismatch)
+	}
+	if err := s.DeleteDemo(ctx, d.ID, d.Version); err != nil {
+		t.Fatalf("DeleteDemo() error = %v", err)
+	}
+	if _, err := s.Demo(ctx, d.ID); !errors.Is(err, micro.ErrNotFound) {
+		t.Fatalf("Demo(%s) after delete error = %v, want %v", d.ID, err, micro.ErrNotFound)
+	}
+	if err := s.DeleteDemo(ctx, d.ID, d.Version); !errors.Is(err, micro.ErrNotFound) {
+		t.Fatalf("DeleteDemo() twice error = %v, want %v", err, micro.ErrNotFound)
+	}
+}
+
+// NewDemo returns a demo at version 1 with a random id, created and
+// updated at at
+func NewDemo(t *testing.T, name, status string, at time.Time) micro.Demo {
+	t.Helper()
+
+	at = timestamp(at)
+
+	return micro.Demo{ID: newID(t), Name: name, Status: status, CreatedAt: at, UpdatedAt: at, Version: 1}
+}
+
+// timestamp truncates at to the precision of PostgreSQL.
+func timestamp(at time.Time) time.Time {
+	return at.UTC().Truncate(time.Microsecond)
+}
+
+func newID(t *testing.T) string {
+	t.Helper()
+
+	id, err := uuid.NewV4()
+	if err != nil {
+		t.Fatalf("uuid.NewV4() error = %v", err)
+	}
+
+	return id.String()
+}
+
+func assertDemo(t *testing.T, got, want micro.Demo) {
+	t.Helper()
+
+	if got.ID != want.ID || got.Name != want.Name || got.Status != want.Status ||
+		!got.CreatedAt.Equal(want.CreatedAt) || !got.UpdatedAt.Equal(want.UpdatedAt) || got.Version != want.Version {
+		t.Fatalf("got demo %+v, want %+v", got, want)
+	}
+}
+
+func assertDemos(t *testing.T, name string, got, want []micro.Demo) {
+	t.Helper()
+
+	if len(got) != len(want) {
+		t.Fatalf("%s: got %d demos, want %d", name, len(got), len(want))
+	}
+	for i := range got {
+		if got[i].ID != want[i].ID {
+			t.Fatalf("%s: demo %d is %s, want %s", name, i, got[i].ID, want[i].ID)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+// Package admin serves the endpoints operators use on a running instance:
+// profiling, metrics, health, build information and runtime controls. It
+// listens on an address of its own, the loopback interface by default, so
+// none of them reach the public router. Metrics, health and build
+// information may also be served on a probe address, without the runtime
+// controls, for the scrapers and probes outside the instance.
+package admin
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"time"
+
+	"github.com/Gympass/gcore/v3/gcontext"
+	"github.com/Gympass/gcore/v3/glog"
+	"github.com/gorilla/mux"
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/buildinfo"
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/health"
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultAddress         = "127.0.0.1:7070"
+	defaultMetricsPath     = "/metrics"
+	defaultReadTimeout     = 15 * time.Second
+	defaultShutdownTimeout = 30 * time.Second
+)
+
+// Config used by admin package. Endpoints whose dependency is left nil are
+// not served.
+type Config struct {
+	// Address serves every endpoint, 127.0.0.1:7070 by default. Anyone
+	// reaching it can change the log level, turn maintenance on and read
+	// the configuration, so it should not be exposed.
+	Address string
+	// ProbeAddress also serves metrics, health and build information when
+	// set, such as :7071 for a Prometheus scraper.
+	ProbeAddress string
+	Logger       glog.Logger
+	// Debug serves net/http/pprof under /debug/pprof/.
+	Debug bool
+	// Metrics is served on MetricsPath, /metrics by default.
+	Metrics     http.Handler
+	MetricsPath string
+	Health      *health.Health
+	BuildInfo   *buildinfo.BuildInfo
+	// Level controls the level of the service logger, read and changed on
+	// /log/level.
+	Level *LevelControl
+	// Maintenance is read and toggled on /maintenance.
+	Maintenance *Maintenance
+	// Settings is the effective configuration dumped as JSON on /config,
+	// fields tagged json:"-" are left out.
+	Settings interface{}
+
+	ReadTimeout     time.Duration
+	ShutdownTimeout time.Duration
+}
+
+// Server is the admin HTTP server.
+type Server struct {
+	cfg    Config
+	router *mux.Router
+	probes *mux.Router
+	srvs   []*http.Server
+}
+
+// New creates a Server based on configuration properties.
+func New(c Config) *Server {
+	if c.Address == "" {
+		c.Address = defaultAddress
+	}
+	if c.MetricsPath == "" {
+		c.MetricsPath = defaultMetricsPath
+	}
+	if c.ReadTimeout <= 0 {
+		c.ReadTimeout = defaultReadTimeout
+	}
+	if c.ShutdownTimeout <= 0 {
+		c.ShutdownTimeout = defaultShutdownTimeout
+	}
+	if c.Logger == nil {
+		c.Logger = glog.Noop()
+	}
+
+	s := &Server{cfg: c, router: mux.NewRouter(), probes: mux.NewRouter()}
+	s.routes()
+	s.probeRoutes(s.probes)
+
+	// Profiles run for as long as asked, so only reading is bounded.
+	s.srvs = append(s.srvs, &http.Server{
+		Addr:              c.Address,
+		Handler:           s.router,
+		ReadHeaderTimeout: c.ReadTimeout,
+		ReadTimeout:       c.ReadTimeout,
+	})
+	if c.ProbeAddress != "" {
+		s.srvs = append(s.srvs, &http.Server{
+			Addr:              c.ProbeAddress,
+			Handler:           s.probes,
+			ReadHeaderTimeout: c.ReadTimeout,
+			ReadTimeout:       c.ReadTimeout,
+		})
+	}
+
+	return s
+}
+
+func (s *Server) routes() {
+	r := s.router
+	s.probeRoutes(r)
+
+	if s.cfg.Debug {
+		r.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+		r.HandleFunc("/debug/pprof/profile", pprof.Profile)
+		r.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		r.HandleFunc("/debug/pprof/trace", pprof.Trace)
+		r.PathPrefix("/debug/pprof/").HandlerFunc(pprof.Index)
+	}
+	if s.cfg.Level != nil {
+		r.Path("/log/level").Methods(http.MethodGet).HandlerFunc(s.logLevel)
+		r.Path("/log/level").Methods(http.MethodPut).HandlerFunc(s.setLogLevel)
+	}
+	if s.cfg.Maintenance != nil {
+		r.Path("/maintenance").Methods(http.MethodGet).HandlerFunc(s.maintenance)
+		r.Path("/maintenance").Methods(http.MethodPut).HandlerFunc(s.setMaintenance)
+	}
+	if s.cfg.Settings != nil {
+		r.Path("/config").Methods(http.MethodGet).HandlerFunc(s.settings)
+	}
+}
+
+// probeRoutes adds the read-only endpoints to r.
+func (s *Server) probeRoutes(r *mux.Router) {
+	r.Use(withLogContext)
+
+	if s.cfg.Metrics != nil {
+		r.Path(s.cfg.MetricsPath).Methods(http.MethodGet).Handler(s.cfg.Metrics)
+	}
+	if s.cfg.Health != nil {
+		r.Path("/health/live").Methods(http.MethodGet).HandlerFunc(s.cfg.Health.Live)
+		r.Path("/health/ready").Methods(http.MethodGet).HandlerFunc(s.cfg.Health.Ready)
+	}
+	if s.cfg.BuildInfo != nil {
+		r.Path("/info").Methods(http.MethodGet).HandlerFunc(s.cfg.BuildInfo.Handler)
+	}
+}
+
+// withLogContext gives each request a context collecting log fields.
+func withLogContext(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(gcontext.NewContext(r.Context())))
+	})
+}
+
+// Handler serves the admin endpoints.
+func (s *Server) Handler() http.Handler {
+	return s.router
+}
+
+// ProbeHandler serves the endpoints of the probe address.
+func (s *Server) ProbeHandler() http.Handler {
+	return s.probes
+}
+
+// Start listens on the configured addresses and serves the admin endpoints
+// in the background, returning once the addresses are bound.
+func (s *Server) Start() error {
+	listeners := make([]net.Listener, 0, len(s.srvs))
+	for _, srv := range s.srvs {
+		l, err := net.Listen("tcp", srv.Addr)
+		if err != nil {
+			for _, l := range listeners {
+				_ = l.Close()
+			}
+			return errors.Wrapf(err, "listening for admin requests on %s", srv.Addr)
+		}
+		listeners = append(listeners, l)
+	}
+
+	for i, srv := range s.srvs {
+		go func(srv *http.Server, l net.Listener) {
+			if err := srv.Serve(l); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				ctx := gcontext.NewContext(context.Background())
+				gcontext.AddError(ctx, err)
+				gcontext.AddString(ctx, "admin.address", srv.Addr)
+				s.cfg.Logger.Error(ctx, "Admin server stopped.")
+			}
+		}(srv, listeners[i])
+	}
+
+	return nil
+}
+
+// Shutdown stops the server, waiting for the requests being served until
+// the shutdown timeout.
+func (s *Server) Shutdown() error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.ShutdownTimeout)
+	defer cancel()
+
+	var first error
+	for _, srv := range s.srvs {
+		if err := srv.Shutdown(ctx); err != nil && first == nil {
+			first = err
+		}
+	}
+
+	return errors.Wrap(first, "shutting down admin server")
+}
@@ -0,0 +1,288 @@
+package admin_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Gympass/gcore/v3/glog"
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/admin"
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/buildinfo"
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/health"
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/rest"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type settings struct {
+	Name   string `json:"name"`
+	Secret string `json:"-"`
+}
+
+func TestServer(t *testing.T) {
+	level := zap.NewAtomicLevelAt(zapcore.InfoLevel)
+	maintenance := admin.NewMaintenance()
+
+	s := admin.New(admin.Config{
+		Logger:      glog.Noop(),
+		Debug:       true,
+		Metrics:     http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { _, _ = w.Write([]byte("up 1\n")) }),
+		Health:      health.New(health.Config{Service: "demo", Logger: glog.Noop()}),
+		BuildInfo:   buildinfo.New(buildinfo.Config{Service: "demo", Version: "1.0.0", Logger: glog.Noop()}),
+		Level:       admin.NewLevelControl(&level, glog.Noop()),
+		Maintenance: maintenance,
+		Settings:    settings{Name: "demo", Secret: "s3cr3t"},
+	})
+
+	tt := []struct {
+		Name             string
+		Method           string
+		Path             string
+		Body             string
+		Header           http.Header
+		ExpectedStatus   int
+		ExpectedContains string
+		ExpectedMissing  string
+	}{
+		{
+			Name:             "test pprof index",
+			Method:           http.MethodGet,
+			Path:             "/debug/pprof/",
+			ExpectedStatus:   http.StatusOK,
+			ExpectedContains: "goroutine",
+		},
+		{
+			Name:             "test metrics",
+			Method:           http.MethodGet,
+			Path:             "/metrics",
+			ExpectedStatus:   http.StatusOK,
+			ExpectedContains: "up 1",
+		},
+		{
+			Name:           "test liveness",
+			Method:         http.MethodGet,
+			Path:           "/health/live",
+			ExpectedStatus: http.StatusOK,
+		},
+		{
+			Name:             "test build info",
+			Method:           http.MethodGet,
+			Path:             "/info",
+			ExpectedStatus:   http.StatusOK,
+			ExpectedContains: `"version":"1.0.0"`,
+		},
+		{
+			Name:             "test log level",
+			Method:           http.MethodGet,
+			Path:             "/log/level",
+			ExpectedStatus:   http.StatusOK,
+			ExpectedContains: `"level":"info"`,
+		},
+		{
+			Name:             "test log level changed",
+			Method:           http.MethodPut,
+			Path:             "/log/level",
+			Body:             `{"level":"debug"}`,
+			Header:           http.Header{admin.ChangedByHeader: {"jane"}},
+			ExpectedStatus:   http.StatusOK,
+			ExpectedContains: `"changed_by":"jane (192.0.2.1:1234)"`,
+		},
+		{
+			Name:           "test invalid log level ttl",
+			Method:         http.MethodPut,
+			Path:           "/log/level",
+			Body:           `{"level":"warn","ttl":"soon"}`,
+			ExpectedStatus: http.StatusBadRequest,
+		},
+		{
+			Name:             "test log level last change",
+			Method:           http.MethodGet,
+			Path:             "/log/level",
+			ExpectedStatus:   http.StatusOK,
+			ExpectedContains: `"previous_level":"info"`,
+		},
+		{
+			Name:           "test unknown log level",
+			Method:         http.MethodPut,
+			Path:           "/log/level",
+			Body:           `{"level":"verbose"}`,
+			ExpectedStatus: http.StatusBadRequest,
+		},
+		{
+			Name:             "test maintenance turned on",
+			Method:           http.MethodPut,
+			Path:             "/maintenance",
+			Body:             `{"enabled":true}`,
+			ExpectedStatus:   http.StatusOK,
+			ExpectedContains: `"enabled":true`,
+		},
+		{
+			Name:             "test configuration dump",
+			Method:           http.MethodGet,
+			Path:             "/config",
+			ExpectedStatus:   http.StatusOK,
+			ExpectedContains: `"name":"demo"`,
+			ExpectedMissing:  "s3cr3t",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.Method, tc.Path, strings.NewReader(tc.Body))
+			if tc.Body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			for k, v := range tc.Header {
+				req.Header[k] = v
+			}
+			rec := httptest.NewRecorder()
+
+			s.Handler().ServeHTTP(rec, req)
+
+			if rec.Code != tc.ExpectedStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tc.ExpectedStatus, rec.Body)
+			}
+			if !strings.Contains(rec.Body.String(), tc.ExpectedContains) {
+				t.Fatalf("body = %s, want %s", rec.Body, tc.ExpectedContains)
+			}
+			if tc.ExpectedMissing != "" && strings.Contains(rec.Body.String(), tc.ExpectedMissing) {
+				t.Fatalf("body = %s, should not have %s", rec.Body, tc.ExpectedMissing)
+			}
+		})
+	}
+
+	if level.Level() != zapcore.DebugLevel {
+		t.Fatalf("log level = %s, want debug", level.Level())
+	}
+	if !maintenance.Enabled() {
+		t.Fatalf("maintenance is off, want on")
+	}
+}
+
+func TestServerEndpointsOptional(t *testing.T) {
+	s := admin.New(admin.Config{})
+
+	for _, path := range []string{"/debug/pprof/", "/metrics", "/log/level", "/config"} {
+		rec := httptest.NewRecorder()
+		s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusNotFound {
+			t.Fatalf("%s status = %d, want %d", path, rec.Code, http.StatusNotFound)
+		}
+	}
+}
+
+func TestServerProbes(t *testing.T) {
+	level := zap.NewAtomicLevelAt(zapcore.InfoLevel)
+
+	s := admin.New(admin.Config{
+		Debug:        true,
+		ProbeAddress: ":0",
+		Metrics:      http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { _, _ = w.Write([]byte("up 1\n")) }),
+		Health:       health.New(health.Config{Service: "demo", Logger: glog.Noop()}),
+		Level:        admin.NewLevelControl(&level, glog.Noop()),
+		Maintenance:  admin.NewMaintenance(),
+		Settings:     settings{Name: "demo"},
+	})
+
+	tt := []struct {
+		Name           string
+		Method         string
+		Path           string
+		ExpectedStatus int
+	}{
+		{Name: "test metrics", Method: http.MethodGet, Path: "/metrics", ExpectedStatus: http.StatusOK},
+		{Name: "test liveness", Method: http.MethodGet, Path: "/health/live", ExpectedStatus: http.StatusOK},
+		{Name: "test pprof not served", Method: http.MethodGet, Path: "/debug/pprof/", ExpectedStatus: http.StatusNotFound},
+		{Name: "test log level not served", Method: http.MethodPut, Path: "/log/level", ExpectedStatus: http.StatusNotFound},
+		{Name: "test maintenance not served", Method: http.MethodPut, Path: "/maintenance", ExpectedStatus: http.StatusNotFound},
+		{Name: "test config not served", Method: http.MethodGet, Path: "/config", ExpectedStatus: http.StatusNotFound},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			s.ProbeHandler().ServeHTTP(rec, httptest.NewRequest(tc.Method, tc.Path, strings.NewReader(`{"level":"debug"}`)))
+			if rec.Code != tc.ExpectedStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tc.ExpectedStatus)
+			}
+		})
+	}
+
+	if level.Level() != zapcore.InfoLevel {
+		t.Fatalf("log level = %s, want info", level.Level())
+	}
+}
+
+func TestMaintenance(t *testing.T) {
+	m := admin.NewMaintenance("/health")
+	h := m.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	tt := []struct {
+		Name                string
+		Enabled             bool
+		Path                string
+		Accept              string
+		ExpectedStatus      int
+		ExpectedContentType string
+	}{
+		{
+			Name:           "test served when off",
+			Path:           "/v1/demos",
+			ExpectedStatus: http.StatusNoContent,
+		},
+		{
+			Name:                "test refused when on",
+			Enabled:             true,
+			Path:                "/v1/demos",
+			ExpectedStatus:      http.StatusServiceUnavailable,
+			ExpectedContentType: rest.ContentTypeProblemJSON,
+		},
+		{
+			Name:                "test refused in the legacy format",
+			Enabled:             true,
+			Path:                "/v1/demos",
+			Accept:              "application/json",
+			ExpectedStatus:      http.StatusServiceUnavailable,
+			ExpectedContentType: rest.ContentTypeJSON,
+		},
+		{
+			Name:           "test exempt paths served when on",
+			Enabled:        true,
+			Path:           "/health/ready",
+			ExpectedStatus: http.StatusNoContent,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			m.Set(tc.Enabled)
+
+			req := httptest.NewRequest(http.MethodGet, tc.Path, nil)
+			req.Header.Set("Accept", tc.Accept)
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tc.ExpectedStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tc.ExpectedStatus)
+			}
+			if ct := rec.Header().Get("Content-Type"); tc.ExpectedContentType != "" && ct != tc.ExpectedContentType {
+				t.Fatalf("content type = %s, want %s", ct, tc.ExpectedContentType)
+			}
+			if rec.Code == http.StatusServiceUnavailable && rec.Header().Get("Retry-After") == "" {
+				t.Fatalf("no Retry-After header")
+			}
+			if tc.ExpectedContentType == rest.ContentTypeProblemJSON {
+				var p struct {
+					Type string `json:"type"`
+				}
+				if err := json.NewDecoder(rec.Body).Decode(&p); err != nil || p.Type != admin.ProblemTypeMaintenance {
+					t.Fatalf("problem type = %q (%v), want %s", p.Type, err, admin.ProblemTypeMaintenance)
+				}
+			}
+		})
+	}
+}
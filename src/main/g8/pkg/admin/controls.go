@@ -0,0 +1,110 @@
+package admin
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/Gympass/gcore/v3/gcontext"
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/rest"
+	"go.uber.org/zap/zapcore"
+)
+
+// ChangedByHeader names who changes the log level, reported along with the
+// address of the request.
+const ChangedByHeader = "X-Changed-By"
+
+// LogLevel is the body of /log/level.
+type LogLevel struct {
+	Level string `json:"level" validate:"required,oneof=debug|info|warn|error|dpanic|panic|fatal"`
+	// TTL is how long the level lasts, such as "15m", before reverting to
+	// the last permanent one. Changes without TTL are permanent.
+	TTL string `json:"ttl,omitempty"`
+}
+
+// LogLevelStatus is the body of /log/level responses.
+type LogLevelStatus struct {
+	Level      string       `json:"level"`
+	LastChange *LevelChange `json:"last_change,omitempty"`
+}
+
+// MaintenanceMode is the body of /maintenance.
+type MaintenanceMode struct {
+	Enabled bool `json:"enabled"`
+}
+
+func (s *Server) logLevel(w http.ResponseWriter, r *http.Request) {
+	st := LogLevelStatus{Level: s.cfg.Level.Level().String()}
+	if c, ok := s.cfg.Level.Last(); ok {
+		st.LastChange = &c
+	}
+
+	s.send(w, r, st)
+}
+
+func (s *Server) setLogLevel(w http.ResponseWriter, r *http.Request) {
+	var req LogLevel
+	if err := rest.Bind(r, &req); err != nil {
+		rest.WriteError(w, r, err)
+		return
+	}
+
+	var level zapcore.Level
+	if err := level.UnmarshalText([]byte(req.Level)); err != nil {
+		rest.WriteError(w, r, err)
+		return
+	}
+
+	var ttl time.Duration
+	if req.TTL != "" {
+		d, err := time.ParseDuration(req.TTL)
+		if err != nil || d <= 0 {
+			rest.WriteError(w, r, rest.FieldErrors{{Field: "ttl", Rule: rest.RuleType, Message: "ttl must be a positive duration such as 15m"}})
+			return
+		}
+		ttl = d
+	}
+
+	by := r.RemoteAddr
+	if name := r.Header.Get(ChangedByHeader); name != "" {
+		by = name + " (" + r.RemoteAddr + ")"
+	}
+
+	c := s.cfg.Level.Set(r.Context(), level, ttl, by)
+
+	s.send(w, r, LogLevelStatus{Level: c.Level, LastChange: &c})
+}
+
+func (s *Server) maintenance(w http.ResponseWriter, r *http.Request) {
+	s.send(w, r, MaintenanceMode{Enabled: s.cfg.Maintenance.Enabled()})
+}
+
+func (s *Server) setMaintenance(w http.ResponseWriter, r *http.Request) {
+	var req MaintenanceMode
+	if err := rest.Bind(r, &req); err != nil {
+		rest.WriteError(w, r, err)
+		return
+	}
+
+	s.cfg.Maintenance.Set(req.Enabled)
+
+	if req.Enabled {
+		s.cfg.Logger.Warn(r.Context(), "Maintenance mode on.")
+	} else {
+		s.cfg.Logger.Warn(r.Context(), "Maintenance mode off.")
+	}
+
+	s.send(w, r, MaintenanceMode{Enabled: req.Enabled})
+}
+
+func (s *Server) settings(w http.ResponseWriter, r *http.Request) {
+	s.send(w, r, s.cfg.Settings)
+}
+
+func (s *Server) send(w http.ResponseWriter, r *http.Request, payload interface{}) {
+	w.Header().Set("Cache-Control", "no-store")
+
+	if err := rest.Send(w, r, http.StatusOK, payload); err != nil {
+		gcontext.AddError(r.Context(), err)
+		s.cfg.Logger.Error(r.Context(), "Failed to write admin response.")
+	}
+}
@@ -0,0 +1,192 @@
+package admin
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync"
+	"time"
+
+	"github.com/Gympass/gcore/v3/gcontext"
+	"github.com/Gympass/gcore/v3/glog"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// LevelChange records the last change of the log level.
+type LevelChange struct {
+	Level    string    `json:"level"`
+	Previous string    `json:"previous_level"`
+	By       string    `json:"changed_by"`
+	At       time.Time `json:"changed_at"`
+	// Until is when the level reverts, nil when the change is permanent.
+	Until *time.Time `json:"reverts_at,omitempty"`
+}
+
+// LevelControl changes the level of the service logger at runtime. A change
+// with a TTL reverts to the last permanent level once it expires, and every
+// change is logged along with who made it.
+type LevelControl struct {
+	level  *zap.AtomicLevel
+	logger glog.Logger
+
+	mu sync.Mutex
+	// base is the level reverted to when a temporary change expires.
+	base   zapcore.Level
+	last   *LevelChange
+	revert *time.Timer
+	// changes counts the changes, so a timer firing after a newer change
+	// leaves it alone.
+	changes uint64
+}
+
+// NewLevelControl creates a LevelControl of level, starting from its
+// current level.
+func NewLevelControl(level *zap.AtomicLevel, logger glog.Logger) *LevelControl {
+	if logger == nil {
+		logger = glog.Noop()
+	}
+
+	return &LevelControl{level: level, logger: logger, base: level.Level()}
+}
+
+// Level returns the current level.
+func (c *LevelControl) Level() zapcore.Level {
+	return c.level.Level()
+}
+
+// Last returns the last change, if any.
+func (c *LevelControl) Last() (LevelChange, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.last == nil {
+		return LevelChange{}, false
+	}
+
+	return *c.last, true
+}
+
+// Set changes the level to l on behalf of by. A positive ttl reverts the
+// change once it expires, otherwise l becomes the level reverted to.
+func (c *LevelControl) Set(ctx context.Context, l zapcore.Level, ttl time.Duration, by string) LevelChange {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.set(ctx, l, ttl, by)
+}
+
+// Raise makes the logger one level more verbose, down to debug.
+func (c *LevelControl) Raise(ctx context.Context, ttl time.Duration, by string) LevelChange {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	l := c.level.Level() - 1
+	if l < zapcore.DebugLevel {
+		l = zapcore.DebugLevel
+	}
+
+	return c.set(ctx, l, ttl, by)
+}
+
+// Lower makes the logger one level less verbose, up to error.
+func (c *LevelControl) Lower(ctx context.Context, ttl time.Duration, by string) LevelChange {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	l := c.level.Level() + 1
+	if l > zapcore.ErrorLevel {
+		l = zapcore.ErrorLevel
+	}
+
+	return c.set(ctx, l, ttl, by)
+}
+
+func (c *LevelControl) set(ctx context.Context, l zapcore.Level, ttl time.Duration, by string) LevelChange {
+	if c.revert != nil {
+		c.revert.Stop()
+		c.revert = nil
+	}
+	c.changes++
+
+	change := LevelChange{
+		Level:    l.String(),
+		Previous: c.level.Level().String(),
+		By:       by,
+		At:       time.Now().UTC(),
+	}
+
+	if ttl > 0 {
+		until := change.At.Add(ttl)
+		change.Until = &until
+		n := c.changes
+		c.revert = time.AfterFunc(ttl, func() { c.expire(n) })
+	} else {
+		c.base = l
+	}
+
+	c.apply(ctx, l, change)
+
+	return change
+}
+
+// OnSignal raises the level when raise arrives and lowers it when lower
+// arrives, such as SIGUSR1 and SIGUSR2, each change lasting ttl.
+func (c *LevelControl) OnSignal(raise, lower os.Signal, ttl time.Duration) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, raise, lower)
+
+	go func() {
+		for sig := range ch {
+			ctx := gcontext.NewContext(context.Background())
+			by := "signal " + sig.String()
+
+			if sig == raise {
+				c.Raise(ctx, ttl, by)
+			} else {
+				c.Lower(ctx, ttl, by)
+			}
+		}
+	}()
+}
+
+// expire reverts the temporary change n, unless another one followed.
+func (c *LevelControl) expire(n uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if n != c.changes {
+		return
+	}
+
+	c.revert = nil
+	c.changes++
+	c.apply(gcontext.NewContext(context.Background()), c.base, LevelChange{
+		Level:    c.base.String(),
+		Previous: c.level.Level().String(),
+		By:       "ttl",
+		At:       time.Now().UTC(),
+	})
+}
+
+// apply sets the level to l and logs change. The change is logged under
+// the more verbose of both levels, so lowering to error still reports it.
+func (c *LevelControl) apply(ctx context.Context, l zapcore.Level, change LevelChange) {
+	gcontext.AddString(ctx, "log.level", change.Level)
+	gcontext.AddString(ctx, "log.previous_level", change.Previous)
+	gcontext.AddString(ctx, "log.changed_by", change.By)
+	gcontext.AddString(ctx, "log.changed_at", change.At.Format(time.RFC3339))
+	if change.Until != nil {
+		gcontext.AddString(ctx, "log.reverts_at", change.Until.Format(time.RFC3339))
+	}
+
+	if l > c.level.Level() {
+		c.logger.Warn(ctx, "Log level changed.")
+		c.level.SetLevel(l)
+	} else {
+		c.level.SetLevel(l)
+		c.logger.Warn(ctx, "Log level changed.")
+	}
+
+	c.last = &change
+}
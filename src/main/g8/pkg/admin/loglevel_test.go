@@ -0,0 +1,101 @@
+package admin_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Gympass/gcore/v3/glog"
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/admin"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestLevelControl(t *testing.T) {
+	ctx := context.Background()
+	level := zap.NewAtomicLevelAt(zapcore.InfoLevel)
+	c := admin.NewLevelControl(&level, glog.Noop())
+
+	if _, ok := c.Last(); ok {
+		t.Fatalf("Last() found a change before any")
+	}
+
+	tt := []struct {
+		Name          string
+		Change        func() admin.LevelChange
+		ExpectedLevel zapcore.Level
+	}{
+		{
+			Name:          "test raise",
+			Change:        func() admin.LevelChange { return c.Raise(ctx, 0, "test") },
+			ExpectedLevel: zapcore.DebugLevel,
+		},
+		{
+			Name:          "test raise stops at debug",
+			Change:        func() admin.LevelChange { return c.Raise(ctx, 0, "test") },
+			ExpectedLevel: zapcore.DebugLevel,
+		},
+		{
+			Name:          "test lower",
+			Change:        func() admin.LevelChange { return c.Lower(ctx, 0, "test") },
+			ExpectedLevel: zapcore.InfoLevel,
+		},
+		{
+			Name:          "test set",
+			Change:        func() admin.LevelChange { return c.Set(ctx, zapcore.ErrorLevel, 0, "test") },
+			ExpectedLevel: zapcore.ErrorLevel,
+		},
+		{
+			Name:          "test lower stops at error",
+			Change:        func() admin.LevelChange { return c.Lower(ctx, 0, "test") },
+			ExpectedLevel: zapcore.ErrorLevel,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			change := tc.Change()
+
+			if level.Level() != tc.ExpectedLevel || change.Level != tc.ExpectedLevel.String() {
+				t.Fatalf("level = %s (%+v), want %s", level.Level(), change, tc.ExpectedLevel)
+			}
+			if change.By != "test" || change.At.IsZero() || change.Until != nil {
+				t.Fatalf("change = %+v, want a permanent one by test", change)
+			}
+			if last, _ := c.Last(); last.Level != change.Level || last.At != change.At {
+				t.Fatalf("Last() = %+v, want %+v", last, change)
+			}
+		})
+	}
+}
+
+func TestLevelControlTTL(t *testing.T) {
+	ctx := context.Background()
+	level := zap.NewAtomicLevelAt(zapcore.WarnLevel)
+	c := admin.NewLevelControl(&level, glog.Noop())
+
+	change := c.Set(ctx, zapcore.DebugLevel, 20*time.Millisecond, "test")
+	if change.Until == nil || !change.Until.After(change.At) {
+		t.Fatalf("change = %+v, want one reverting later", change)
+	}
+
+	// A newer temporary change keeps its own TTL.
+	c.Set(ctx, zapcore.InfoLevel, time.Hour, "test")
+	time.Sleep(50 * time.Millisecond)
+	if level.Level() != zapcore.InfoLevel {
+		t.Fatalf("level = %s, want info until the newer change expires", level.Level())
+	}
+
+	c.Set(ctx, zapcore.DebugLevel, 20*time.Millisecond, "test")
+	deadline := time.Now().Add(time.Second)
+	for level.Level() != zapcore.WarnLevel {
+		if time.Now().After(deadline) {
+			t.Fatalf("level = %s, want warn once the TTL expired", level.Level())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if last, _ := c.Last(); last.By != "ttl" || last.Previous != "debug" {
+		t.Fatalf("Last() = %+v, want the revert", last)
+	}
+}
@@ -0,0 +1,69 @@
+package admin
+
+import (
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/rest"
+	"github.com/pkg/errors"
+)
+
+// ProblemTypeMaintenance identifies requests refused during maintenance.
+const ProblemTypeMaintenance = "urn:problem-type:maintenance"
+
+// ErrMaintenance is returned for requests refused during maintenance.
+var ErrMaintenance = errors.New("service is under maintenance")
+
+func init() {
+	rest.RegisterProblem(ErrMaintenance, rest.ProblemType{
+		Type:   ProblemTypeMaintenance,
+		Title:  "Under maintenance",
+		Status: http.StatusServiceUnavailable,
+	})
+}
+
+// Maintenance is a switch refusing API requests while on.
+type Maintenance struct {
+	on     atomic.Bool
+	except []string
+}
+
+// NewMaintenance creates a Maintenance, off, that keeps serving the paths
+// starting with one of except, such as the health endpoints.
+func NewMaintenance(except ...string) *Maintenance {
+	return &Maintenance{except: except}
+}
+
+// Enabled tells whether requests are refused.
+func (m *Maintenance) Enabled() bool {
+	return m.on.Load()
+}
+
+// Set turns maintenance on or off.
+func (m *Maintenance) Set(on bool) {
+	m.on.Store(on)
+}
+
+// Middleware answers 503 while maintenance is on, see rest.WriteError; add it
+// with Router.Use.
+func (m *Maintenance) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !m.Enabled() || m.exempt(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("Retry-After", "60")
+		rest.WriteError(w, r, ErrMaintenance)
+	})
+}
+
+func (m *Maintenance) exempt(path string) bool {
+	for _, prefix := range m.except {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,154 @@
+// Package buildinfo describes the running binary: the values injected at
+// build time through ldflags, the VCS and module data recorded by the Go
+// toolchain and a few runtime facts.
+package buildinfo
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"time"
+
+	"github.com/Gympass/gcore/v3/gcontext"
+	"github.com/Gympass/gcore/v3/glog"
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/rest"
+)
+
+// Config used by buildinfo package
+type Config struct {
+	Service   string
+	Version   string
+	BuildTime string
+	GoVersion string
+	// Features lists the optional components and whether they are enabled.
+	Features map[string]bool
+	Logger   glog.Logger
+}
+
+// Module is a dependency compiled into the binary.
+type Module struct {
+	Path    string `json:"path"`
+	Version string `json:"version"`
+	Replace string `json:"replace,omitempty"`
+}
+
+// Info is the description of the running binary.
+type Info struct {
+	Service      string          `json:"service,omitempty"`
+	Version      string          `json:"version"`
+	BuildTime    string          `json:"build_time"`
+	GoVersion    string          `json:"go_version"`
+	Runtime      string          `json:"runtime"`
+	Revision     string          `json:"revision,omitempty"`
+	RevisionTime string          `json:"revision_time,omitempty"`
+	Modified     bool            `json:"modified,omitempty"`
+	Uptime       string          `json:"uptime,omitempty"`
+	GOMAXPROCS   int             `json:"gomaxprocs"`
+	NumCPU       int             `json:"num_cpu"`
+	Features     map[string]bool `json:"features,omitempty"`
+	Dependencies []Module        `json:"dependencies,omitempty"`
+}
+
+// BuildInfo serves the description of the running binary.
+type BuildInfo struct {
+	cfg     Config
+	started time.Time
+}
+
+// New creates a BuildInfo based on configuration properties
+func New(cfg Config) *BuildInfo {
+	return &BuildInfo{cfg: cfg, started: time.Now()}
+}
+
+// Info returns the current description of the binary.
+func (b *BuildInfo) Info() Info {
+	info := Info{
+		Service:    b.cfg.Service,
+		Version:    b.cfg.Version,
+		BuildTime:  b.cfg.BuildTime,
+		GoVersion:  b.cfg.GoVersion,
+		Runtime:    runtime.Version(),
+		Uptime:     time.Since(b.started).Round(time.Second).String(),
+		GOMAXPROCS: runtime.GOMAXPROCS(0),
+		NumCPU:     runtime.NumCPU(),
+		Features:   b.cfg.Features,
+	}
+
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+
+	for _, s := range bi.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			info.Revision = s.Value
+		case "vcs.time":
+			info.RevisionTime = s.Value
+		case "vcs.modified":
+			info.Modified = s.Value == "true"
+		}
+	}
+
+	for _, dep := range bi.Deps {
+		m := Module{Path: dep.Path, Version: dep.Version}
+		if dep.Replace != nil {
+			m.Replace = dep.Replace.Path + " " + dep.Replace.Version
+		}
+		info.Dependencies = append(info.Dependencies, m)
+	}
+
+	sort.Slice(info.Dependencies, func(i, j int) bool {
+		return info.Dependencies[i].Path < info.Dependencies[j].Path
+	})
+
+	return info
+}
+
+// Handler ...
+// ShowEntity godoc
+// @Summary Build and runtime information
+// @Description Version, VCS revision, dependencies, uptime and enabled features of the running binary
+// @Produce  json
+// @Success 200 {object} Info
+// @Router /info [get]
+func (b *BuildInfo) Handler(w http.ResponseWriter, r *http.Request) {
+	if err := rest.Send(w, r, http.StatusOK, b.Info()); err != nil {
+		gcontext.AddError(r.Context(), err)
+		b.cfg.Logger.Error(r.Context(), "Failed to write build info.")
+	}
+}
+
+// Write prints the build description to w, as JSON when asJSON is set,
+// for the version command. Uptime and features are left out since the
+// command does not start the service.
+func Write(w io.Writer, cfg Config, asJSON bool) error {
+	info := New(cfg).Info()
+	info.Uptime, info.Features = "", nil
+
+	if asJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(info)
+	}
+
+	_, err := fmt.Fprintf(w,
+		"version:    %s\nbuild time: %s\ngo version: %s\nruntime:    %s\nrevision:   %s\nmodified:   %v\n",
+		info.Version, info.BuildTime, info.GoVersion, info.Runtime, info.Revision, info.Modified,
+	)
+	if err != nil {
+		return err
+	}
+
+	for _, dep := range info.Dependencies {
+		if _, err := fmt.Fprintf(w, "dep:        %s %s\n", dep.Path, dep.Version); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,61 @@
+package buildinfo
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/Gympass/gcore/v3/glog"
+	"github.com/Gympass/gcore/v3/gtest"
+)
+
+func TestHandler(t *testing.T) {
+	b := New(Config{
+		Service:   "test-service",
+		Version:   "v1.2.3",
+		BuildTime: "1683000000",
+		GoVersion: "go1.19",
+		Features:  map[string]bool{"swagger": true, "datadog": false},
+		Logger:    glog.Noop(),
+	})
+
+	rr := httptest.NewRecorder()
+	b.Handler(rr, httptest.NewRequest(http.MethodGet, "/info", nil))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status %d and got %d", http.StatusOK, rr.Code)
+	}
+
+	var info Info
+	gtest.AssertNil(t, json.NewDecoder(rr.Body).Decode(&info))
+
+	if info.Version != "v1.2.3" || info.Service != "test-service" || info.GOMAXPROCS != runtime.GOMAXPROCS(0) {
+		t.Fatalf("Unexpected info %+v", info)
+	}
+
+	if !info.Features["swagger"] || info.Features["datadog"] || info.Uptime == "" {
+		t.Fatalf("Unexpected info %+v", info)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	cfg := Config{Version: "v1.2.3", Features: map[string]bool{"swagger": true}}
+
+	var text bytes.Buffer
+	gtest.AssertNil(t, Write(&text, cfg, false))
+	if !strings.Contains(text.String(), "version:    v1.2.3\n") {
+		t.Fatalf("Unexpected output %s", text.String())
+	}
+
+	var info Info
+	var js bytes.Buffer
+	gtest.AssertNil(t, Write(&js, cfg, true))
+	gtest.AssertNil(t, json.Unmarshal(js.Bytes(), &info))
+	if info.Version != "v1.2.3" || info.Features != nil {
+		t.Fatalf("Unexpected info %+v", info)
+	}
+}
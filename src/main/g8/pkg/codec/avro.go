@@ -0,0 +1,91 @@
+package codec
+
+import (
+	"sync"
+
+	"github.com/hamba/avro/v2"
+	"github.com/pkg/errors"
+)
+
+// AvroCodec encodes payloads as Avro binary. Structs are mapped to records
+// by their json tags. Payloads are decoded with the schema they were
+// written with, so fields unknown to the struct are skipped and fields
+// missing from the payload are left zero.
+type AvroCodec struct {
+	api     avro.API
+	schemas sync.Map
+}
+
+// NewAvroCodec creates an AvroCodec.
+func NewAvroCodec() *AvroCodec {
+	return &AvroCodec{api: avro.Config{TagKey: "json"}.Freeze()}
+}
+
+// Format implements Codec.
+func (c *AvroCodec) Format() Format { return FormatAvro }
+
+// Validate implements Codec.
+func (c *AvroCodec) Validate(definition string) error {
+	_, err := c.parse(definition)
+	return err
+}
+
+// Compatible implements Codec, following the schema resolution rules of
+// the Avro specification.
+func (c *AvroCodec) Compatible(reader, writer string) error {
+	r, err := c.parse(reader)
+	if err != nil {
+		return err
+	}
+	w, err := c.parse(writer)
+	if err != nil {
+		return err
+	}
+
+	if err := avro.NewSchemaCompatibility().Compatible(r, w); err != nil {
+		return errors.Wrap(ErrIncompatible, err.Error())
+	}
+
+	return nil
+}
+
+// Encode implements Codec.
+func (c *AvroCodec) Encode(s Schema, v interface{}) ([]byte, error) {
+	schema, err := c.parse(s.Definition)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := c.api.Marshal(schema, v)
+	if err != nil {
+		return nil, errors.Wrap(ErrUnsupportedPayload, err.Error())
+	}
+
+	return b, nil
+}
+
+// Decode implements Codec.
+func (c *AvroCodec) Decode(s Schema, data []byte, v interface{}) error {
+	schema, err := c.parse(s.Definition)
+	if err != nil {
+		return err
+	}
+
+	return errors.Wrap(c.api.Unmarshal(schema, data, v), "decoding avro")
+}
+
+// parse returns the schema of definition, parsed once. Every definition
+// gets its own cache of named types, so versions do not clash.
+func (c *AvroCodec) parse(definition string) (avro.Schema, error) {
+	if s, ok := c.schemas.Load(definition); ok {
+		return s.(avro.Schema), nil
+	}
+
+	s, err := avro.ParseWithCache(definition, "", &avro.SchemaCache{})
+	if err != nil {
+		return nil, errors.Wrap(ErrInvalidSchema, err.Error())
+	}
+	c.schemas.Store(definition, s)
+
+	return s, nil
+}
@@ -0,0 +1,103 @@
+// Package codec encodes message payloads with versioned schemas. Schemas
+// are kept by a Registry, which refuses versions breaking compatibility,
+// and messages carry the id of their schema in a header so consumers can
+// decode payloads written with older versions.
+package codec
+
+import (
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// HeaderSchemaID is the message header holding the id of the schema of
+// the payload.
+const HeaderSchemaID = "schema-id"
+
+// Format is a serialization format.
+type Format string
+
+// Supported formats
+const (
+	FormatJSON     Format = "json"
+	FormatAvro     Format = "avro"
+	FormatProtobuf Format = "protobuf"
+)
+
+var (
+	// ErrUnknownFormat is returned for formats without a registered Codec.
+	ErrUnknownFormat = errors.New("unknown schema format")
+	// ErrUnknownSchema is returned for schemas missing from the registry.
+	ErrUnknownSchema = errors.New("unknown schema")
+	// ErrInvalidSchema is returned for definitions a Codec cannot parse.
+	ErrInvalidSchema = errors.New("invalid schema")
+	// ErrIncompatible is returned when a reader schema cannot decode the
+	// payloads of a writer schema.
+	ErrIncompatible = errors.New("incompatible schema")
+	// ErrUnsupportedPayload is returned for values a Codec cannot encode
+	// or decode into.
+	ErrUnsupportedPayload = errors.New("unsupported payload")
+)
+
+// Schema is a version of the schema of a subject.
+type Schema struct {
+	// ID identifies the schema among every subject of the registry.
+	ID      int    `json:"id"`
+	Subject string `json:"subject"`
+	Version int    `json:"version"`
+	Format  Format `json:"format"`
+	// Definition is the schema in the language of its format.
+	Definition string `json:"schema"`
+}
+
+// Subject returns the subject of the payloads of topic.
+func Subject(topic string) string {
+	return topic + "-value"
+}
+
+// Codec encodes payloads in one format.
+type Codec interface {
+	Format() Format
+	// Validate checks that definition is a valid schema.
+	Validate(definition string) error
+	// Compatible returns an error wrapping ErrIncompatible unless payloads
+	// written with the writer schema can be decoded with the reader schema.
+	Compatible(reader, writer string) error
+	// Encode writes v with schema s.
+	Encode(s Schema, v interface{}) ([]byte, error)
+	// Decode reads into v a payload written with schema s.
+	Decode(s Schema, data []byte, v interface{}) error
+}
+
+var codecs = struct {
+	sync.RWMutex
+	byFormat map[Format]Codec
+}{
+	byFormat: map[Format]Codec{
+		FormatJSON:     JSONCodec{},
+		FormatAvro:     NewAvroCodec(),
+		FormatProtobuf: ProtobufCodec{},
+	},
+}
+
+// Register makes c available to registries and serializers, replacing any
+// codec of the same format.
+func Register(c Codec) {
+	codecs.Lock()
+	defer codecs.Unlock()
+
+	codecs.byFormat[c.Format()] = c
+}
+
+// Lookup returns the codec of format f.
+func Lookup(f Format) (Codec, error) {
+	codecs.RLock()
+	defer codecs.RUnlock()
+
+	c, ok := codecs.byFormat[f]
+	if !ok {
+		return nil, errors.Wrapf(ErrUnknownFormat, "%q", f)
+	}
+
+	return c, nil
+}
@@ -0,0 +1,179 @@
+package codec_test
+
+import (
+	"testing"
+
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/codec"
+	"github.com/pkg/errors"
+)
+
+const (
+	avroV1 = `{"type":"record","name":"Demo","fields":[
+		{"name":"id","type":"string"},
+		{"name":"name","type":"string"}]}`
+	avroV2 = `{"type":"record","name":"Demo","fields":[
+		{"name":"id","type":"string"},
+		{"name":"name","type":"string"},
+		{"name":"status","type":"string","default":"active"}]}`
+	avroRequired = `{"type":"record","name":"Demo","fields":[
+		{"name":"id","type":"string"},
+		{"name":"name","type":"string"},
+		{"name":"status","type":"string"}]}`
+	avroRetyped = `{"type":"record","name":"Demo","fields":[
+		{"name":"id","type":"long"},
+		{"name":"name","type":"string"}]}`
+
+	jsonV1 = `{"type":"object","required":["id"],"properties":{
+		"id":{"type":"string"},"version":{"type":"integer"}}}`
+	jsonV2 = `{"type":"object","required":["id"],"properties":{
+		"id":{"type":"string"},"version":{"type":"number"},"name":{"type":"string"}}}`
+	jsonRequired = `{"type":"object","required":["id","name"],"properties":{
+		"id":{"type":"string"},"name":{"type":"string"}}}`
+	jsonRetyped = `{"type":"object","required":["id"],"properties":{
+		"id":{"type":"integer"}}}`
+
+	protoV1 = `{"file":[{"name":"demo.proto","package":"demo","syntax":"proto3",
+		"messageType":[{"name":"Demo","field":[
+			{"name":"id","number":1,"label":"LABEL_OPTIONAL","type":"TYPE_STRING"},
+			{"name":"version","number":2,"label":"LABEL_OPTIONAL","type":"TYPE_INT32"}]}]}]}`
+	protoV2 = `{"file":[{"name":"demo.proto","package":"demo","syntax":"proto3",
+		"messageType":[{"name":"Demo","field":[
+			{"name":"id","number":1,"label":"LABEL_OPTIONAL","type":"TYPE_STRING"},
+			{"name":"version","number":2,"label":"LABEL_OPTIONAL","type":"TYPE_INT64"},
+			{"name":"name","number":3,"label":"LABEL_OPTIONAL","type":"TYPE_STRING"}]}]}]}`
+	protoRetyped = `{"file":[{"name":"demo.proto","package":"demo","syntax":"proto3",
+		"messageType":[{"name":"Demo","field":[
+			{"name":"id","number":1,"label":"LABEL_OPTIONAL","type":"TYPE_STRING"},
+			{"name":"version","number":2,"label":"LABEL_REPEATED","type":"TYPE_INT32"}]}]}]}`
+	protoRenumbered = `{"file":[{"name":"demo.proto","package":"demo","syntax":"proto3",
+		"messageType":[{"name":"Demo","field":[
+			{"name":"id","number":1,"label":"LABEL_OPTIONAL","type":"TYPE_STRING"},
+			{"name":"version","number":2,"label":"LABEL_OPTIONAL","type":"TYPE_STRING"}]}]}]}`
+)
+
+func TestCompatible(t *testing.T) {
+	tt := []struct {
+		Name          string
+		Format        codec.Format
+		Reader        string
+		Writer        string
+		ExpectedError error
+	}{
+		{
+			Name:   "test avro reader adding a field with default",
+			Format: codec.FormatAvro,
+			Reader: avroV2,
+			Writer: avroV1,
+		},
+		{
+			Name:   "test avro reader ignoring a field",
+			Format: codec.FormatAvro,
+			Reader: avroV1,
+			Writer: avroV2,
+		},
+		{
+			Name:          "test avro reader adding a field without default",
+			Format:        codec.FormatAvro,
+			Reader:        avroRequired,
+			Writer:        avroV1,
+			ExpectedError: codec.ErrIncompatible,
+		},
+		{
+			Name:          "test avro field changing type",
+			Format:        codec.FormatAvro,
+			Reader:        avroRetyped,
+			Writer:        avroV1,
+			ExpectedError: codec.ErrIncompatible,
+		},
+		{
+			Name:          "test avro invalid schema",
+			Format:        codec.FormatAvro,
+			Reader:        `{"type":"record"}`,
+			Writer:        avroV1,
+			ExpectedError: codec.ErrInvalidSchema,
+		},
+		{
+			Name:   "test json reader adding an optional property",
+			Format: codec.FormatJSON,
+			Reader: jsonV2,
+			Writer: jsonV1,
+		},
+		{
+			Name:          "test json reader requiring a new property",
+			Format:        codec.FormatJSON,
+			Reader:        jsonRequired,
+			Writer:        jsonV1,
+			ExpectedError: codec.ErrIncompatible,
+		},
+		{
+			Name:          "test json property changing type",
+			Format:        codec.FormatJSON,
+			Reader:        jsonRetyped,
+			Writer:        jsonV1,
+			ExpectedError: codec.ErrIncompatible,
+		},
+		{
+			Name:          "test json numbers not read as integers",
+			Format:        codec.FormatJSON,
+			Reader:        jsonV1,
+			Writer:        jsonV2,
+			ExpectedError: codec.ErrIncompatible,
+		},
+		{
+			Name:   "test protobuf adding a field and widening a varint",
+			Format: codec.FormatProtobuf,
+			Reader: protoV2,
+			Writer: protoV1,
+		},
+		{
+			Name:   "test protobuf reader ignoring a field",
+			Format: codec.FormatProtobuf,
+			Reader: protoV1,
+			Writer: protoV2,
+		},
+		{
+			Name:          "test protobuf field becoming repeated",
+			Format:        codec.FormatProtobuf,
+			Reader:        protoRetyped,
+			Writer:        protoV1,
+			ExpectedError: codec.ErrIncompatible,
+		},
+		{
+			Name:          "test protobuf field changing wire type",
+			Format:        codec.FormatProtobuf,
+			Reader:        protoRenumbered,
+			Writer:        protoV1,
+			ExpectedError: codec.ErrIncompatible,
+		},
+		{
+			Name:          "test protobuf empty descriptor set",
+			Format:        codec.FormatProtobuf,
+			Reader:        `{}`,
+			Writer:        protoV1,
+			ExpectedError: codec.ErrInvalidSchema,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			c, err := codec.Lookup(tc.Format)
+			if err != nil {
+				t.Fatalf("Lookup() error = %v", err)
+			}
+
+			err = c.Compatible(tc.Reader, tc.Writer)
+			if tc.ExpectedError == nil && err != nil {
+				t.Fatalf("Compatible() error = %v", err)
+			}
+			if tc.ExpectedError != nil && !errors.Is(err, tc.ExpectedError) {
+				t.Fatalf("Compatible() error = %v, want %v", err, tc.ExpectedError)
+			}
+		})
+	}
+}
+
+func TestLookup(t *testing.T) {
+	if _, err := codec.Lookup("xml"); !errors.Is(err, codec.ErrUnknownFormat) {
+		t.Fatalf("Lookup() error = %v, want %v", err, codec.ErrUnknownFormat)
+	}
+}
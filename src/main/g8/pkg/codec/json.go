@@ -0,0 +1,94 @@
+package codec
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+)
+
+// JSONCodec encodes payloads as JSON. Its schemas are JSON Schema documents,
+// of which only type, properties, required and items are checked for
+// compatibility, payloads are not validated.
+type JSONCodec struct{}
+
+type jsonSchema struct {
+	Type       string                 `json:"type"`
+	Properties map[string]*jsonSchema `json:"properties"`
+	Required   []string               `json:"required"`
+	Items      *jsonSchema            `json:"items"`
+}
+
+// Format implements Codec.
+func (JSONCodec) Format() Format { return FormatJSON }
+
+// Validate implements Codec.
+func (JSONCodec) Validate(definition string) error {
+	_, err := parseJSONSchema(definition)
+	return err
+}
+
+// Compatible implements Codec. Readers must only require properties the
+// writer requires, and properties known to both must have the same type,
+// integers being readable as numbers.
+func (JSONCodec) Compatible(reader, writer string) error {
+	r, err := parseJSONSchema(reader)
+	if err != nil {
+		return err
+	}
+	w, err := parseJSONSchema(writer)
+	if err != nil {
+		return err
+	}
+
+	return compatibleJSON("\$", r, w)
+}
+
+// Encode implements Codec.
+func (JSONCodec) Encode(_ Schema, v interface{}) ([]byte, error) {
+	b, err := json.Marshal(v)
+	return b, errors.Wrap(err, "encoding json")
+}
+
+// Decode implements Codec.
+func (JSONCodec) Decode(_ Schema, data []byte, v interface{}) error {
+	return errors.Wrap(json.Unmarshal(data, v), "decoding json")
+}
+
+func parseJSONSchema(definition string) (*jsonSchema, error) {
+	var s jsonSchema
+	if err := json.Unmarshal([]byte(definition), &s); err != nil {
+		return nil, errors.Wrap(ErrInvalidSchema, err.Error())
+	}
+
+	return &s, nil
+}
+
+func compatibleJSON(path string, r, w *jsonSchema) error {
+	if r.Type != "" && w.Type != "" && r.Type != w.Type && !(r.Type == "number" && w.Type == "integer") {
+		return errors.Wrapf(ErrIncompatible, "%s is a %s read as a %s", path, w.Type, r.Type)
+	}
+
+	required := make(map[string]bool, len(w.Required))
+	for _, name := range w.Required {
+		required[name] = true
+	}
+	for _, name := range r.Required {
+		if !required[name] {
+			return errors.Wrapf(ErrIncompatible, "%s.%s is required but may be missing", path, name)
+		}
+	}
+
+	for name, rp := range r.Properties {
+		if wp, ok := w.Properties[name]; ok {
+			if err := compatibleJSON(path+"."+name, rp, wp); err != nil {
+				return err
+			}
+		}
+	}
+
+	if r.Items != nil && w.Items != nil {
+		return compatibleJSON(path+"[]", r.Items, w.Items)
+	}
+
+	return nil
+}
@@ -0,0 +1,143 @@
+package codec
+
+import (
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	"github.com/pkg/errors"
+)
+
+// ProtobufCodec encodes generated protocol buffer messages. Its schemas are
+// file descriptor sets in their JSON form, such as written by
+// "buf build --as-file-descriptor-set -o schema.json", describing the first
+// message of their last file.
+type ProtobufCodec struct{}
+
+// Format implements Codec.
+func (ProtobufCodec) Format() Format { return FormatProtobuf }
+
+// Validate implements Codec.
+func (ProtobufCodec) Validate(definition string) error {
+	_, err := parseProtobuf(definition)
+	return err
+}
+
+// Compatible implements Codec. Fields are matched by number and must have
+// the same cardinality and a wire compatible type, and fields required by
+// the reader must be known to the writer.
+func (ProtobufCodec) Compatible(reader, writer string) error {
+	r, err := parseProtobuf(reader)
+	if err != nil {
+		return err
+	}
+	w, err := parseProtobuf(writer)
+	if err != nil {
+		return err
+	}
+
+	return compatibleProtobuf(r, w, make(map[protoreflect.FullName]bool))
+}
+
+// Encode implements Codec.
+func (ProtobufCodec) Encode(_ Schema, v interface{}) ([]byte, error) {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return nil, errors.Wrapf(ErrUnsupportedPayload, "protobuf needs a proto.Message, got %T", v)
+	}
+
+	b, err := proto.Marshal(m)
+	return b, errors.Wrap(err, "encoding protobuf")
+}
+
+// Decode implements Codec.
+func (ProtobufCodec) Decode(_ Schema, data []byte, v interface{}) error {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return errors.Wrapf(ErrUnsupportedPayload, "protobuf needs a proto.Message, got %T", v)
+	}
+
+	return errors.Wrap(proto.Unmarshal(data, m), "decoding protobuf")
+}
+
+// parseProtobuf returns the message described by definition.
+func parseProtobuf(definition string) (protoreflect.MessageDescriptor, error) {
+	var set descriptorpb.FileDescriptorSet
+	if err := protojson.Unmarshal([]byte(definition), &set); err != nil {
+		return nil, errors.Wrap(ErrInvalidSchema, err.Error())
+	}
+	if len(set.File) == 0 {
+		return nil, errors.Wrap(ErrInvalidSchema, "no file in descriptor set")
+	}
+
+	files, err := protodesc.NewFiles(&set)
+	if err != nil {
+		return nil, errors.Wrap(ErrInvalidSchema, err.Error())
+	}
+
+	fd, err := files.FindFileByPath(set.File[len(set.File)-1].GetName())
+	if err != nil {
+		return nil, errors.Wrap(ErrInvalidSchema, err.Error())
+	}
+	if fd.Messages().Len() == 0 {
+		return nil, errors.Wrapf(ErrInvalidSchema, "no message in %s", fd.Path())
+	}
+
+	return fd.Messages().Get(0), nil
+}
+
+func compatibleProtobuf(r, w protoreflect.MessageDescriptor, seen map[protoreflect.FullName]bool) error {
+	if seen[r.FullName()] {
+		return nil
+	}
+	seen[r.FullName()] = true
+
+	rf, wf := r.Fields(), w.Fields()
+	for i := 0; i < rf.Len(); i++ {
+		f := rf.Get(i)
+
+		g := wf.ByNumber(f.Number())
+		if g == nil {
+			if f.Cardinality() == protoreflect.Required {
+				return errors.Wrapf(ErrIncompatible, "%s is required but unknown to the writer", f.FullName())
+			}
+			continue
+		}
+
+		if (f.Cardinality() == protoreflect.Repeated) != (g.Cardinality() == protoreflect.Repeated) || f.IsMap() != g.IsMap() {
+			return errors.Wrapf(ErrIncompatible, "%s changed cardinality", f.FullName())
+		}
+		if wireType(f.Kind()) != wireType(g.Kind()) {
+			return errors.Wrapf(ErrIncompatible, "%s is a %s read as a %s", f.FullName(), g.Kind(), f.Kind())
+		}
+
+		if f.Message() != nil && g.Message() != nil {
+			if err := compatibleProtobuf(f.Message(), g.Message(), seen); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// wireType groups the kinds whose encodings can be read as one another.
+func wireType(k protoreflect.Kind) string {
+	switch k {
+	case protoreflect.Int32Kind, protoreflect.Int64Kind, protoreflect.Uint32Kind,
+		protoreflect.Uint64Kind, protoreflect.BoolKind, protoreflect.EnumKind:
+		return "varint"
+	case protoreflect.Sint32Kind, protoreflect.Sint64Kind:
+		return "zigzag"
+	case protoreflect.Fixed32Kind, protoreflect.Sfixed32Kind:
+		return "fixed32"
+	case protoreflect.Fixed64Kind, protoreflect.Sfixed64Kind:
+		return "fixed64"
+	case protoreflect.StringKind, protoreflect.BytesKind:
+		return "bytes"
+	default:
+		return k.String()
+	}
+}
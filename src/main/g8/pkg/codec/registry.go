@@ -0,0 +1,336 @@
+package codec
+
+import (
+	"context"
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Compatibility is the check made against the latest version of a subject
+// before registering a new one.
+type Compatibility string
+
+// Compatibility levels, named after those of the Confluent Schema Registry
+const (
+	// CompatibilityNone accepts any new version.
+	CompatibilityNone Compatibility = "NONE"
+	// CompatibilityBackward makes the new version able to read the
+	// payloads of the previous one, so consumers are upgraded first.
+	CompatibilityBackward Compatibility = "BACKWARD"
+	// CompatibilityForward makes the previous version able to read the
+	// payloads of the new one, so producers are upgraded first.
+	CompatibilityForward Compatibility = "FORWARD"
+	// CompatibilityFull is both backward and forward.
+	CompatibilityFull Compatibility = "FULL"
+)
+
+// Check returns an error wrapping ErrIncompatible unless next may follow
+// prev under the compatibility level.
+func (c Compatibility) Check(prev, next Schema) error {
+	if c == CompatibilityNone || c == "" {
+		return nil
+	}
+
+	if prev.Format != next.Format {
+		return errors.Wrapf(ErrIncompatible, "%s changed format from %s to %s", next.Subject, prev.Format, next.Format)
+	}
+
+	codec, err := Lookup(next.Format)
+	if err != nil {
+		return err
+	}
+
+	if c == CompatibilityBackward || c == CompatibilityFull {
+		if err := codec.Compatible(next.Definition, prev.Definition); err != nil {
+			return errors.Wrapf(err, "%s version %d cannot read version %d", next.Subject, next.Version, prev.Version)
+		}
+	}
+	if c == CompatibilityForward || c == CompatibilityFull {
+		if err := codec.Compatible(prev.Definition, next.Definition); err != nil {
+			return errors.Wrapf(err, "%s version %d cannot read version %d", next.Subject, prev.Version, next.Version)
+		}
+	}
+
+	return nil
+}
+
+// Registry keeps the versions of the schemas of every subject.
+type Registry interface {
+	// Schema returns the schema identified by id.
+	Schema(ctx context.Context, id int) (Schema, error)
+	// Latest returns the last version of the schema of subject.
+	Latest(ctx context.Context, subject string) (Schema, error)
+	// Register adds a version to the schemas of subject, unless its
+	// definition is already registered, and returns it.
+	Register(ctx context.Context, subject string, format Format, definition string) (Schema, error)
+}
+
+// MemoryRegistry is a Registry kept in memory.
+type MemoryRegistry struct {
+	compatibility Compatibility
+
+	mu       sync.RWMutex
+	schemas  []Schema
+	subjects map[string][]int
+}
+
+// NewMemoryRegistry creates an empty MemoryRegistry checking new versions
+// with the given compatibility level.
+func NewMemoryRegistry(c Compatibility) *MemoryRegistry {
+	return &MemoryRegistry{compatibility: c, subjects: make(map[string][]int)}
+}
+
+// Schema implements Registry.
+func (r *MemoryRegistry) Schema(_ context.Context, id int) (Schema, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if id < 1 || id > len(r.schemas) {
+		return Schema{}, errors.Wrapf(ErrUnknownSchema, "id %d", id)
+	}
+
+	return r.schemas[id-1], nil
+}
+
+// Latest implements Registry.
+func (r *MemoryRegistry) Latest(_ context.Context, subject string) (Schema, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	ids := r.subjects[subject]
+	if len(ids) == 0 {
+		return Schema{}, errors.Wrapf(ErrUnknownSchema, "subject %s", subject)
+	}
+
+	return r.schemas[ids[len(ids)-1]-1], nil
+}
+
+// Register implements Registry.
+func (r *MemoryRegistry) Register(_ context.Context, subject string, format Format, definition string) (Schema, error) {
+	s, _, err := r.register(subject, format, definition)
+	return s, err
+}
+
+// register is Register, also reporting whether a version was added.
+func (r *MemoryRegistry) register(subject string, format Format, definition string) (Schema, bool, error) {
+	codec, err := Lookup(format)
+	if err != nil {
+		return Schema{}, false, err
+	}
+	if err := codec.Validate(definition); err != nil {
+		return Schema{}, false, errors.Wrapf(err, "subject %s", subject)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ids := r.subjects[subject]
+	for _, id := range ids {
+		if s := r.schemas[id-1]; s.Format == format && s.Definition == definition {
+			return s, false, nil
+		}
+	}
+
+	s := Schema{ID: len(r.schemas) + 1, Subject: subject, Version: 1, Format: format, Definition: definition}
+	if len(ids) > 0 {
+		prev := r.schemas[ids[len(ids)-1]-1]
+		s.Version = prev.Version + 1
+
+		if err := r.compatibility.Check(prev, s); err != nil {
+			return Schema{}, false, err
+		}
+	}
+
+	r.add(s)
+
+	return s, true, nil
+}
+
+// add stores s, whose id follows the last one.
+func (r *MemoryRegistry) add(s Schema) {
+	r.schemas = append(r.schemas, s)
+	r.subjects[s.Subject] = append(r.subjects[s.Subject], s.ID)
+}
+
+// FileRegistry is a MemoryRegistry saved to a JSON file after every new
+// version, standing in for a schema registry server.
+type FileRegistry struct {
+	*MemoryRegistry
+
+	path string
+	// saving serializes the writes of the file.
+	saving sync.Mutex
+}
+
+type registryFile struct {
+	Schemas []Schema `json:"schemas"`
+}
+
+// NewFileRegistry creates a FileRegistry saved to path, starting with the
+// schemas it holds when it exists.
+func NewFileRegistry(path string, c Compatibility) (*FileRegistry, error) {
+	r := &FileRegistry{MemoryRegistry: NewMemoryRegistry(c), path: path}
+
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "reading schema registry")
+	}
+
+	var f registryFile
+	if err := json.Unmarshal(b, &f); err != nil {
+		return nil, errors.Wrapf(err, "decoding schema registry %s", path)
+	}
+
+	sort.Slice(f.Schemas, func(i, j int) bool { return f.Schemas[i].ID < f.Schemas[j].ID })
+	for i, s := range f.Schemas {
+		if s.ID != i+1 {
+			return nil, errors.Wrapf(ErrInvalidSchema, "schema registry %s misses id %d", path, i+1)
+		}
+		r.add(s)
+	}
+
+	return r, nil
+}
+
+// Register implements Registry.
+func (r *FileRegistry) Register(_ context.Context, subject string, format Format, definition string) (Schema, error) {
+	s, added, err := r.register(subject, format, definition)
+	if err != nil || !added {
+		return s, err
+	}
+
+	return s, r.save()
+}
+
+// save writes the registry to a temporary file renamed over the previous one.
+func (r *FileRegistry) save() error {
+	r.saving.Lock()
+	defer r.saving.Unlock()
+
+	r.mu.RLock()
+	b, err := json.MarshalIndent(registryFile{Schemas: r.schemas}, "", "  ")
+	r.mu.RUnlock()
+	if err != nil {
+		return errors.Wrap(err, "encoding schema registry")
+	}
+
+	tmp := r.path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o600); err != nil {
+		return errors.Wrap(err, "writing schema registry")
+	}
+
+	return errors.Wrap(os.Rename(tmp, r.path), "writing schema registry")
+}
+
+// extensions maps the file extensions of LoadFS to formats, the longest
+// first.
+var extensions = []struct {
+	ext    string
+	format Format
+}{
+	{".pb.json", FormatProtobuf},
+	{".avsc", FormatAvro},
+	{".json", FormatJSON},
+}
+
+// LoadFS registers the schemas of fsys into r. Each directory is a subject
+// holding one file per version, named after the version number and
+// ending with .avsc for Avro, .pb.json for Protobuf or .json for JSON
+// Schema, such as "demo-events-value/2.avsc". Versions are registered in
+// order, so each one is checked against the previous one.
+func LoadFS(ctx context.Context, r Registry, fsys fs.FS) error {
+	subjects, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return errors.Wrap(err, "reading schemas")
+	}
+
+	for _, subject := range subjects {
+		if !subject.IsDir() {
+			continue
+		}
+
+		if err := loadSubject(ctx, r, fsys, subject.Name()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// LoadDir registers the schemas of dir into r, see LoadFS.
+func LoadDir(ctx context.Context, r Registry, dir string) error {
+	return LoadFS(ctx, r, os.DirFS(filepath.Clean(dir)))
+}
+
+type schemaFile struct {
+	name    string
+	version int
+	format  Format
+}
+
+func loadSubject(ctx context.Context, r Registry, fsys fs.FS, subject string) error {
+	entries, err := fs.ReadDir(fsys, subject)
+	if err != nil {
+		return errors.Wrapf(err, "reading schemas of %s", subject)
+	}
+
+	files := make([]schemaFile, 0, len(entries))
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+
+		f, ok := parseSchemaFile(e.Name())
+		if !ok {
+			return errors.Wrapf(ErrInvalidSchema, "unexpected schema file %s/%s", subject, e.Name())
+		}
+		files = append(files, f)
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].version < files[j].version })
+
+	for i, f := range files {
+		if f.version != i+1 {
+			return errors.Wrapf(ErrInvalidSchema, "%s misses version %d", subject, i+1)
+		}
+
+		b, err := fs.ReadFile(fsys, path.Join(subject, f.name))
+		if err != nil {
+			return errors.Wrapf(err, "reading schema %s/%s", subject, f.name)
+		}
+
+		if _, err := r.Register(ctx, subject, f.format, string(b)); err != nil {
+			return errors.Wrapf(err, "registering %s/%s", subject, f.name)
+		}
+	}
+
+	return nil
+}
+
+func parseSchemaFile(name string) (schemaFile, bool) {
+	for _, e := range extensions {
+		if !strings.HasSuffix(name, e.ext) {
+			continue
+		}
+
+		version, err := strconv.Atoi(strings.TrimSuffix(name, e.ext))
+		if err != nil || version < 1 {
+			return schemaFile{}, false
+		}
+
+		return schemaFile{name: name, version: version, format: e.format}, true
+	}
+
+	return schemaFile{}, false
+}
@@ -0,0 +1,243 @@
+package codec_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/codec"
+	"github.com/pkg/errors"
+)
+
+func TestRegister(t *testing.T) {
+	ctx := context.Background()
+
+	tt := []struct {
+		Name            string
+		Compatibility   codec.Compatibility
+		Versions        []string
+		ExpectedError   error
+		ExpectedVersion int
+	}{
+		{
+			Name:            "test backward accepts readers with defaults",
+			Compatibility:   codec.CompatibilityBackward,
+			Versions:        []string{avroV1, avroV2},
+			ExpectedVersion: 2,
+		},
+		{
+			Name:          "test backward rejects new fields without default",
+			Compatibility: codec.CompatibilityBackward,
+			Versions:      []string{avroV1, avroRequired},
+			ExpectedError: codec.ErrIncompatible,
+		},
+		{
+			Name:            "test forward accepts new fields without default",
+			Compatibility:   codec.CompatibilityForward,
+			Versions:        []string{avroV1, avroRequired},
+			ExpectedVersion: 2,
+		},
+		{
+			Name:          "test forward rejects removed fields without default",
+			Compatibility: codec.CompatibilityForward,
+			Versions:      []string{avroRequired, avroV1},
+			ExpectedError: codec.ErrIncompatible,
+		},
+		{
+			Name:            "test full accepts fields with default",
+			Compatibility:   codec.CompatibilityFull,
+			Versions:        []string{avroV1, avroV2, avroV1},
+			ExpectedVersion: 1,
+		},
+		{
+			Name:          "test full rejects new fields without default",
+			Compatibility: codec.CompatibilityFull,
+			Versions:      []string{avroV1, avroRequired},
+			ExpectedError: codec.ErrIncompatible,
+		},
+		{
+			Name:            "test none accepts anything",
+			Compatibility:   codec.CompatibilityNone,
+			Versions:        []string{avroV1, avroRetyped},
+			ExpectedVersion: 2,
+		},
+		{
+			Name:            "test registered definitions are not versioned again",
+			Compatibility:   codec.CompatibilityFull,
+			Versions:        []string{avroV1, avroV1},
+			ExpectedVersion: 1,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			r := codec.NewMemoryRegistry(tc.Compatibility)
+
+			var (
+				s   codec.Schema
+				err error
+			)
+			for _, v := range tc.Versions {
+				if s, err = r.Register(ctx, "demo-value", codec.FormatAvro, v); err != nil {
+					break
+				}
+			}
+
+			if tc.ExpectedError != nil {
+				if !errors.Is(err, tc.ExpectedError) {
+					t.Fatalf("Register() error = %v, want %v", err, tc.ExpectedError)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Register() error = %v", err)
+			}
+			if s.Version != tc.ExpectedVersion {
+				t.Fatalf("Register() version = %d, want %d", s.Version, tc.ExpectedVersion)
+			}
+
+			got, err := r.Schema(ctx, s.ID)
+			if err != nil || got != s {
+				t.Fatalf("Schema(%d) = %v, %v, want %v", s.ID, got, err, s)
+			}
+		})
+	}
+}
+
+func TestFileRegistry(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "registry.json")
+
+	r, err := codec.NewFileRegistry(path, codec.CompatibilityFull)
+	if err != nil {
+		t.Fatalf("NewFileRegistry() error = %v", err)
+	}
+
+	other, err := r.Register(ctx, "other-value", codec.FormatJSON, jsonV1)
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	v1, err := r.Register(ctx, "demo-value", codec.FormatAvro, avroV1)
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	// Reopened, the ids are kept and new ones follow.
+	r, err = codec.NewFileRegistry(path, codec.CompatibilityFull)
+	if err != nil {
+		t.Fatalf("NewFileRegistry() error = %v", err)
+	}
+
+	if got, err := r.Schema(ctx, other.ID); err != nil || got != other {
+		t.Fatalf("Schema(%d) = %v, %v, want %v", other.ID, got, err, other)
+	}
+	if got, err := r.Register(ctx, "demo-value", codec.FormatAvro, avroV1); err != nil || got != v1 {
+		t.Fatalf("Register() = %v, %v, want %v", got, err, v1)
+	}
+
+	v2, err := r.Register(ctx, "demo-value", codec.FormatAvro, avroV2)
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	if v2.ID != 3 || v2.Version != 2 {
+		t.Fatalf("Register() = id %d version %d, want id 3 version 2", v2.ID, v2.Version)
+	}
+
+	if got, err := r.Latest(ctx, "demo-value"); err != nil || got != v2 {
+		t.Fatalf("Latest() = %v, %v, want %v", got, err, v2)
+	}
+}
+
+func TestLoadFS(t *testing.T) {
+	ctx := context.Background()
+
+	tt := []struct {
+		Name          string
+		Files         fstest.MapFS
+		ExpectedError error
+		Expected      map[string]codec.Format
+	}{
+		{
+			Name: "test versions of every subject",
+			Files: fstest.MapFS{
+				"demo-value/1.avsc":     {Data: []byte(avroV1)},
+				"demo-value/2.avsc":     {Data: []byte(avroV2)},
+				"other-value/1.json":    {Data: []byte(jsonV1)},
+				"proto-value/1.pb.json": {Data: []byte(protoV1)},
+				"README.md":             {Data: []byte("ignored")},
+			},
+			Expected: map[string]codec.Format{
+				"demo-value":  codec.FormatAvro,
+				"other-value": codec.FormatJSON,
+				"proto-value": codec.FormatProtobuf,
+			},
+		},
+		{
+			Name: "test versions loaded in numeric order",
+			Files: fstest.MapFS{
+				"demo-value/1.avsc":  {Data: []byte(avroV1)},
+				"demo-value/10.avsc": {Data: []byte(avroV1)},
+				"demo-value/2.avsc":  {Data: []byte(avroV2)},
+				"demo-value/3.avsc":  {Data: []byte(avroV1)},
+				"demo-value/4.avsc":  {Data: []byte(avroV1)},
+				"demo-value/5.avsc":  {Data: []byte(avroV1)},
+				"demo-value/6.avsc":  {Data: []byte(avroV1)},
+				"demo-value/7.avsc":  {Data: []byte(avroV1)},
+				"demo-value/8.avsc":  {Data: []byte(avroV1)},
+				"demo-value/9.avsc":  {Data: []byte(avroV1)},
+			},
+			Expected: map[string]codec.Format{"demo-value": codec.FormatAvro},
+		},
+		{
+			Name: "test missing version",
+			Files: fstest.MapFS{
+				"demo-value/1.avsc": {Data: []byte(avroV1)},
+				"demo-value/3.avsc": {Data: []byte(avroV2)},
+			},
+			ExpectedError: codec.ErrInvalidSchema,
+		},
+		{
+			Name: "test unexpected file",
+			Files: fstest.MapFS{
+				"demo-value/latest.avsc": {Data: []byte(avroV1)},
+			},
+			ExpectedError: codec.ErrInvalidSchema,
+		},
+		{
+			Name: "test incompatible version",
+			Files: fstest.MapFS{
+				"demo-value/1.avsc": {Data: []byte(avroV1)},
+				"demo-value/2.avsc": {Data: []byte(avroRetyped)},
+			},
+			ExpectedError: codec.ErrIncompatible,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			r := codec.NewMemoryRegistry(codec.CompatibilityFull)
+
+			err := codec.LoadFS(ctx, r, tc.Files)
+			if tc.ExpectedError != nil {
+				if !errors.Is(err, tc.ExpectedError) {
+					t.Fatalf("LoadFS() error = %v, want %v", err, tc.ExpectedError)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadFS() error = %v", err)
+			}
+
+			for subject, format := range tc.Expected {
+				s, err := r.Latest(ctx, subject)
+				if err != nil {
+					t.Fatalf("Latest(%s) error = %v", subject, err)
+				}
+				if s.Format != format {
+					t.Fatalf("Latest(%s) format = %s, want %s", subject, s.Format, format)
+				}
+			}
+		})
+	}
+}
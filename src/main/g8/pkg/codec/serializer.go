@@ -0,0 +1,72 @@
+package codec
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// Serializer encodes payloads with the latest schema of their subject and
+// decodes them with the schema named by their headers.
+type Serializer struct {
+	registry Registry
+}
+
+// NewSerializer creates a Serializer reading the schemas of r.
+func NewSerializer(r Registry) *Serializer {
+	return &Serializer{registry: r}
+}
+
+// Marshal encodes v with the latest schema of subject and returns it with
+// the headers naming the schema. A nil Serializer writes JSON without
+// headers, as before schemas were introduced.
+func (s *Serializer) Marshal(ctx context.Context, subject string, v interface{}) ([]byte, map[string]string, error) {
+	if s == nil {
+		b, err := JSONCodec{}.Encode(Schema{}, v)
+		return b, nil, err
+	}
+
+	schema, err := s.registry.Latest(ctx, subject)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	codec, err := Lookup(schema.Format)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	b, err := codec.Encode(schema, v)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "encoding %s version %d", subject, schema.Version)
+	}
+
+	return b, map[string]string{HeaderSchemaID: strconv.Itoa(schema.ID)}, nil
+}
+
+// Unmarshal decodes data into v with the schema named by headers. Payloads
+// without schema id, or read by a nil Serializer, are decoded as JSON.
+func (s *Serializer) Unmarshal(ctx context.Context, headers map[string]string, data []byte, v interface{}) error {
+	raw, ok := headers[HeaderSchemaID]
+	if s == nil || !ok {
+		return JSONCodec{}.Decode(Schema{}, data, v)
+	}
+
+	id, err := strconv.Atoi(raw)
+	if err != nil {
+		return errors.Wrapf(ErrUnknownSchema, "invalid id %q", raw)
+	}
+
+	schema, err := s.registry.Schema(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	codec, err := Lookup(schema.Format)
+	if err != nil {
+		return err
+	}
+
+	return errors.Wrapf(codec.Decode(schema, data, v), "decoding %s version %d", schema.Subject, schema.Version)
+}
@@ -0,0 +1,162 @@
+package codec_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/codec"
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+type demoV1 struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type demoV2 struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Status string `json:"status"`
+}
+
+func TestSerializerAvro(t *testing.T) {
+	ctx := context.Background()
+	r := codec.NewMemoryRegistry(codec.CompatibilityFull)
+	s := codec.NewSerializer(r)
+
+	if _, err := r.Register(ctx, "demo-value", codec.FormatAvro, avroV1); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	old, oldHeaders, err := s.Marshal(ctx, "demo-value", demoV1{ID: "1", Name: "old"})
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if oldHeaders[codec.HeaderSchemaID] != "1" {
+		t.Fatalf("Marshal() headers = %v, want schema id 1", oldHeaders)
+	}
+
+	if _, err := r.Register(ctx, "demo-value", codec.FormatAvro, avroV2); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	cur, curHeaders, err := s.Marshal(ctx, "demo-value", demoV2{ID: "2", Name: "new", Status: "inactive"})
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if curHeaders[codec.HeaderSchemaID] != "2" {
+		t.Fatalf("Marshal() headers = %v, want schema id 2", curHeaders)
+	}
+
+	// Payloads of both versions are read by the current reader.
+	var got demoV2
+	if err := s.Unmarshal(ctx, oldHeaders, old, &got); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if want := (demoV2{ID: "1", Name: "old"}); got != want {
+		t.Fatalf("Unmarshal() = %v, want %v", got, want)
+	}
+
+	got = demoV2{}
+	if err := s.Unmarshal(ctx, curHeaders, cur, &got); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if want := (demoV2{ID: "2", Name: "new", Status: "inactive"}); got != want {
+		t.Fatalf("Unmarshal() = %v, want %v", got, want)
+	}
+
+	if _, _, err := s.Marshal(ctx, "other-value", demoV1{}); !errors.Is(err, codec.ErrUnknownSchema) {
+		t.Fatalf("Marshal() error = %v, want %v", err, codec.ErrUnknownSchema)
+	}
+	err = s.Unmarshal(ctx, map[string]string{codec.HeaderSchemaID: "9"}, cur, &got)
+	if !errors.Is(err, codec.ErrUnknownSchema) {
+		t.Fatalf("Unmarshal() error = %v, want %v", err, codec.ErrUnknownSchema)
+	}
+}
+
+func TestSerializerProtobuf(t *testing.T) {
+	ctx := context.Background()
+	r := codec.NewMemoryRegistry(codec.CompatibilityFull)
+	s := codec.NewSerializer(r)
+
+	set := &descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{
+		protodesc.ToFileDescriptorProto(wrapperspb.File_google_protobuf_wrappers_proto),
+	}}
+	definition, err := protojson.Marshal(set)
+	if err != nil {
+		t.Fatalf("protojson.Marshal() error = %v", err)
+	}
+
+	if _, err := r.Register(ctx, "wrapper-value", codec.FormatProtobuf, string(definition)); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	b, headers, err := s.Marshal(ctx, "wrapper-value", wrapperspb.Double(1.5))
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+
+	var got wrapperspb.DoubleValue
+	if err := s.Unmarshal(ctx, headers, b, &got); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if got.GetValue() != 1.5 {
+		t.Fatalf("Unmarshal() = %v, want 1.5", got.GetValue())
+	}
+
+	if _, _, err := s.Marshal(ctx, "wrapper-value", demoV1{}); !errors.Is(err, codec.ErrUnsupportedPayload) {
+		t.Fatalf("Marshal() error = %v, want %v", err, codec.ErrUnsupportedPayload)
+	}
+}
+
+func TestSerializerJSON(t *testing.T) {
+	ctx := context.Background()
+	r := codec.NewMemoryRegistry(codec.CompatibilityFull)
+
+	tt := []struct {
+		Name       string
+		Serializer *codec.Serializer
+	}{
+		{
+			Name: "test nil serializer",
+		},
+		{
+			Name:       "test json schema",
+			Serializer: codec.NewSerializer(r),
+		},
+	}
+
+	if _, err := r.Register(ctx, "demo-value", codec.FormatJSON, jsonV2); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			b, headers, err := tc.Serializer.Marshal(ctx, "demo-value", demoV1{ID: "1", Name: "json"})
+			if err != nil {
+				t.Fatalf("Marshal() error = %v", err)
+			}
+
+			var got demoV1
+			if err := tc.Serializer.Unmarshal(ctx, headers, b, &got); err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			if want := (demoV1{ID: "1", Name: "json"}); got != want {
+				t.Fatalf("Unmarshal() = %v, want %v", got, want)
+			}
+
+			// Payloads written before schemas carry no header.
+			got = demoV1{}
+			if err := tc.Serializer.Unmarshal(ctx, nil, []byte(`{"id":"2"}`), &got); err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			if got.ID != "2" {
+				t.Fatalf("Unmarshal() = %v, want id 2", got)
+			}
+		})
+	}
+}
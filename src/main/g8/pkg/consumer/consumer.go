@@ -0,0 +1,440 @@
+// Package consumer runs the handlers of Kafka topics: messages sharing a
+// key are handled in order while others run concurrently, offsets are
+// committed once handled, and failing messages are retried with backoff
+// before being dead-lettered.
+package consumer
+
+import (
+	"context"
+	"hash/fnv"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Gympass/gcore/v3/gcontext"
+	"github.com/Gympass/gcore/v3/glog"
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/requestid"
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/tracing"
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+)
+
+// Headers added to dead-lettered messages.
+const (
+	HeaderOriginalTopic     = "original-topic"
+	HeaderOriginalPartition = "original-partition"
+	HeaderOriginalOffset    = "original-offset"
+	HeaderError             = "error"
+)
+
+const (
+	defaultConcurrency     = 1
+	defaultMaxAttempts     = 5
+	defaultMinBackoff      = 100 * time.Millisecond
+	defaultMaxBackoff      = 30 * time.Second
+	defaultShutdownTimeout = 30 * time.Second
+)
+
+// Message is a record read from, or written to, a topic.
+type Message struct {
+	Topic     string
+	Partition int
+	Offset    int64
+	Key       []byte
+	Value     []byte
+	Headers   map[string]string
+	Time      time.Time
+}
+
+// Handler processes the messages of a topic. Messages whose handler fails
+// are retried, unless the error is wrapped by Permanent.
+type Handler func(ctx context.Context, m Message) error
+
+// Reader reads the messages of a topic for a consumer group.
+type Reader interface {
+	// Fetch blocks until a message is available or ctx is done.
+	Fetch(ctx context.Context) (Message, error)
+	// Commit marks m, and the messages before it in its partition, as consumed.
+	Commit(ctx context.Context, m Message) error
+	// Lag is the number of messages left to read.
+	Lag() int64
+	Close() error
+}
+
+// Broker is the message broker the Consumer reads from and dead-letters to.
+type Broker interface {
+	Subscribe(group, topic string) Reader
+	Publish(ctx context.Context, m Message) error
+}
+
+type permanent struct {
+	error
+}
+
+func (p permanent) Unwrap() error { return p.error }
+
+// Permanent marks err as not worth retrying, such as a message that cannot
+// be decoded, so the message is dead-lettered at once.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return permanent{err}
+}
+
+// IsPermanent reports whether err was marked by Permanent.
+func IsPermanent(err error) bool {
+	var p permanent
+	return errors.As(err, &p)
+}
+
+// Config used by the Consumer
+type Config struct {
+	Broker Broker
+	Logger glog.Logger
+	// Group is the consumer group sharing the partitions of the topics.
+	Group string
+	// Concurrency is the number of messages of a topic handled at once, 1
+	// when zero. Messages with the same key are always handled in order.
+	Concurrency int
+	// MaxAttempts before a message is dead-lettered, 5 when zero.
+	MaxAttempts int
+	// MinBackoff and MaxBackoff bound the delay between two attempts,
+	// doubled after each failure. They default to 100ms and 30s.
+	MinBackoff, MaxBackoff time.Duration
+	// DeadLetterTopic receives the messages still failing after
+	// MaxAttempts, which are dropped when it is empty.
+	DeadLetterTopic string
+	// LagInterval between two logs of the lag of each topic, none when zero.
+	LagInterval time.Duration
+	// ShutdownTimeout bounds the wait for running handlers once Run is
+	// stopped, after which their context is canceled. 30s when zero.
+	ShutdownTimeout time.Duration
+}
+
+// Consumer dispatches the messages of the subscribed topics to their handlers.
+type Consumer struct {
+	cfg      Config
+	handlers map[string]Handler
+}
+
+// New creates a Consumer based on configuration properties
+func New(c Config) *Consumer {
+	if c.Concurrency <= 0 {
+		c.Concurrency = defaultConcurrency
+	}
+	if c.MaxAttempts <= 0 {
+		c.MaxAttempts = defaultMaxAttempts
+	}
+	if c.MinBackoff <= 0 {
+		c.MinBackoff = defaultMinBackoff
+	}
+	if c.MaxBackoff < c.MinBackoff {
+		c.MaxBackoff = defaultMaxBackoff
+	}
+	if c.ShutdownTimeout <= 0 {
+		c.ShutdownTimeout = defaultShutdownTimeout
+	}
+
+	return &Consumer{cfg: c, handlers: make(map[string]Handler)}
+}
+
+// Handle subscribes h to topic, replacing any previous handler. It must be
+// called before Run.
+func (c *Consumer) Handle(topic string, h Handler) {
+	c.handlers[topic] = h
+}
+
+// Run consumes the subscribed topics until ctx is done, then waits up to
+// the shutdown timeout for the running handlers. Messages fetched but not
+// handled yet are left uncommitted, to be read again.
+func (c *Consumer) Run(ctx context.Context) {
+	// Handlers outlive ctx, so they can finish during the shutdown.
+	hctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for topic, h := range c.handlers {
+		wg.Add(1)
+		go func(topic string, h Handler) {
+			defer wg.Done()
+			c.consume(ctx, hctx, topic, h)
+		}(topic, h)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return
+	case <-ctx.Done():
+	}
+
+	timer := time.NewTimer(c.cfg.ShutdownTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-done:
+	case <-timer.C:
+		c.cfg.Logger.Warn(gcontext.NewContext(context.Background()), "Consumer shutdown timed out, canceling handlers.")
+		cancel()
+		<-done
+	}
+}
+
+func (c *Consumer) consume(ctx, hctx context.Context, topic string, h Handler) {
+	r := c.cfg.Broker.Subscribe(c.cfg.Group, topic)
+	defer func() {
+		if err := r.Close(); err != nil {
+			c.log(topic, err, "Could not close consumer.")
+		}
+	}()
+
+	if c.cfg.LagInterval > 0 {
+		go c.logLag(ctx, topic, r)
+	}
+
+	offsets := newOffsets()
+	workers := make([]chan Message, c.cfg.Concurrency)
+
+	var wg sync.WaitGroup
+	for i := range workers {
+		workers[i] = make(chan Message, 1)
+
+		wg.Add(1)
+		go func(queue <-chan Message) {
+			defer wg.Done()
+			c.work(ctx, hctx, r, h, offsets, queue)
+		}(workers[i])
+	}
+
+	c.fetch(ctx, topic, r, offsets, workers)
+
+	wg.Wait()
+}
+
+// fetch dispatches the messages of r to the workers until ctx is done.
+// Messages with the same key always go to the same worker.
+func (c *Consumer) fetch(ctx context.Context, topic string, r Reader, offsets *offsets, workers []chan Message) {
+	failures := 0
+	for {
+		m, err := r.Fetch(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			failures++
+			c.log(topic, err, "Could not fetch message.")
+			if !sleep(ctx, c.backoff(failures)) {
+				return
+			}
+			continue
+		}
+		failures = 0
+
+		offsets.fetched(m)
+
+		select {
+		case workers[slot(m, len(workers))] <- m:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (c *Consumer) work(ctx, hctx context.Context, r Reader, h Handler, offsets *offsets, queue <-chan Message) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case m := <-queue:
+			if ctx.Err() != nil {
+				return
+			}
+			if !c.process(ctx, hctx, h, m) {
+				return
+			}
+
+			if next, ok := offsets.processed(m); ok {
+				// Committed even during the shutdown, so it is not read again.
+				if err := r.Commit(hctx, next); err != nil {
+					c.logMessage(m, err, 0, "Could not commit message.")
+				}
+			}
+		}
+	}
+}
+
+// process handles m, retrying failures, and dead-letters it when it keeps
+// failing. It returns false when stopped before m was done with. The
+// attempts share a span continuing the trace held by the headers of m.
+func (c *Consumer) process(ctx, hctx context.Context, h Handler, m Message) bool {
+	hctx, span := tracing.StartProcess(hctx, m.Topic, m.Headers,
+		semconv.MessagingKafkaConsumerGroup(c.cfg.Group),
+		semconv.MessagingKafkaSourcePartition(m.Partition),
+		semconv.MessagingKafkaMessageOffset(int(m.Offset)),
+	)
+	defer span.End()
+
+	for attempt := 1; ; attempt++ {
+		err := c.handle(hctx, h, m)
+		if err == nil {
+			return true
+		}
+		span.RecordError(err)
+		if hctx.Err() != nil {
+			return false
+		}
+
+		if IsPermanent(err) || attempt >= c.cfg.MaxAttempts {
+			span.SetStatus(codes.Error, err.Error())
+			return c.deadLetter(ctx, hctx, m, err, attempt)
+		}
+
+		c.logMessage(m, err, attempt, "Message not handled, retrying.")
+		if !sleep(ctx, c.backoff(attempt)) {
+			return false
+		}
+	}
+}
+
+// handle runs h with a context logging the coordinates and the request id
+// of m.
+func (c *Consumer) handle(ctx context.Context, h Handler, m Message) error {
+	mctx := gcontext.NewContext(ctx)
+	gcontext.AddString(mctx, "kafka.topic", m.Topic)
+	gcontext.AddString(mctx, "kafka.partition", strconv.Itoa(m.Partition))
+	gcontext.AddString(mctx, "kafka.offset", strconv.FormatInt(m.Offset, 10))
+	gcontext.AddString(mctx, "kafka.key", string(m.Key))
+	if id := requestid.FromContext(ctx); id != "" {
+		gcontext.AddString(mctx, requestid.Field, id)
+	}
+
+	return h(mctx, m)
+}
+
+func (c *Consumer) deadLetter(ctx, hctx context.Context, m Message, cause error, attempts int) bool {
+	if c.cfg.DeadLetterTopic == "" {
+		c.logMessage(m, cause, attempts, "Message not handled, dropped.")
+		return true
+	}
+
+	dl := deadLetter(m, c.cfg.DeadLetterTopic, cause)
+	for failures := 1; ; failures++ {
+		err := c.cfg.Broker.Publish(hctx, dl)
+		if err == nil {
+			c.logMessage(m, cause, attempts, "Message not handled, dead-lettered.")
+			return true
+		}
+
+		c.logMessage(m, err, attempts, "Message not dead-lettered, retrying.")
+		if !sleep(ctx, c.backoff(failures)) {
+			return false
+		}
+	}
+}
+
+// backoff returns the delay after the given number of failed attempts.
+func (c *Consumer) backoff(attempts int) time.Duration {
+	d := c.cfg.MinBackoff
+	for i := 1; i < attempts && d < c.cfg.MaxBackoff; i++ {
+		d *= 2
+	}
+	if d > c.cfg.MaxBackoff {
+		d = c.cfg.MaxBackoff
+	}
+
+	return d
+}
+
+func (c *Consumer) logLag(ctx context.Context, topic string, r Reader) {
+	ticker := time.NewTicker(c.cfg.LagInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			lctx := gcontext.NewContext(context.Background())
+			gcontext.AddString(lctx, "kafka.group", c.cfg.Group)
+			gcontext.AddString(lctx, "kafka.topic", topic)
+			gcontext.AddString(lctx, "kafka.lag", strconv.FormatInt(r.Lag(), 10))
+
+			c.cfg.Logger.Info(lctx, "Consumer lag.")
+		}
+	}
+}
+
+func (c *Consumer) log(topic string, err error, msg string) {
+	lctx := gcontext.NewContext(context.Background())
+	gcontext.AddError(lctx, err)
+	gcontext.AddString(lctx, "kafka.group", c.cfg.Group)
+	gcontext.AddString(lctx, "kafka.topic", topic)
+
+	c.cfg.Logger.Error(lctx, msg)
+}
+
+func (c *Consumer) logMessage(m Message, err error, attempts int, msg string) {
+	lctx := gcontext.NewContext(context.Background())
+	gcontext.AddError(lctx, err)
+	gcontext.AddString(lctx, "kafka.group", c.cfg.Group)
+	gcontext.AddString(lctx, "kafka.topic", m.Topic)
+	gcontext.AddString(lctx, "kafka.partition", strconv.Itoa(m.Partition))
+	gcontext.AddString(lctx, "kafka.offset", strconv.FormatInt(m.Offset, 10))
+	gcontext.AddString(lctx, "kafka.key", string(m.Key))
+	if id := m.Headers[requestid.Header]; id != "" {
+		gcontext.AddString(lctx, requestid.Field, id)
+	}
+	if attempts > 0 {
+		gcontext.AddString(lctx, "kafka.attempts", strconv.Itoa(attempts))
+	}
+
+	c.cfg.Logger.Error(lctx, msg)
+}
+
+// deadLetter returns m redirected to topic, with its origin and the cause
+// of the failure in its headers.
+func deadLetter(m Message, topic string, cause error) Message {
+	headers := make(map[string]string, len(m.Headers)+4)
+	for k, v := range m.Headers {
+		headers[k] = v
+	}
+	headers[HeaderOriginalTopic] = m.Topic
+	headers[HeaderOriginalPartition] = strconv.Itoa(m.Partition)
+	headers[HeaderOriginalOffset] = strconv.FormatInt(m.Offset, 10)
+	headers[HeaderError] = cause.Error()
+
+	return Message{Topic: topic, Key: m.Key, Value: m.Value, Headers: headers, Time: m.Time}
+}
+
+// slot returns the worker handling m among n.
+func slot(m Message, n int) int {
+	if len(m.Key) == 0 {
+		// Unordered, spread by offset.
+		return int(m.Offset % int64(n))
+	}
+
+	h := fnv.New32a()
+	_, _ = h.Write(m.Key)
+
+	return int(h.Sum32() % uint32(n))
+}
+
+// sleep waits for d, returning false if ctx is done first.
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
@@ -0,0 +1,341 @@
+package consumer_test
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Gympass/gcore/v3/glog"
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/consumer"
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	testTopic = "demo-events"
+	testGroup = "demo"
+	testDLQ   = "demo-events-dlq"
+)
+
+var errHandler = errors.New("handler failed")
+
+// run starts c and returns a function stopping it.
+func run(c *consumer.Consumer) func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		c.Run(ctx)
+		close(done)
+	}()
+
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// waitFor fails t unless cond holds within a second.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func publish(t *testing.T, b *consumer.MemoryBroker, keys ...string) {
+	t.Helper()
+
+	for i, key := range keys {
+		m := consumer.Message{Topic: testTopic, Key: []byte(key), Value: []byte(strconv.Itoa(i))}
+		if err := b.Publish(context.Background(), m); err != nil {
+			t.Fatalf("Publish() error = %v", err)
+		}
+	}
+}
+
+func TestConsumerFailures(t *testing.T) {
+	tt := []struct {
+		Name              string
+		Failures          int
+		Err               error
+		DeadLetterTopic   string
+		ExpectedAttempts  int
+		ExpectedDLQ       int
+		ExpectedCommitted int64
+	}{
+		{
+			Name:              "test handled at once",
+			ExpectedAttempts:  1,
+			ExpectedCommitted: 1,
+		},
+		{
+			Name:              "test retried until handled",
+			Failures:          2,
+			Err:               errHandler,
+			DeadLetterTopic:   testDLQ,
+			ExpectedAttempts:  3,
+			ExpectedCommitted: 1,
+		},
+		{
+			Name:              "test dead-lettered after max attempts",
+			Failures:          10,
+			Err:               errHandler,
+			DeadLetterTopic:   testDLQ,
+			ExpectedAttempts:  3,
+			ExpectedDLQ:       1,
+			ExpectedCommitted: 1,
+		},
+		{
+			Name:              "test permanent errors not retried",
+			Failures:          10,
+			Err:               consumer.Permanent(errHandler),
+			DeadLetterTopic:   testDLQ,
+			ExpectedAttempts:  1,
+			ExpectedDLQ:       1,
+			ExpectedCommitted: 1,
+		},
+		{
+			Name:              "test dropped without dead-letter topic",
+			Failures:          10,
+			Err:               errHandler,
+			ExpectedAttempts:  3,
+			ExpectedCommitted: 1,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			b := consumer.NewMemoryBroker()
+			publish(t, b, "a")
+
+			c := consumer.New(consumer.Config{
+				Broker:          b,
+				Logger:          glog.Noop(),
+				Group:           testGroup,
+				MaxAttempts:     3,
+				MinBackoff:      time.Millisecond,
+				MaxBackoff:      time.Millisecond,
+				DeadLetterTopic: tc.DeadLetterTopic,
+			})
+
+			var (
+				mu       sync.Mutex
+				attempts int
+			)
+			c.Handle(testTopic, func(_ context.Context, _ consumer.Message) error {
+				mu.Lock()
+				defer mu.Unlock()
+
+				attempts++
+				if attempts <= tc.Failures {
+					return tc.Err
+				}
+				return nil
+			})
+
+			stop := run(c)
+			waitFor(t, "commit", func() bool { return b.Committed(testGroup, testTopic) == tc.ExpectedCommitted })
+			stop()
+
+			if attempts != tc.ExpectedAttempts {
+				t.Fatalf("handled %d times, want %d", attempts, tc.ExpectedAttempts)
+			}
+
+			dlq := b.Messages(testDLQ)
+			if len(dlq) != tc.ExpectedDLQ {
+				t.Fatalf("dead-lettered %d messages, want %d", len(dlq), tc.ExpectedDLQ)
+			}
+			for _, m := range dlq {
+				if m.Headers[consumer.HeaderOriginalTopic] != testTopic || m.Headers[consumer.HeaderError] == "" {
+					t.Fatalf("dead-lettered message without origin: %v", m.Headers)
+				}
+			}
+		})
+	}
+}
+
+func TestConsumerKeyOrdering(t *testing.T) {
+	b := consumer.NewMemoryBroker()
+
+	var keys []string
+	for i := 0; i < 60; i++ {
+		keys = append(keys, "key-"+strconv.Itoa(i%6))
+	}
+	publish(t, b, keys...)
+
+	c := consumer.New(consumer.Config{
+		Broker:      b,
+		Logger:      glog.Noop(),
+		Group:       testGroup,
+		Concurrency: 4,
+	})
+
+	var (
+		mu      sync.Mutex
+		running int
+		peak    int
+		seen    = make(map[string][]int)
+	)
+	c.Handle(testTopic, func(_ context.Context, m consumer.Message) error {
+		mu.Lock()
+		running++
+		if running > peak {
+			peak = running
+		}
+		mu.Unlock()
+
+		time.Sleep(time.Millisecond)
+
+		mu.Lock()
+		defer mu.Unlock()
+		running--
+		n, _ := strconv.Atoi(string(m.Value))
+		seen[string(m.Key)] = append(seen[string(m.Key)], n)
+		return nil
+	})
+
+	stop := run(c)
+	waitFor(t, "commit", func() bool { return b.Committed(testGroup, testTopic) == int64(len(keys)) })
+	stop()
+
+	if peak > 4 {
+		t.Fatalf("%d messages handled at once, want at most 4", peak)
+	}
+
+	for key, values := range seen {
+		for i := 1; i < len(values); i++ {
+			if values[i] < values[i-1] {
+				t.Fatalf("messages of %s handled out of order: %v", key, values)
+			}
+		}
+	}
+}
+
+func TestConsumerShutdown(t *testing.T) {
+	b := consumer.NewMemoryBroker()
+	publish(t, b, "a", "a")
+
+	c := consumer.New(consumer.Config{
+		Broker: b,
+		Logger: glog.Noop(),
+		Group:  testGroup,
+	})
+
+	started, release := make(chan struct{}), make(chan struct{})
+	var handled int
+	c.Handle(testTopic, func(_ context.Context, _ consumer.Message) error {
+		handled++
+		close(started)
+		<-release
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		c.Run(ctx)
+		close(done)
+	}()
+
+	<-started
+	cancel()
+
+	select {
+	case <-done:
+		t.Fatalf("Run() returned before the running handler")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	close(release)
+	<-done
+
+	// The running message is committed, the queued one is read again.
+	if handled != 1 {
+		t.Fatalf("handled %d messages, want 1", handled)
+	}
+	if got := b.Committed(testGroup, testTopic); got != 1 {
+		t.Fatalf("committed offset %d, want 1", got)
+	}
+}
+
+func TestConsumerShutdownTimeout(t *testing.T) {
+	b := consumer.NewMemoryBroker()
+	publish(t, b, "a")
+
+	c := consumer.New(consumer.Config{
+		Broker:          b,
+		Logger:          glog.Noop(),
+		Group:           testGroup,
+		ShutdownTimeout: 10 * time.Millisecond,
+	})
+
+	started := make(chan struct{})
+	c.Handle(testTopic, func(ctx context.Context, _ consumer.Message) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		c.Run(ctx)
+		close(done)
+	}()
+
+	<-started
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("Run() did not cancel the running handler")
+	}
+
+	if got := b.Committed(testGroup, testTopic); got != 0 {
+		t.Fatalf("committed offset %d, want 0", got)
+	}
+}
+
+func TestConsumerTraceContext(t *testing.T) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	b := consumer.NewMemoryBroker()
+	c := consumer.New(consumer.Config{Broker: b, Logger: glog.Noop(), Group: testGroup})
+
+	traces := make(chan string, 1)
+	c.Handle(testTopic, func(ctx context.Context, m consumer.Message) error {
+		traces <- trace.SpanContextFromContext(ctx).TraceID().String()
+		return nil
+	})
+
+	m := consumer.Message{
+		Topic:   testTopic,
+		Key:     []byte("a"),
+		Headers: map[string]string{"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+	}
+	if err := b.Publish(context.Background(), m); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+
+	stop := run(c)
+	defer stop()
+
+	select {
+	case got := <-traces:
+		if got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+			t.Fatalf("handler trace = %s, want the trace of the message", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("timed out waiting for the handler")
+	}
+}
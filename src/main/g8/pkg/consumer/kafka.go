@@ -0,0 +1,124 @@
+package consumer
+
+import (
+	"context"
+	"time"
+
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/tracing"
+	"github.com/pkg/errors"
+	"github.com/segmentio/kafka-go"
+)
+
+// KafkaBroker reads and writes Kafka topics.
+type KafkaBroker struct {
+	brokers []string
+	w       *kafka.Writer
+	client  *kafka.Client
+}
+
+// NewKafkaBroker creates a KafkaBroker connecting to brokers.
+func NewKafkaBroker(brokers []string) *KafkaBroker {
+	return &KafkaBroker{
+		brokers: brokers,
+		w: &kafka.Writer{
+			Addr:         kafka.TCP(brokers...),
+			Balancer:     &kafka.Hash{},
+			RequiredAcks: kafka.RequireAll,
+			BatchTimeout: 10 * time.Millisecond,
+		},
+		client: &kafka.Client{Addr: kafka.TCP(brokers...)},
+	}
+}
+
+// Name implements health.Checker.
+func (b *KafkaBroker) Name() string {
+	return "kafka-broker"
+}
+
+// Check implements health.Checker by asking the brokers for the cluster
+// metadata.
+func (b *KafkaBroker) Check(ctx context.Context) error {
+	_, err := b.client.Metadata(ctx, &kafka.MetadataRequest{})
+	return errors.Wrap(err, "reading kafka metadata")
+}
+
+// Subscribe implements Broker. Offsets are committed synchronously.
+func (b *KafkaBroker) Subscribe(group, topic string) Reader {
+	return &kafkaReader{r: kafka.NewReader(kafka.ReaderConfig{
+		Brokers: b.brokers,
+		GroupID: group,
+		Topic:   topic,
+	})}
+}
+
+// Publish implements Broker, continuing the trace of ctx.
+func (b *KafkaBroker) Publish(ctx context.Context, m Message) (err error) {
+	values := make(map[string]string, len(m.Headers))
+	for k, v := range m.Headers {
+		values[k] = v
+	}
+
+	ctx, span := tracing.StartPublish(ctx, m.Topic, values)
+	defer func() { tracing.End(span, err) }()
+
+	headers := make([]kafka.Header, 0, len(values))
+	for k, v := range values {
+		headers = append(headers, kafka.Header{Key: k, Value: []byte(v)})
+	}
+
+	err = b.w.WriteMessages(ctx, kafka.Message{
+		Topic:   m.Topic,
+		Key:     m.Key,
+		Value:   m.Value,
+		Headers: headers,
+		Time:    m.Time,
+	})
+
+	return errors.Wrapf(err, "writing to %s", m.Topic)
+}
+
+// Close flushes and closes the underlying writer.
+func (b *KafkaBroker) Close() error {
+	return b.w.Close()
+}
+
+type kafkaReader struct {
+	r *kafka.Reader
+}
+
+func (r *kafkaReader) Fetch(ctx context.Context) (Message, error) {
+	km, err := r.r.FetchMessage(ctx)
+	if err != nil {
+		return Message{}, errors.Wrap(err, "fetching message")
+	}
+
+	m := Message{
+		Topic:     km.Topic,
+		Partition: km.Partition,
+		Offset:    km.Offset,
+		Key:       km.Key,
+		Value:     km.Value,
+		Time:      km.Time,
+	}
+	if len(km.Headers) > 0 {
+		m.Headers = make(map[string]string, len(km.Headers))
+		for _, h := range km.Headers {
+			m.Headers[h.Key] = string(h.Value)
+		}
+	}
+
+	return m, nil
+}
+
+func (r *kafkaReader) Commit(ctx context.Context, m Message) error {
+	err := r.r.CommitMessages(ctx, kafka.Message{Topic: m.Topic, Partition: m.Partition, Offset: m.Offset})
+	return errors.Wrap(err, "committing message")
+}
+
+func (r *kafkaReader) Lag() int64 {
+	return r.r.Stats().Lag
+}
+
+func (r *kafkaReader) Close() error {
+	return r.r.Close()
+}
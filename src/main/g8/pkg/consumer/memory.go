@@ -0,0 +1,113 @@
+package consumer
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryBroker keeps single-partition topics in memory, meant for tests.
+type MemoryBroker struct {
+	mu        sync.Mutex
+	topics    map[string][]Message
+	committed map[string]int64
+	// published is closed, and replaced, whenever a message is published.
+	published chan struct{}
+}
+
+// NewMemoryBroker creates an empty MemoryBroker.
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{
+		topics:    make(map[string][]Message),
+		committed: make(map[string]int64),
+		published: make(chan struct{}),
+	}
+}
+
+// Publish implements Broker.
+func (b *MemoryBroker) Publish(_ context.Context, m Message) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	m.Partition = 0
+	m.Offset = int64(len(b.topics[m.Topic]))
+	if m.Time.IsZero() {
+		m.Time = time.Now()
+	}
+	b.topics[m.Topic] = append(b.topics[m.Topic], m)
+
+	close(b.published)
+	b.published = make(chan struct{})
+
+	return nil
+}
+
+// Messages returns the messages published to topic.
+func (b *MemoryBroker) Messages(topic string) []Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return append([]Message(nil), b.topics[topic]...)
+}
+
+// Committed returns the offset of the next message group reads from topic.
+func (b *MemoryBroker) Committed(group, topic string) int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.committed[group+"/"+topic]
+}
+
+// Subscribe implements Broker. The reader starts from the committed offset.
+func (b *MemoryBroker) Subscribe(group, topic string) Reader {
+	return &memoryReader{broker: b, group: group, topic: topic, next: b.Committed(group, topic)}
+}
+
+type memoryReader struct {
+	broker       *MemoryBroker
+	group, topic string
+	next         int64
+}
+
+func (r *memoryReader) Fetch(ctx context.Context) (Message, error) {
+	for {
+		r.broker.mu.Lock()
+		log, published := r.broker.topics[r.topic], r.broker.published
+		r.broker.mu.Unlock()
+
+		if r.next < int64(len(log)) {
+			m := log[r.next]
+			r.next++
+			return m, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return Message{}, ctx.Err()
+		case <-published:
+		}
+	}
+}
+
+func (r *memoryReader) Commit(_ context.Context, m Message) error {
+	r.broker.mu.Lock()
+	defer r.broker.mu.Unlock()
+
+	key := r.group + "/" + r.topic
+	if m.Offset+1 > r.broker.committed[key] {
+		r.broker.committed[key] = m.Offset + 1
+	}
+
+	return nil
+}
+
+func (r *memoryReader) Lag() int64 {
+	r.broker.mu.Lock()
+	defer r.broker.mu.Unlock()
+
+	return int64(len(r.broker.topics[r.topic])) - r.next
+}
+
+func (r *memoryReader) Close() error {
+	return nil
+}
@@ -0,0 +1,56 @@
+package consumer
+
+import "sync"
+
+// offsets tracks the messages of a topic handled out of order, so only
+// offsets below which every message is done get committed.
+type offsets struct {
+	mu         sync.Mutex
+	partitions map[int][]*pending
+}
+
+type pending struct {
+	m    Message
+	done bool
+}
+
+func newOffsets() *offsets {
+	return &offsets{partitions: make(map[int][]*pending)}
+}
+
+// fetched records m as in flight. Messages of a partition are fetched in
+// offset order.
+func (o *offsets) fetched(m Message) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.partitions[m.Partition] = append(o.partitions[m.Partition], &pending{m: m})
+}
+
+// processed records m as done and returns the last message of its
+// partition which can be committed, if any.
+func (o *offsets) processed(m Message) (Message, bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	queue := o.partitions[m.Partition]
+	for _, p := range queue {
+		if p.m.Offset == m.Offset {
+			p.done = true
+			break
+		}
+	}
+
+	n := 0
+	for n < len(queue) && queue[n].done {
+		n++
+	}
+	if n == 0 {
+		return Message{}, false
+	}
+
+	last := queue[n-1].m
+	o.partitions[m.Partition] = queue[n:]
+
+	return last, true
+}
@@ -0,0 +1,57 @@
+package consumer
+
+import "testing"
+
+func TestOffsets(t *testing.T) {
+	o := newOffsets()
+	for _, m := range []Message{
+		{Partition: 0, Offset: 10},
+		{Partition: 0, Offset: 11},
+		{Partition: 1, Offset: 5},
+		{Partition: 0, Offset: 12},
+	} {
+		o.fetched(m)
+	}
+
+	tt := []struct {
+		Name           string
+		Processed      Message
+		ExpectedCommit bool
+		ExpectedOffset int64
+	}{
+		{
+			Name:      "test waits for earlier offsets",
+			Processed: Message{Partition: 0, Offset: 11},
+		},
+		{
+			Name:           "test other partitions are independent",
+			Processed:      Message{Partition: 1, Offset: 5},
+			ExpectedCommit: true,
+			ExpectedOffset: 5,
+		},
+		{
+			Name:           "test commits contiguous offsets",
+			Processed:      Message{Partition: 0, Offset: 10},
+			ExpectedCommit: true,
+			ExpectedOffset: 11,
+		},
+		{
+			Name:           "test commits the last offset",
+			Processed:      Message{Partition: 0, Offset: 12},
+			ExpectedCommit: true,
+			ExpectedOffset: 12,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			m, ok := o.processed(tc.Processed)
+			if ok != tc.ExpectedCommit {
+				t.Fatalf("processed() commit = %v, want %v", ok, tc.ExpectedCommit)
+			}
+			if ok && m.Offset != tc.ExpectedOffset {
+				t.Fatalf("processed() offset = %d, want %d", m.Offset, tc.ExpectedOffset)
+			}
+		})
+	}
+}
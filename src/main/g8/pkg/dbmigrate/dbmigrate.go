@@ -3,6 +3,8 @@ package dbmigrate
 import (
 	"context"
 	"fmt"
+	"net"
+	"net/url"
 	"os"
 
 	"github.com/Gympass/gcore/v3/gcontext"
@@ -33,6 +35,7 @@ type Config struct {
 
 // Migrate is used to go up and down with migrations.
 type Migrate struct {
+	// dsn is the connection URL without password, for logging.
 	dsn string
 	src string
 
@@ -56,7 +59,7 @@ func New(c Config, opts ...string) (*Migrate, error) {
 		query = fmt.Sprintf("%s&%s", query, opts[i])
 	}
 
-	pgdsn := newDSN(c.User, c.Pass, c.Host, c.Port, c.Database, query)
+	pgdsn := newDSN(url.UserPassword(c.User, c.Pass), c.Host, c.Port, c.Database, query)
 
 	srcURL := fmt.Sprintf("file://%s", c.Directory)
 
@@ -71,7 +74,7 @@ func New(c Config, opts ...string) (*Migrate, error) {
 	}
 
 	return &Migrate{
-		dsn:     newDSN("user", "pass", c.Host, c.Port, c.Database, query),
+		dsn:     newDSN(url.User(c.User), c.Host, c.Port, c.Database, query),
 		src:     srcURL,
 		sd:      sourceDrv,
 		migrate: m,
@@ -79,17 +82,18 @@ func New(c Config, opts ...string) (*Migrate, error) {
 	}, nil
 }
 
-func newDSN(user, pass, host, port, db, query string) string {
+// newDSN returns the connection URL of the database, logged without
+// password.
+func newDSN(user *url.Userinfo, host, port, db, query string) string {
+	u := url.URL{
+		Scheme:   "postgres",
+		User:     user,
+		Host:     net.JoinHostPort(host, port),
+		Path:     "/" + db,
+		RawQuery: query,
+	}
 
-	return fmt.Sprintf(
-		"postgres://%s:%s@%s:%s/%s?%s",
-		user,
-		pass,
-		host,
-		port,
-		db,
-		query,
-	)
+	return u.String()
 }
 
 // Up applies all available migrations.
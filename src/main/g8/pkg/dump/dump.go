@@ -0,0 +1,223 @@
+// Package dump logs the requests served by a router along with their
+// responses, to debug integrations. Bodies are capped and the headers,
+// query parameters and JSON fields carrying credentials are redacted.
+package dump
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Gympass/gcore/v3/gcontext"
+	"github.com/Gympass/gcore/v3/glog"
+	"github.com/gorilla/mux"
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/recorder"
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/requestid"
+)
+
+const defaultMaxBodySize = 4 << 10
+
+// unmatchedRoute names the requests served without a mux route.
+const unmatchedRoute = "unmatched"
+
+// Config used by dump package.
+type Config struct {
+	Logger glog.Logger
+	// MaxBodySize is the number of bytes of each body kept, 4KiB by
+	// default. Longer bodies are truncated.
+	MaxBodySize int
+	// RedactHeaders are masked in addition to the DefaultRedactHeaders.
+	RedactHeaders []string
+	// RedactFields are the JSON fields, at any depth, and query parameters
+	// masked in addition to the DefaultRedactFields.
+	RedactFields []string
+	// SampleRate is the fraction of requests dumped, all of them by
+	// default.
+	SampleRate float64
+	// Routes are the mux route templates dumped, all of them when empty.
+	Routes []string
+	// Skip are the mux route templates never dumped, such as health checks.
+	Skip []string
+}
+
+// Dumper logs requests and responses.
+type Dumper struct {
+	logger      glog.Logger
+	maxBodySize int
+	sampleRate  float64
+	routes      map[string]bool
+	skip        map[string]bool
+	redact      *redactor
+}
+
+// New creates a Dumper based on configuration properties.
+func New(c Config) *Dumper {
+	if c.Logger == nil {
+		c.Logger = glog.Noop()
+	}
+	if c.MaxBodySize <= 0 {
+		c.MaxBodySize = defaultMaxBodySize
+	}
+	if c.SampleRate <= 0 || c.SampleRate > 1 {
+		c.SampleRate = 1
+	}
+
+	return &Dumper{
+		logger:      c.Logger,
+		maxBodySize: c.MaxBodySize,
+		sampleRate:  c.SampleRate,
+		routes:      set(c.Routes),
+		skip:        set(c.Skip),
+		redact: newRedactor(
+			append(append([]string(nil), DefaultRedactHeaders...), c.RedactHeaders...),
+			append(append([]string(nil), DefaultRedactFields...), c.RedactFields...),
+		),
+	}
+}
+
+// Middleware logs the method, route, headers, query, bodies, status and
+// latency of the requests matching the configured routes and sample rate;
+// add it with Router.Use.
+func (d *Dumper) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := unmatchedRoute
+		if cr := mux.CurrentRoute(r); cr != nil {
+			if t, err := cr.GetPathTemplate(); err == nil {
+				route = t
+			}
+		}
+
+		if !d.dumps(route) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		reqBody := d.captureRequest(r)
+		rec := &bodyRecorder{Recorder: recorder.New(w), max: d.maxBodySize}
+		start := time.Now()
+
+		next.ServeHTTP(rec, r)
+
+		latency := time.Since(start)
+
+		ctx := gcontext.NewContext(r.Context())
+		gcontext.AddString(ctx, "http.method", r.Method)
+		gcontext.AddString(ctx, "http.route", route)
+		gcontext.AddString(ctx, "http.target", r.URL.Path)
+		gcontext.AddString(ctx, "http.query", d.redact.query(r.URL.Query()))
+		gcontext.AddString(ctx, "http.request.headers", d.redact.headers(r.Header))
+		gcontext.AddString(ctx, "http.request.body", d.redact.body(r.Header.Get("Content-Type"), reqBody.Bytes(), reqBody.truncated))
+		gcontext.AddString(ctx, "http.status_code", strconv.Itoa(rec.Status()))
+		gcontext.AddString(ctx, "http.response.headers", d.redact.headers(rec.Header()))
+		gcontext.AddString(ctx, "http.response.body", d.redact.body(rec.Header().Get("Content-Type"), rec.body.Bytes(), rec.truncated))
+		gcontext.AddString(ctx, "http.latency_ms", strconv.FormatFloat(float64(latency)/float64(time.Millisecond), 'f', 3, 64))
+		if id := requestid.FromContext(r.Context()); id != "" {
+			gcontext.AddString(ctx, requestid.Field, id)
+		}
+
+		d.logger.Info(ctx, "Request dumped.")
+	})
+}
+
+// dumps tells whether the requests of route are dumped this time.
+func (d *Dumper) dumps(route string) bool {
+	if d.skip[route] || (len(d.routes) > 0 && !d.routes[route]) {
+		return false
+	}
+
+	return d.sampleRate >= 1 || rand.Float64() < d.sampleRate
+}
+
+// captureRequest keeps the first bytes of the body of r, which the handler
+// still reads whole.
+func (d *Dumper) captureRequest(r *http.Request) *capped {
+	c := &capped{}
+	if r.Body == nil || r.Body == http.NoBody {
+		return c
+	}
+
+	b, err := io.ReadAll(io.LimitReader(r.Body, int64(d.maxBodySize)+1))
+	if len(b) > d.maxBodySize {
+		c.Write(b[:d.maxBodySize])
+		c.truncated = true
+	} else {
+		c.Write(b)
+	}
+
+	r.Body = &replayBody{Reader: io.MultiReader(bytes.NewReader(b), &errReader{err: err}, r.Body), Closer: r.Body}
+
+	return c
+}
+
+// capped holds the first bytes of a body.
+type capped struct {
+	bytes.Buffer
+	truncated bool
+}
+
+// replayBody gives back the bytes read by captureRequest before the rest.
+type replayBody struct {
+	io.Reader
+	io.Closer
+}
+
+// errReader reports the error met while capturing a request body, if any,
+// at the point the handler would have met it.
+type errReader struct {
+	err error
+}
+
+func (e *errReader) Read([]byte) (int, error) {
+	if e.err != nil {
+		return 0, e.err
+	}
+
+	return 0, io.EOF
+}
+
+// bodyRecorder records the status and the first bytes of the body written
+// by a handler.
+type bodyRecorder struct {
+	*recorder.Recorder
+	max       int
+	body      bytes.Buffer
+	truncated bool
+}
+
+func (w *bodyRecorder) Write(b []byte) (int, error) {
+	if room := w.max - w.body.Len(); room > 0 {
+		if len(b) > room {
+			w.body.Write(b[:room])
+			w.truncated = true
+		} else {
+			w.body.Write(b)
+		}
+	} else if len(b) > 0 {
+		w.truncated = true
+	}
+
+	return w.Recorder.Write(b)
+}
+
+func set(values []string) map[string]bool {
+	m := make(map[string]bool, len(values))
+	for _, v := range values {
+		m[v] = true
+	}
+
+	return m
+}
+
+// encode writes v as a JSON log field.
+func encode(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err.Error()
+	}
+
+	return string(b)
+}
@@ -0,0 +1,172 @@
+package dump
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestRedactBody(t *testing.T) {
+	r := newRedactor(DefaultRedactHeaders, append(DefaultRedactFields, "cpf"))
+
+	tt := []struct {
+		Name        string
+		ContentType string
+		Body        string
+		Truncated   bool
+		Expected    string
+	}{
+		{
+			Name:        "test json fields at any depth",
+			ContentType: "application/json; charset=utf-8",
+			Body:        `{"name":"demo","Password":"p4ss","user":{"cpf":123,"tags":[{"token":"t0k"}]}}`,
+			Expected:    `{"Password":"[REDACTED]","name":"demo","user":{"cpf":"[REDACTED]","tags":[{"token":"[REDACTED]"}]}}`,
+		},
+		{
+			Name:        "test truncated json",
+			ContentType: "application/problem+json",
+			Body:        `{"name":"demo","password":"p4ss","secret":"s3c`,
+			Truncated:   true,
+			Expected:    `{"name":"demo","password":"[REDACTED]","secret":"[REDACTED]"...[truncated]`,
+		},
+		{
+			Name:        "test form",
+			ContentType: "application/x-www-form-urlencoded",
+			Body:        "user=demo&password=p4ss",
+			Expected:    `{"password":"[REDACTED]","user":"demo"}`,
+		},
+		{
+			Name:        "test text",
+			ContentType: "text/plain",
+			Body:        "hello",
+			Expected:    "hello",
+		},
+		{
+			Name:        "test json sent as text",
+			ContentType: "text/plain",
+			Body:        `{"user":"demo","password":"p4ss"}`,
+			Expected:    `{"password":"[REDACTED]","user":"demo"}`,
+		},
+		{
+			Name:     "test untyped json fragment",
+			Body:     `log line {"password": "p4ss", "token":t0k}`,
+			Expected: `log line {"password":"[REDACTED]", "token":"[REDACTED]"}`,
+		},
+		{
+			Name:     "test untyped text starting with a value",
+			Body:     `true "password":"p4ss"`,
+			Expected: `true "password":"[REDACTED]"`,
+		},
+		{
+			Name:        "test binary",
+			ContentType: "image/png",
+			Body:        "\x89PNG",
+			Expected:    "4 bytes of image/png",
+		},
+		{
+			Name:     "test empty",
+			Expected: "",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			got := r.body(tc.ContentType, []byte(tc.Body), tc.Truncated)
+			if got != tc.Expected {
+				t.Fatalf("body() = %s, want %s", got, tc.Expected)
+			}
+		})
+	}
+}
+
+func TestRedactHeadersAndQuery(t *testing.T) {
+	r := newRedactor(append(DefaultRedactHeaders, "x-tenant-key"), DefaultRedactFields)
+
+	h := http.Header{"Authorization": {"Bearer t0k"}, "X-Tenant-Key": {"k3y"}, "Accept": {"text/plain", "application/json"}}
+	if got, want := r.headers(h), `{"Accept":"text/plain, application/json","Authorization":"[REDACTED]","X-Tenant-Key":"[REDACTED]"}`; got != want {
+		t.Fatalf("headers() = %s, want %s", got, want)
+	}
+
+	q := url.Values{"access_token": {"t0k"}, "limit": {"10"}}
+	if got, want := r.query(q), `{"access_token":"[REDACTED]","limit":"10"}`; got != want {
+		t.Fatalf("query() = %s, want %s", got, want)
+	}
+}
+
+func TestDumps(t *testing.T) {
+	tt := []struct {
+		Name     string
+		Config   Config
+		Route    string
+		Expected bool
+	}{
+		{
+			Name:     "test every route by default",
+			Route:    "/v1/demos",
+			Expected: true,
+		},
+		{
+			Name:     "test skipped route",
+			Config:   Config{Skip: []string{"/health"}},
+			Route:    "/health",
+			Expected: false,
+		},
+		{
+			Name:     "test opted in route",
+			Config:   Config{Routes: []string{"/v1/demos"}},
+			Route:    "/v1/demos",
+			Expected: true,
+		},
+		{
+			Name:     "test route not opted in",
+			Config:   Config{Routes: []string{"/v1/demos"}},
+			Route:    "/v1/demos/{id}",
+			Expected: false,
+		},
+		{
+			Name:     "test tiny sample rate",
+			Config:   Config{SampleRate: 1e-12},
+			Route:    "/v1/demos",
+			Expected: false,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			if got := New(tc.Config).dumps(tc.Route); got != tc.Expected {
+				t.Fatalf("dumps(%s) = %t, want %t", tc.Route, got, tc.Expected)
+			}
+		})
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	d := New(Config{MaxBodySize: 8})
+
+	var received string
+	router := mux.NewRouter()
+	router.Use(d.Middleware)
+	router.Path("/v1/demos").Methods(http.MethodPost).HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		received = string(b)
+
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write(b)
+	})
+
+	body := `{"name":"a name longer than the cap"}`
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/demos", strings.NewReader(body)))
+
+	if received != body {
+		t.Fatalf("handler read %q, want %q", received, body)
+	}
+	if rec.Code != http.StatusCreated || rec.Body.String() != body {
+		t.Fatalf("response = %d %q, want %d %q", rec.Code, rec.Body, http.StatusCreated, body)
+	}
+}
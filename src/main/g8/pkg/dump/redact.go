@@ -0,0 +1,153 @@
+package dump
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Redacted replaces the values masked in dumps.
+const Redacted = "[REDACTED]"
+
+// truncatedMark ends the bodies longer than the maximum size.
+const truncatedMark = "...[truncated]"
+
+var (
+	// DefaultRedactHeaders are always masked.
+	DefaultRedactHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key"}
+	// DefaultRedactFields are always masked in JSON bodies, forms and query
+	// parameters.
+	DefaultRedactFields = []string{"password", "secret", "token", "access_token", "refresh_token", "client_secret", "api_key"}
+)
+
+// redactor masks headers and fields by name, ignoring case.
+type redactor struct {
+	maskHeaders map[string]bool
+	maskFields  map[string]bool
+	// jsonField matches the fields of JSON bodies that cannot be decoded,
+	// such as truncated ones.
+	jsonField *regexp.Regexp
+}
+
+func newRedactor(headers, fields []string) *redactor {
+	r := &redactor{maskHeaders: make(map[string]bool), maskFields: make(map[string]bool)}
+
+	for _, h := range headers {
+		r.maskHeaders[http.CanonicalHeaderKey(h)] = true
+	}
+
+	quoted := make([]string, 0, len(fields))
+	for _, f := range fields {
+		r.maskFields[strings.ToLower(f)] = true
+		quoted = append(quoted, regexp.QuoteMeta(f))
+	}
+	r.jsonField = regexp.MustCompile(`(?i)"(` + strings.Join(quoted, "|") + `)"\s*:\s*("(?:[^"\\]|\\.)*"?|[^,}\]\s]*)`)
+
+	return r
+}
+
+func (r *redactor) headers(h http.Header) string {
+	out := make(map[string]string, len(h))
+	for k, v := range h {
+		if r.maskHeaders[http.CanonicalHeaderKey(k)] {
+			out[k] = Redacted
+		} else {
+			out[k] = strings.Join(v, ", ")
+		}
+	}
+
+	return encode(out)
+}
+
+func (r *redactor) query(q url.Values) string {
+	if len(q) == 0 {
+		return ""
+	}
+
+	return encode(r.values(q))
+}
+
+func (r *redactor) values(q url.Values) map[string]string {
+	out := make(map[string]string, len(q))
+	for k, v := range q {
+		if r.maskFields[strings.ToLower(k)] {
+			out[k] = Redacted
+		} else {
+			out[k] = strings.Join(v, ", ")
+		}
+	}
+
+	return out
+}
+
+// body describes b, a body of type ct: forms are redacted, JSON and other
+// text are redacted as JSON, since clients often send it mislabeled, and
+// binary content is only measured.
+func (r *redactor) body(ct string, b []byte, truncated bool) string {
+	if len(b) == 0 {
+		return ""
+	}
+
+	mt, _, _ := mime.ParseMediaType(ct)
+
+	var s string
+	switch {
+	case mt == "application/x-www-form-urlencoded":
+		q, err := url.ParseQuery(string(b))
+		if err != nil || truncated {
+			return strconv.Itoa(len(b)) + " bytes of unreadable form"
+		}
+		s = encode(r.values(q))
+	case strings.HasSuffix(mt, "json"), strings.HasPrefix(mt, "text/"), strings.HasSuffix(mt, "xml"), mt == "":
+		s = r.json(b, truncated)
+	default:
+		return strconv.Itoa(len(b)) + " bytes of " + mt
+	}
+
+	if truncated {
+		s += truncatedMark
+	}
+
+	return s
+}
+
+// json masks the redacted fields of the JSON document b, falling back to
+// matching them in the text when b is not a whole document.
+func (r *redactor) json(b []byte, truncated bool) string {
+	if !truncated {
+		d := json.NewDecoder(bytes.NewReader(b))
+		d.UseNumber()
+
+		var v interface{}
+		if err := d.Decode(&v); err == nil && d.Decode(&struct{}{}) == io.EOF {
+			return encode(r.walk(v))
+		}
+	}
+
+	return r.jsonField.ReplaceAllString(string(b), `"\$1":"`+Redacted+`"`)
+}
+
+func (r *redactor) walk(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, e := range t {
+			if r.maskFields[strings.ToLower(k)] {
+				t[k] = Redacted
+			} else {
+				t[k] = r.walk(e)
+			}
+		}
+	case []interface{}:
+		for i, e := range t {
+			t[i] = r.walk(e)
+		}
+	}
+
+	return v
+}
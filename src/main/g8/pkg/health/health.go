@@ -0,0 +1,305 @@
+// Package health serves the liveness and readiness endpoints probed by
+// Kubernetes, running the checks registered by the service dependencies.
+package health
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Gympass/gcore/v3/gcontext"
+	"github.com/Gympass/gcore/v3/glog"
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/rest"
+	"github.com/pkg/errors"
+)
+
+// Status of a check or of the whole service.
+type Status string
+
+const (
+	// StatusUp means the check passed.
+	StatusUp Status = "up"
+	// StatusDown means the check failed or timed out.
+	StatusDown Status = "down"
+
+	// ShutdownCheck is the check reported down once shutdown begins.
+	ShutdownCheck = "shutdown"
+
+	defaultTimeout  = time.Second
+	defaultCacheTTL = time.Second
+)
+
+// ErrShuttingDown is reported by the shutdown check once shutdown begins.
+var ErrShuttingDown = errors.New("server is shutting down")
+
+// Checker is implemented by the dependencies the service needs to serve
+// requests, such as database pools or message producers.
+type Checker interface {
+	// Name identifies the check in the readiness report.
+	Name() string
+	// Check returns an error when the dependency is not usable. It must
+	// give up when ctx is done.
+	Check(ctx context.Context) error
+}
+
+// Detailer is implemented by checkers with more to report than a status,
+// such as connection pool statistics.
+type Detailer interface {
+	Details() interface{}
+}
+
+type checkerFunc struct {
+	name string
+	fn   func(context.Context) error
+}
+
+func (c checkerFunc) Name() string                    { return c.name }
+func (c checkerFunc) Check(ctx context.Context) error { return c.fn(ctx) }
+
+// CheckerFunc adapts fn into a Checker called name.
+func CheckerFunc(name string, fn func(ctx context.Context) error) Checker {
+	return checkerFunc{name: name, fn: fn}
+}
+
+// Config used by health package
+type Config struct {
+	Service string
+	Logger  glog.Logger
+	// Timeout bounds each check without a timeout of its own.
+	Timeout time.Duration
+	// CacheTTL is how long a readiness report is reused before the checks
+	// run again, so frequent probes do not hammer the dependencies.
+	CacheTTL time.Duration
+}
+
+type registration struct {
+	checker Checker
+	timeout time.Duration
+}
+
+// Health holds the registered checks and serves their reports.
+type Health struct {
+	service  string
+	logger   glog.Logger
+	timeout  time.Duration
+	cacheTTL time.Duration
+	started  time.Time
+
+	shuttingDown atomic.Bool
+
+	mu       sync.Mutex
+	checks   []registration
+	report   Report
+	reported time.Time
+}
+
+// New creates a Health based on configuration properties
+func New(cfg Config) *Health {
+	h := &Health{
+		service:  cfg.Service,
+		logger:   cfg.Logger,
+		timeout:  cfg.Timeout,
+		cacheTTL: cfg.CacheTTL,
+		started:  time.Now(),
+	}
+
+	if h.timeout <= 0 {
+		h.timeout = defaultTimeout
+	}
+
+	if h.cacheTTL <= 0 {
+		h.cacheTTL = defaultCacheTTL
+	}
+
+	return h
+}
+
+// Register adds c to the readiness checks. A zero timeout uses
+// Config.Timeout.
+func (h *Health) Register(c Checker, timeout time.Duration) {
+	if timeout <= 0 {
+		timeout = h.timeout
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.checks = append(h.checks, registration{checker: c, timeout: timeout})
+	h.reported = time.Time{}
+}
+
+// Shutdown turns readiness down for good, so Kubernetes stops routing
+// traffic to the pod while in-flight requests drain.
+func (h *Health) Shutdown() {
+	if h.shuttingDown.CompareAndSwap(false, true) {
+		h.logger.Warn(gcontext.NewContext(context.Background()), "Readiness is down, shutdown has begun.")
+	}
+}
+
+// ShutdownOnSignal calls Shutdown as soon as one of the signals arrives.
+// httpserver.Run listens to the same signals to stop the server, so this
+// makes readiness fail during the graceful shutdown.
+func (h *Health) ShutdownOnSignal(sig ...os.Signal) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, sig...)
+
+	go func() {
+		<-ch
+		signal.Stop(ch)
+		h.Shutdown()
+	}()
+}
+
+// CheckResult is the outcome of one check.
+type CheckResult struct {
+	Name    string  `json:"name"`
+	Status  Status  `json:"status"`
+	Latency float64 `json:"latency_ms"`
+	Error   string  `json:"error,omitempty"`
+	// Details are reported by checkers implementing Detailer.
+	Details interface{} `json:"details,omitempty"`
+}
+
+// Report is the body of the health endpoints.
+type Report struct {
+	Status  Status        `json:"status"`
+	Service string        `json:"service"`
+	Uptime  string        `json:"uptime"`
+	Checks  []CheckResult `json:"checks,omitempty"`
+}
+
+// Live ...
+// ShowEntity godoc
+// @Summary Liveness probe
+// @Description Reports the process is running, whatever the state of its dependencies
+// @Produce  json
+// @Success 200 {object} Report
+// @Router /health/live [get]
+func (h *Health) Live(w http.ResponseWriter, r *http.Request) {
+	h.send(w, r, http.StatusOK, Report{Status: StatusUp, Service: h.service, Uptime: h.uptime()})
+}
+
+// Ready ...
+// ShowEntity godoc
+// @Summary Readiness probe
+// @Description Runs the registered dependency checks, failing once shutdown begins
+// @Produce  json
+// @Success 200 {object} Report
+// @Failure 503 {object} Report
+// @Router /health/ready [get]
+func (h *Health) Ready(w http.ResponseWriter, r *http.Request) {
+	report := h.Check()
+
+	status := http.StatusOK
+	if report.Status != StatusUp {
+		status = http.StatusServiceUnavailable
+	}
+
+	h.send(w, r, status, report)
+}
+
+// Check returns the readiness report, running the checks concurrently
+// unless the last report is younger than Config.CacheTTL.
+func (h *Health) Check() Report {
+	if h.shuttingDown.Load() {
+		return Report{
+			Status:  StatusDown,
+			Service: h.service,
+			Uptime:  h.uptime(),
+			Checks:  []CheckResult{{Name: ShutdownCheck, Status: StatusDown, Error: ErrShuttingDown.Error()}},
+		}
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if time.Since(h.reported) < h.cacheTTL {
+		report := h.report
+		report.Uptime = h.uptime()
+		return report
+	}
+
+	results := make([]CheckResult, len(h.checks))
+
+	var wg sync.WaitGroup
+	for i := range h.checks {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = h.run(h.checks[i])
+		}(i)
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusUp, Service: h.service, Uptime: h.uptime(), Checks: results}
+	for _, res := range results {
+		if res.Status != StatusUp {
+			report.Status = StatusDown
+		}
+	}
+
+	h.report, h.reported = report, time.Now()
+
+	return report
+}
+
+func (h *Health) run(reg registration) CheckResult {
+	// Results are shared by the following probes, so checks are not tied
+	// to the request that happened to trigger them.
+	ctx, cancel := context.WithTimeout(context.Background(), reg.timeout)
+	defer cancel()
+
+	res := CheckResult{Name: reg.checker.Name(), Status: StatusUp}
+
+	start := time.Now()
+	err := runCheck(ctx, reg.checker)
+	res.Latency = float64(time.Since(start).Microseconds()) / 1000
+
+	if d, ok := reg.checker.(Detailer); ok {
+		res.Details = d.Details()
+	}
+
+	if err != nil {
+		res.Status, res.Error = StatusDown, err.Error()
+
+		lctx := gcontext.NewContext(context.Background())
+		gcontext.AddString(lctx, "health.check", res.Name)
+		gcontext.AddError(lctx, err)
+		h.logger.Warn(lctx, "Health check failed.")
+	}
+
+	return res
+}
+
+// runCheck returns when the check does or its timeout expires, whichever
+// comes first, so a check ignoring its context cannot hang the probe.
+func runCheck(ctx context.Context, c Checker) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- c.Check(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return errors.Wrap(ctx.Err(), "check timed out")
+	}
+}
+
+func (h *Health) uptime() string {
+	return time.Since(h.started).Round(time.Second).String()
+}
+
+func (h *Health) send(w http.ResponseWriter, r *http.Request, status int, report Report) {
+	w.Header().Set("Cache-Control", "no-store")
+
+	if err := rest.Send(w, r, status, report); err != nil {
+		gcontext.AddError(r.Context(), err)
+		h.logger.Error(r.Context(), "Failed to write health report.")
+	}
+}
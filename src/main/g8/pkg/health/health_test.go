@@ -0,0 +1,119 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Gympass/gcore/v3/glog"
+	"github.com/pkg/errors"
+)
+
+func TestReady(t *testing.T) {
+	tt := []struct {
+		Name           string
+		Checks         []Checker
+		ExpectedStatus int
+		ExpectedChecks map[string]Status
+	}{
+		{
+			Name:           "test ready without checks",
+			ExpectedStatus: http.StatusOK,
+			ExpectedChecks: map[string]Status{},
+		},
+		{
+			Name: "test ready with every check up",
+			Checks: []Checker{
+				CheckerFunc("postgres", func(context.Context) error { return nil }),
+				CheckerFunc("kafka", func(context.Context) error { return nil }),
+			},
+			ExpectedStatus: http.StatusOK,
+			ExpectedChecks: map[string]Status{"postgres": StatusUp, "kafka": StatusUp},
+		},
+		{
+			Name: "test not ready with failing and hanging checks",
+			Checks: []Checker{
+				CheckerFunc("postgres", func(context.Context) error { return errors.New("connection refused") }),
+				CheckerFunc("cache", func(context.Context) error { select {} }),
+				CheckerFunc("kafka", func(context.Context) error { return nil }),
+			},
+			ExpectedStatus: http.StatusServiceUnavailable,
+			ExpectedChecks: map[string]Status{"postgres": StatusDown, "cache": StatusDown, "kafka": StatusUp},
+		},
+	}
+
+	for _, testCase := range tt {
+		t.Run(testCase.Name, func(t *testing.T) {
+			h := New(Config{Service: "test-service", Logger: glog.Noop(), Timeout: 50 * time.Millisecond})
+			for _, c := range testCase.Checks {
+				h.Register(c, 0)
+			}
+
+			rr := httptest.NewRecorder()
+			start := time.Now()
+			h.Ready(rr, httptest.NewRequest(http.MethodGet, "/health/ready", nil))
+
+			if time.Since(start) > time.Second {
+				t.Fatalf("Expected checks to run concurrently within their timeout")
+			}
+
+			if rr.Code != testCase.ExpectedStatus {
+				t.Fatalf("Expected status %d and got %d", testCase.ExpectedStatus, rr.Code)
+			}
+
+			var report Report
+			if err := json.NewDecoder(rr.Body).Decode(&report); err != nil {
+				t.Fatal(err)
+			}
+
+			if len(report.Checks) != len(testCase.ExpectedChecks) {
+				t.Fatalf("Expected %d checks and got %+v", len(testCase.ExpectedChecks), report.Checks)
+			}
+
+			for _, c := range report.Checks {
+				if c.Status != testCase.ExpectedChecks[c.Name] {
+					t.Fatalf("Expected %s to be %s and got %+v", c.Name, testCase.ExpectedChecks[c.Name], c)
+				}
+			}
+		})
+	}
+}
+
+func TestReadyCachesResults(t *testing.T) {
+	var calls int32
+
+	h := New(Config{Logger: glog.Noop(), CacheTTL: time.Minute})
+	h.Register(CheckerFunc("postgres", func(context.Context) error {
+		atomic.AddInt32(&calls, 1)
+		return nil
+	}), 0)
+
+	for i := 0; i < 3; i++ {
+		h.Check()
+	}
+
+	if calls != 1 {
+		t.Fatalf("Expected the check to run once and got %d", calls)
+	}
+}
+
+func TestReadyDownOnShutdown(t *testing.T) {
+	h := New(Config{Logger: glog.Noop()})
+	h.Shutdown()
+
+	rr := httptest.NewRecorder()
+	h.Ready(rr, httptest.NewRequest(http.MethodGet, "/health/ready", nil))
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected status %d and got %d", http.StatusServiceUnavailable, rr.Code)
+	}
+
+	rr = httptest.NewRecorder()
+	h.Live(rr, httptest.NewRequest(http.MethodGet, "/health/live", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected liveness to stay up and got %d", rr.Code)
+	}
+}
@@ -0,0 +1,362 @@
+// Package idempotency lets clients retry mutating requests safely: the
+// first response sent for an Idempotency-Key is stored and replayed to the
+// retries carrying the same key.
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Gympass/gcore/v3/gcontext"
+	"github.com/Gympass/gcore/v3/gerror"
+	"github.com/Gympass/gcore/v3/glog"
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/recorder"
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/requestid"
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/rest"
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/rest/auth"
+	"github.com/pkg/errors"
+)
+
+const (
+	// HeaderKey is the request header carrying the idempotency key.
+	HeaderKey = "Idempotency-Key"
+	// HeaderReplayed is set to true on replayed responses.
+	HeaderReplayed = "Idempotent-Replayed"
+
+	// ProblemTypeKeyInUse identifies retries sent while the first request
+	// with their key is still running.
+	ProblemTypeKeyInUse = "urn:problem-type:idempotency-key-in-use"
+	// ProblemTypeKeyReused identifies keys sent again with another request.
+	ProblemTypeKeyReused = "urn:problem-type:idempotency-key-reused"
+	// ProblemTypeBodyTooLarge identifies requests with a key whose body
+	// exceeds Config.MaxBodySize.
+	ProblemTypeBodyTooLarge = "urn:problem-type:idempotency-body-too-large"
+
+	maxKeyLength       = 255
+	defaultRetention   = 24 * time.Hour
+	defaultLockTimeout = time.Minute
+	defaultMaxBodySize = 1 << 20
+)
+
+// unstoredHeaders are set by the middlewares around Handle for each
+// response, so they are neither stored nor replayed.
+var unstoredHeaders = []string{"Content-Encoding", "Vary", requestid.Header}
+
+var (
+	// ErrKeyInUse is returned while the request holding a key runs.
+	ErrKeyInUse = errors.New("a request with this idempotency key is in progress")
+	// ErrKeyReused is returned when a key comes with another request.
+	ErrKeyReused = errors.New("idempotency key was used for another request")
+	// ErrInvalidKey is returned for empty or too long keys.
+	ErrInvalidKey = errors.New("invalid idempotency key")
+	// ErrBodyTooLarge is returned for bodies exceeding Config.MaxBodySize.
+	ErrBodyTooLarge = errors.New("request body too large")
+)
+
+func init() {
+	rest.RegisterProblem(ErrKeyInUse, rest.ProblemType{
+		Type:   ProblemTypeKeyInUse,
+		Title:  "Idempotency key in use",
+		Status: http.StatusConflict,
+	})
+	rest.RegisterProblem(ErrKeyReused, rest.ProblemType{
+		Type:   ProblemTypeKeyReused,
+		Title:  "Idempotency key reused",
+		Status: http.StatusUnprocessableEntity,
+	})
+	rest.RegisterProblem(ErrBodyTooLarge, rest.ProblemType{
+		Type:   ProblemTypeBodyTooLarge,
+		Title:  "Request body too large",
+		Status: http.StatusRequestEntityTooLarge,
+	})
+}
+
+// Record is what a Store keeps for a key.
+type Record struct {
+	Key string
+	// Fingerprint identifies the request that reserved the key.
+	Fingerprint string
+	// Completed tells whether the response below was stored, otherwise
+	// the request is still running.
+	Completed bool
+	Status    int
+	Header    http.Header
+	Body      []byte
+	CreatedAt time.Time
+	// ExpiresAt is when the key may be reserved again.
+	ExpiresAt time.Time
+}
+
+// Store keeps the records of the keys. Records past their ExpiresAt must
+// be treated as missing.
+type Store interface {
+	// Lock reserves rec.Key for the request identified by rec. When the key
+	// is already reserved or completed, it returns the current record and
+	// false instead.
+	Lock(ctx context.Context, rec Record) (Record, bool, error)
+	// Complete stores the response of the request holding rec.Key.
+	Complete(ctx context.Context, rec Record) error
+	// Unlock releases rec.Key, held by a request that did not complete,
+	// so it can be retried.
+	Unlock(ctx context.Context, rec Record) error
+	// Purge deletes the records expired at now and returns how many.
+	Purge(ctx context.Context, now time.Time) (int64, error)
+}
+
+// Config used by idempotency package
+type Config struct {
+	Store  Store
+	Logger glog.Logger
+	// Retention is how long responses are replayed, 24h when zero.
+	Retention time.Duration
+	// LockTimeout releases keys held by requests that never completed,
+	// such as those of a crashed instance, 1m when zero.
+	LockTimeout time.Duration
+	// Required rejects requests without key with 400.
+	Required bool
+	// MaxBodySize bounds the bodies of requests with a key, read whole to
+	// fingerprint them, 1MiB when zero. Larger ones are rejected with 413.
+	MaxBodySize int64
+	// Now returns the current time, time.Now when nil.
+	Now func() time.Time
+}
+
+// Idempotency is the middleware storing and replaying responses.
+type Idempotency struct {
+	cfg Config
+}
+
+// New creates an Idempotency based on configuration properties
+func New(c Config) *Idempotency {
+	if c.Retention <= 0 {
+		c.Retention = defaultRetention
+	}
+	if c.LockTimeout <= 0 {
+		c.LockTimeout = defaultLockTimeout
+	}
+	if c.MaxBodySize <= 0 {
+		c.MaxBodySize = defaultMaxBodySize
+	}
+	if c.Now == nil {
+		c.Now = time.Now
+	}
+
+	return &Idempotency{cfg: c}
+}
+
+// Handle wraps a handler of a middleware.GMiddlewareHandlerError, inside
+// rest.Problems so its errors are written as problem details:
+//
+//	mw.HandlerError(rest.Problems(idem.Handle(handler.CreateDemo)))
+//
+// Requests with an Idempotency-Key reserve it while next runs. The
+// response next writes is stored and replayed to later requests with the
+// same key and fingerprint (method, path, query and body); the same key with
+// another fingerprint fails with ErrKeyReused, and while the first request
+// runs with ErrKeyInUse. Returned errors and 5xx responses release the key
+// instead, so clients can retry them. Behind auth.Authenticator.Require,
+// keys are scoped to the subject of the token, so that clients cannot
+// replay the responses of one another. A nil Idempotency returns next.
+func (i *Idempotency) Handle(next func(http.ResponseWriter, *http.Request) error) func(http.ResponseWriter, *http.Request) error {
+	if i == nil {
+		return next
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) error {
+		key := r.Header.Get(HeaderKey)
+		if key == "" && !i.cfg.Required {
+			return next(w, r)
+		}
+		if key == "" || len(key) > maxKeyLength {
+			return gerror.NewBadRequest(ErrInvalidKey).
+				WithMessage(HeaderKey + " header must hold 1 to " + strconv.Itoa(maxKeyLength) + " characters")
+		}
+
+		fp, err := fingerprint(r, i.cfg.MaxBodySize)
+		if err != nil {
+			return err
+		}
+
+		now := i.cfg.Now()
+		rec := Record{Key: scope(r, key), Fingerprint: fp, CreatedAt: now, ExpiresAt: now.Add(i.cfg.LockTimeout)}
+
+		cur, locked, err := i.cfg.Store.Lock(r.Context(), rec)
+		if err != nil {
+			return errors.Wrap(err, "locking idempotency key")
+		}
+
+		if !locked {
+			switch {
+			case cur.Fingerprint != fp:
+				return ErrKeyReused
+			case !cur.Completed:
+				return ErrKeyInUse
+			}
+			return replay(w, cur)
+		}
+
+		rw := &bodyRecorder{Recorder: recorder.New(w), before: w.Header().Clone()}
+		err = next(rw, r)
+
+		// Client disconnections must not leave the key reserved.
+		ctx := gcontext.NewContext(context.Background())
+
+		if err != nil || !rw.Started() || rw.Status() >= http.StatusInternalServerError {
+			if uerr := i.cfg.Store.Unlock(ctx, rec); uerr != nil {
+				i.logError(r, uerr, "Could not unlock idempotency key.")
+			}
+			return err
+		}
+
+		rec.Completed = true
+		rec.Status, rec.Header, rec.Body = rw.Status(), rw.header, rw.body.Bytes()
+		rec.ExpiresAt = i.cfg.Now().Add(i.cfg.Retention)
+		if cerr := i.cfg.Store.Complete(ctx, rec); cerr != nil {
+			i.logError(r, cerr, "Could not store idempotent response.")
+		}
+
+		return nil
+	}
+}
+
+// Purge deletes the expired records every interval until ctx is done.
+func (i *Idempotency) Purge(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			lctx := gcontext.NewContext(context.Background())
+
+			n, err := i.cfg.Store.Purge(ctx, i.cfg.Now())
+			if err != nil {
+				gcontext.AddError(lctx, err)
+				i.cfg.Logger.Error(lctx, "Could not purge idempotency keys.")
+				continue
+			}
+
+			gcontext.AddString(lctx, "idempotency.purged", strconv.FormatInt(n, 10))
+			i.cfg.Logger.Debug(lctx, "Idempotency keys purged.")
+		}
+	}
+}
+
+func (i *Idempotency) logError(r *http.Request, err error, msg string) {
+	gcontext.AddError(r.Context(), err)
+	i.cfg.Logger.Error(r.Context(), msg)
+}
+
+// scope prefixes key with the hash of the token subject of r, if any. The
+// hash has a fixed length, so scoped keys never collide.
+func scope(r *http.Request, key string) string {
+	c, ok := auth.FromContext(r.Context())
+	if !ok || c.Subject == "" {
+		return key
+	}
+
+	sum := sha256.Sum256([]byte(c.Subject))
+	return hex.EncodeToString(sum[:]) + ":" + key
+}
+
+// fingerprint hashes the method, path, query and body of r, leaving the
+// body readable for the handler. The query is sorted, so that reordering
+// its parameters does not change the fingerprint. Bodies over max bytes fail with ErrBodyTooLarge.
+func fingerprint(r *http.Request, max int64) (string, error) {
+	var body []byte
+	if r.Body != nil {
+		var err error
+		if body, err = io.ReadAll(io.LimitReader(r.Body, max+1)); err != nil {
+			return "", errors.Wrap(err, "reading body")
+		}
+		if int64(len(body)) > max {
+			return "", errors.Wrapf(ErrBodyTooLarge, "over %d bytes", max)
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	h := sha256.New()
+	io.WriteString(h, r.Method+" "+r.URL.Path+"?"+r.URL.Query().Encode()+"\n")
+	h.Write(body)
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// replay writes the response stored in rec. The headers of unstoredHeaders
+// are left to the current middlewares, records stored before they were
+// filtered out may still hold them.
+func replay(w http.ResponseWriter, rec Record) error {
+	for k, v := range rec.Header {
+		if !unstored(k) {
+			w.Header()[k] = v
+		}
+	}
+	w.Header().Set(HeaderReplayed, "true")
+	w.WriteHeader(rec.Status)
+
+	_, err := w.Write(rec.Body)
+
+	return err
+}
+
+func unstored(key string) bool {
+	for _, k := range unstoredHeaders {
+		if http.CanonicalHeaderKey(key) == http.CanonicalHeaderKey(k) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// bodyRecorder captures the response written through it, along with the
+// headers set by the handler: those differing from before.
+type bodyRecorder struct {
+	*recorder.Recorder
+
+	before http.Header
+	header http.Header
+	body   bytes.Buffer
+}
+
+func (rw *bodyRecorder) WriteHeader(status int) {
+	if !rw.Started() {
+		rw.header = http.Header{}
+		for k, v := range rw.Header() {
+			if !unstored(k) && !equal(v, rw.before[k]) {
+				rw.header[k] = append([]string(nil), v...)
+			}
+		}
+	}
+	rw.Recorder.WriteHeader(status)
+}
+
+func (rw *bodyRecorder) Write(b []byte) (int, error) {
+	if !rw.Started() {
+		rw.WriteHeader(http.StatusOK)
+	}
+	rw.body.Write(b)
+
+	return rw.Recorder.Write(b)
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,257 @@
+package idempotency_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Gympass/gcore/v3/glog"
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/idempotency"
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/requestid"
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/rest"
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/rest/auth"
+	"github.com/pkg/errors"
+)
+
+var errHandlerTest = errors.New("handler failed")
+
+func TestHandle(t *testing.T) {
+	var calls int32
+	fail := make(map[string]bool)
+
+	idem := idempotency.New(idempotency.Config{Store: idempotency.NewMemoryStore(), Logger: glog.Noop()})
+	h := rest.Problems(idem.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		n := atomic.AddInt32(&calls, 1)
+		if fail[r.Header.Get(idempotency.HeaderKey)] {
+			return errHandlerTest
+		}
+		w.Header().Set("Location", "/v1/demo/1")
+		w.WriteHeader(http.StatusCreated)
+		_, err := w.Write([]byte(`{"call":` + strconv.Itoa(int(n)) + `}`))
+		return err
+	}))
+
+	tt := []struct {
+		Name             string
+		Key              string
+		Query            string
+		Body             string
+		Fail             bool
+		ExpectedStatus   int
+		ExpectedBody     string
+		ExpectedReplayed bool
+		ExpectedCalls    int32
+	}{
+		{Name: "test without key", Body: `{"a":1}`, ExpectedStatus: http.StatusCreated, ExpectedBody: `{"call":1}`, ExpectedCalls: 1},
+		{Name: "test first request", Key: "k1", Body: `{"a":1}`, ExpectedStatus: http.StatusCreated, ExpectedBody: `{"call":2}`, ExpectedCalls: 2},
+		{Name: "test replay", Key: "k1", Body: `{"a":1}`, ExpectedStatus: http.StatusCreated, ExpectedBody: `{"call":2}`, ExpectedReplayed: true, ExpectedCalls: 2},
+		{Name: "test key reused", Key: "k1", Body: `{"a":2}`, ExpectedStatus: http.StatusUnprocessableEntity, ExpectedBody: idempotency.ProblemTypeKeyReused, ExpectedCalls: 2},
+		{Name: "test failure releases key", Key: "k2", Body: `{"a":1}`, Fail: true, ExpectedStatus: http.StatusInternalServerError, ExpectedCalls: 3},
+		{Name: "test retry after failure", Key: "k2", Body: `{"a":1}`, ExpectedStatus: http.StatusCreated, ExpectedBody: `{"call":4}`, ExpectedCalls: 4},
+		{Name: "test key too long", Key: strings.Repeat("k", 256), Body: `{"a":1}`, ExpectedStatus: http.StatusBadRequest, ExpectedCalls: 4},
+		{Name: "test first request with query", Key: "k3", Query: "a=1&b=2", Body: `{"a":1}`, ExpectedStatus: http.StatusCreated, ExpectedBody: `{"call":5}`, ExpectedCalls: 5},
+		{Name: "test replay with reordered query", Key: "k3", Query: "b=2&a=1", Body: `{"a":1}`, ExpectedStatus: http.StatusCreated, ExpectedBody: `{"call":5}`, ExpectedReplayed: true, ExpectedCalls: 5},
+		{Name: "test key reused with another query", Key: "k3", Query: "a=1", Body: `{"a":1}`, ExpectedStatus: http.StatusUnprocessableEntity, ExpectedBody: idempotency.ProblemTypeKeyReused, ExpectedCalls: 5},
+	}
+
+	for _, tc := range tt {
+		fail[tc.Key] = tc.Fail
+
+		r := httptest.NewRequest(http.MethodPost, "/v1/demo?"+tc.Query, strings.NewReader(tc.Body))
+		if tc.Key != "" {
+			r.Header.Set(idempotency.HeaderKey, tc.Key)
+		}
+		w := httptest.NewRecorder()
+
+		if err := h(w, r); err != nil {
+			t.Fatalf("%s: error = %v", tc.Name, err)
+		}
+		if w.Code != tc.ExpectedStatus {
+			t.Fatalf("%s: status = %d, want %d (%s)", tc.Name, w.Code, tc.ExpectedStatus, w.Body)
+		}
+		if !strings.Contains(w.Body.String(), tc.ExpectedBody) {
+			t.Fatalf("%s: body = %s, want it to contain %s", tc.Name, w.Body, tc.ExpectedBody)
+		}
+		if got := w.Header().Get(idempotency.HeaderReplayed) == "true"; got != tc.ExpectedReplayed {
+			t.Fatalf("%s: replayed = %v, want %v", tc.Name, got, tc.ExpectedReplayed)
+		}
+		if tc.ExpectedReplayed && w.Header().Get("Location") != "/v1/demo/1" {
+			t.Fatalf("%s: location = %q, want the stored one", tc.Name, w.Header().Get("Location"))
+		}
+		if got := atomic.LoadInt32(&calls); got != tc.ExpectedCalls {
+			t.Fatalf("%s: handler calls = %d, want %d", tc.Name, got, tc.ExpectedCalls)
+		}
+	}
+}
+
+func TestHandleInFlight(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+
+	idem := idempotency.New(idempotency.Config{Store: idempotency.NewMemoryStore(), Logger: glog.Noop()})
+	h := rest.Problems(idem.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		close(started)
+		<-release
+		w.WriteHeader(http.StatusCreated)
+		return nil
+	}))
+
+	send := func() *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/v1/demo", strings.NewReader(`{}`))
+		r.Header.Set(idempotency.HeaderKey, "k")
+		w := httptest.NewRecorder()
+		if err := h(w, r); err != nil {
+			t.Errorf("error = %v", err)
+		}
+		return w
+	}
+
+	first := make(chan *httptest.ResponseRecorder)
+	go func() { first <- send() }()
+
+	select {
+	case <-started:
+	case <-time.After(time.Second):
+		t.Fatal("first request did not start")
+	}
+
+	if w := send(); w.Code != http.StatusConflict || !strings.Contains(w.Body.String(), idempotency.ProblemTypeKeyInUse) {
+		t.Fatalf("duplicate status = %d, body = %s, want 409", w.Code, w.Body)
+	}
+
+	close(release)
+	if w := <-first; w.Code != http.StatusCreated {
+		t.Fatalf("first status = %d, want 201", w.Code)
+	}
+}
+
+func TestHandleRequired(t *testing.T) {
+	idem := idempotency.New(idempotency.Config{Store: idempotency.NewMemoryStore(), Logger: glog.Noop(), Required: true})
+	h := rest.Problems(idem.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		w.WriteHeader(http.StatusCreated)
+		return nil
+	}))
+
+	w := httptest.NewRecorder()
+	if err := h(w, httptest.NewRequest(http.MethodPost, "/v1/demo", nil)); err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400", w.Code)
+	}
+}
+
+func TestHandleSubjects(t *testing.T) {
+	var calls int32
+
+	idem := idempotency.New(idempotency.Config{Store: idempotency.NewMemoryStore(), Logger: glog.Noop()})
+	h := rest.Problems(idem.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		n := atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusCreated)
+		_, err := w.Write([]byte(`{"call":` + strconv.Itoa(int(n)) + `}`))
+		return err
+	}))
+
+	tt := []struct {
+		Name             string
+		Subject          string
+		ExpectedBody     string
+		ExpectedReplayed bool
+	}{
+		{Name: "test first subject", Subject: "alice", ExpectedBody: `{"call":1}`},
+		{Name: "test other subject with same key", Subject: "bob", ExpectedBody: `{"call":2}`},
+		{Name: "test first subject replayed", Subject: "alice", ExpectedBody: `{"call":1}`, ExpectedReplayed: true},
+	}
+
+	for _, tc := range tt {
+		r := httptest.NewRequest(http.MethodPost, "/v1/demo", strings.NewReader(`{"a":1}`))
+		r.Header.Set(idempotency.HeaderKey, "k")
+		r = r.WithContext(auth.NewContext(r.Context(), &auth.Claims{Subject: tc.Subject}))
+		w := httptest.NewRecorder()
+
+		if err := h(w, r); err != nil {
+			t.Fatalf("%s: error = %v", tc.Name, err)
+		}
+		if w.Code != http.StatusCreated || w.Body.String() != tc.ExpectedBody {
+			t.Fatalf("%s: status = %d, body = %s, want 201 and %s", tc.Name, w.Code, w.Body, tc.ExpectedBody)
+		}
+		if got := w.Header().Get(idempotency.HeaderReplayed) == "true"; got != tc.ExpectedReplayed {
+			t.Fatalf("%s: replayed = %v, want %v", tc.Name, got, tc.ExpectedReplayed)
+		}
+	}
+}
+
+func TestHandleHeaders(t *testing.T) {
+	idem := idempotency.New(idempotency.Config{Store: idempotency.NewMemoryStore(), Logger: glog.Noop()})
+	h := rest.Problems(idem.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		w.Header().Set("Location", "/v1/demo/1")
+		w.Header().Set("Vary", "Accept")
+		w.WriteHeader(http.StatusCreated)
+		return nil
+	}))
+
+	send := func(id string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/v1/demo", strings.NewReader(`{}`))
+		r.Header.Set(idempotency.HeaderKey, "k")
+		w := httptest.NewRecorder()
+		// Set for each response by the middlewares around Handle.
+		w.Header().Set(requestid.Header, id)
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		if err := h(w, r); err != nil {
+			t.Fatalf("error = %v", err)
+		}
+		return w
+	}
+
+	send("req-1")
+	w := send("req-2")
+
+	if w.Header().Get(idempotency.HeaderReplayed) != "true" {
+		t.Fatalf("second response not replayed")
+	}
+	if got := w.Header().Get("Location"); got != "/v1/demo/1" {
+		t.Fatalf("location = %q, want the stored one", got)
+	}
+	if got := w.Header().Get(requestid.Header); got != "req-2" {
+		t.Fatalf("request id = %q, want the one of the retry", got)
+	}
+	if got := w.Header().Values("Access-Control-Allow-Origin"); len(got) != 1 {
+		t.Fatalf("allowed origins = %v, want the one of the retry only", got)
+	}
+	if got := w.Header().Get("Vary"); got != "" {
+		t.Fatalf("vary = %q, want none replayed", got)
+	}
+}
+
+func TestHandleBodyTooLarge(t *testing.T) {
+	idem := idempotency.New(idempotency.Config{Store: idempotency.NewMemoryStore(), Logger: glog.Noop(), MaxBodySize: 8})
+	h := rest.Problems(idem.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		w.WriteHeader(http.StatusCreated)
+		return nil
+	}))
+
+	tt := []struct {
+		Name           string
+		Body           string
+		ExpectedStatus int
+	}{
+		{Name: "test body at limit", Body: `{"a":12}`, ExpectedStatus: http.StatusCreated},
+		{Name: "test body over limit", Body: `{"a":123}`, ExpectedStatus: http.StatusRequestEntityTooLarge},
+	}
+
+	for i, tc := range tt {
+		r := httptest.NewRequest(http.MethodPost, "/v1/demo", strings.NewReader(tc.Body))
+		r.Header.Set(idempotency.HeaderKey, strconv.Itoa(i))
+		w := httptest.NewRecorder()
+
+		if err := h(w, r); err != nil {
+			t.Fatalf("%s: error = %v", tc.Name, err)
+		}
+		if w.Code != tc.ExpectedStatus {
+			t.Fatalf("%s: status = %d, want %d (%s)", tc.Name, w.Code, tc.ExpectedStatus, w.Body)
+		}
+	}
+}
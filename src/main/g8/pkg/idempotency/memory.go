@@ -0,0 +1,80 @@
+package idempotency
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore is a Store kept in memory, meant for tests and services
+// running a single instance.
+type MemoryStore struct {
+	mu      sync.Mutex
+	records map[string]Record
+}
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{records: map[string]Record{}}
+}
+
+// Lock implements Store.
+func (s *MemoryStore) Lock(_ context.Context, rec Record) (Record, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if cur, ok := s.records[rec.Key]; ok && cur.ExpiresAt.After(rec.CreatedAt) {
+		return copyRecord(cur), false, nil
+	}
+	s.records[rec.Key] = copyRecord(rec)
+
+	return rec, true, nil
+}
+
+// Complete implements Store.
+func (s *MemoryStore) Complete(_ context.Context, rec Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if cur, ok := s.records[rec.Key]; ok && cur.Fingerprint == rec.Fingerprint {
+		s.records[rec.Key] = copyRecord(rec)
+	}
+
+	return nil
+}
+
+// Unlock implements Store.
+func (s *MemoryStore) Unlock(_ context.Context, rec Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if cur, ok := s.records[rec.Key]; ok && cur.Fingerprint == rec.Fingerprint && !cur.Completed {
+		delete(s.records, rec.Key)
+	}
+
+	return nil
+}
+
+// Purge implements Store.
+func (s *MemoryStore) Purge(_ context.Context, now time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var n int64
+	for k, rec := range s.records {
+		if !rec.ExpiresAt.After(now) {
+			delete(s.records, k)
+			n++
+		}
+	}
+
+	return n, nil
+}
+
+// copyRecord keeps callers from sharing the header and body of rec.
+func copyRecord(rec Record) Record {
+	rec.Header = rec.Header.Clone()
+	rec.Body = append([]byte(nil), rec.Body...)
+
+	return rec
+}
@@ -0,0 +1,12 @@
+package idempotency_test
+
+import (
+	"testing"
+
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/idempotency"
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/idempotency/storetest"
+)
+
+func TestMemoryStore(t *testing.T) {
+	storetest.Run(t, idempotency.NewMemoryStore())
+}
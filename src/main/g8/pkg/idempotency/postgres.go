@@ -0,0 +1,108 @@
+package idempotency
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// lockKey inserts the record, or takes over an expired one, and
+	// returns nothing when the key is held.
+	lockKey = `INSERT INTO idempotency_keys (key, fingerprint, created_at, expires_at)
+VALUES (\$1, \$2, \$3, \$4)
+ON CONFLICT (key) DO UPDATE SET
+    fingerprint = EXCLUDED.fingerprint,
+    completed   = false,
+    status      = 0,
+    header      = NULL,
+    body        = NULL,
+    created_at  = EXCLUDED.created_at,
+    expires_at  = EXCLUDED.expires_at
+WHERE idempotency_keys.expires_at <= EXCLUDED.created_at
+RETURNING key`
+	selectKey = `SELECT key, fingerprint, completed, status, header, body, created_at, expires_at
+FROM idempotency_keys WHERE key = \$1`
+	completeKey = `UPDATE idempotency_keys
+SET completed = true, status = \$3, header = \$4, body = \$5, expires_at = \$6
+WHERE key = \$1 AND fingerprint = \$2`
+	unlockKey = `DELETE FROM idempotency_keys WHERE key = \$1 AND fingerprint = \$2 AND NOT completed`
+	purgeKeys = `DELETE FROM idempotency_keys WHERE expires_at <= \$1`
+)
+
+// PostgresStore is a Store backed by the idempotency_keys table, shared by
+// every instance of the service.
+type PostgresStore struct {
+	db *sql.DB
+}
+
+// NewPostgresStore creates a PostgresStore using db.
+func NewPostgresStore(db *sql.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// Lock implements Store.
+func (s *PostgresStore) Lock(ctx context.Context, rec Record) (Record, bool, error) {
+	var key string
+	err := s.db.QueryRowContext(ctx, lockKey, rec.Key, rec.Fingerprint, rec.CreatedAt, rec.ExpiresAt).Scan(&key)
+	if err == nil {
+		return rec, true, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return Record{}, false, errors.Wrap(err, "inserting idempotency key")
+	}
+
+	var (
+		cur    Record
+		header []byte
+	)
+	err = s.db.QueryRowContext(ctx, selectKey, rec.Key).
+		Scan(&cur.Key, &cur.Fingerprint, &cur.Completed, &cur.Status, &header, &cur.Body, &cur.CreatedAt, &cur.ExpiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		// Unlocked meanwhile, the client may retry at once.
+		return Record{Key: rec.Key, Fingerprint: rec.Fingerprint}, false, nil
+	}
+	if err != nil {
+		return Record{}, false, errors.Wrap(err, "selecting idempotency key")
+	}
+
+	if len(header) > 0 {
+		if err := json.Unmarshal(header, &cur.Header); err != nil {
+			return Record{}, false, errors.Wrap(err, "decoding idempotency header")
+		}
+	}
+
+	return cur, false, nil
+}
+
+// Complete implements Store.
+func (s *PostgresStore) Complete(ctx context.Context, rec Record) error {
+	header, err := json.Marshal(rec.Header)
+	if err != nil {
+		return errors.Wrap(err, "encoding idempotency header")
+	}
+
+	_, err = s.db.ExecContext(ctx, completeKey, rec.Key, rec.Fingerprint, rec.Status, header, rec.Body, rec.ExpiresAt)
+
+	return errors.Wrap(err, "updating idempotency key")
+}
+
+// Unlock implements Store.
+func (s *PostgresStore) Unlock(ctx context.Context, rec Record) error {
+	_, err := s.db.ExecContext(ctx, unlockKey, rec.Key, rec.Fingerprint)
+
+	return errors.Wrap(err, "deleting idempotency key")
+}
+
+// Purge implements Store.
+func (s *PostgresStore) Purge(ctx context.Context, now time.Time) (int64, error) {
+	res, err := s.db.ExecContext(ctx, purgeKeys, now)
+	if err != nil {
+		return 0, errors.Wrap(err, "purging idempotency keys")
+	}
+
+	return res.RowsAffected()
+}
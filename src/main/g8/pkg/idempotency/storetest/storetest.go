@@ -0,0 +1,144 @@
+// Package storetest holds the conformance suite every idempotency.Store
+// implementation must pass.
+package storetest
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/idempotency"
+)
+
+// Run executes the conformance suite against s, which may hold records
+// of other tests.
+func Run(t *testing.T, s idempotency.Store) {
+	t.Run("Lock", func(t *testing.T) { testLock(t, s) })
+	t.Run("Complete", func(t *testing.T) { testComplete(t, s) })
+	t.Run("Unlock", func(t *testing.T) { testUnlock(t, s) })
+	t.Run("Expiry", func(t *testing.T) { testExpiry(t, s) })
+}
+
+func testLock(t *testing.T, s idempotency.Store) {
+	ctx := context.Background()
+	rec := newRecord(t, "first", time.Minute)
+
+	if _, locked, err := s.Lock(ctx, rec); err != nil || !locked {
+		t.Fatalf("Lock() = %v, %v, want locked", locked, err)
+	}
+
+	other := rec
+	other.Fingerprint = "second"
+	cur, locked, err := s.Lock(ctx, other)
+	if err != nil || locked {
+		t.Fatalf("Lock() held key = %v, %v, want not locked", locked, err)
+	}
+	if cur.Fingerprint != rec.Fingerprint || cur.Completed {
+		t.Fatalf("Lock() held key returned %+v, want the running record", cur)
+	}
+}
+
+func testComplete(t *testing.T, s idempotency.Store) {
+	ctx := context.Background()
+	rec := newRecord(t, "first", time.Minute)
+
+	if _, locked, err := s.Lock(ctx, rec); err != nil || !locked {
+		t.Fatalf("Lock() = %v, %v, want locked", locked, err)
+	}
+
+	rec.Completed = true
+	rec.Status = http.StatusCreated
+	rec.Header = http.Header{"Location": {"/v1/demo/1"}, "Content-Type": {"application/json"}}
+	rec.Body = []byte(`{"id":"1"}`)
+	if err := s.Complete(ctx, rec); err != nil {
+		t.Fatalf("Complete() error = %v", err)
+	}
+
+	cur, locked, err := s.Lock(ctx, newRetry(rec))
+	if err != nil || locked {
+		t.Fatalf("Lock() completed key = %v, %v, want not locked", locked, err)
+	}
+	if !cur.Completed || cur.Status != rec.Status || string(cur.Body) != string(rec.Body) ||
+		cur.Header.Get("Location") != "/v1/demo/1" || cur.Fingerprint != rec.Fingerprint {
+		t.Fatalf("Lock() completed key returned %+v, want %+v", cur, rec)
+	}
+
+	// Unlocking a completed key keeps its response.
+	if err := s.Unlock(ctx, rec); err != nil {
+		t.Fatalf("Unlock() error = %v", err)
+	}
+	if cur, _, _ := s.Lock(ctx, newRetry(rec)); !cur.Completed {
+		t.Fatalf("Lock() after unlock returned %+v, want the completed record", cur)
+	}
+}
+
+func testUnlock(t *testing.T, s idempotency.Store) {
+	ctx := context.Background()
+	rec := newRecord(t, "first", time.Minute)
+
+	if _, locked, err := s.Lock(ctx, rec); err != nil || !locked {
+		t.Fatalf("Lock() = %v, %v, want locked", locked, err)
+	}
+	if err := s.Unlock(ctx, rec); err != nil {
+		t.Fatalf("Unlock() error = %v", err)
+	}
+	if _, locked, err := s.Lock(ctx, newRetry(rec)); err != nil || !locked {
+		t.Fatalf("Lock() after unlock = %v, %v, want locked", locked, err)
+	}
+}
+
+func testExpiry(t *testing.T, s idempotency.Store) {
+	ctx := context.Background()
+	rec := newRecord(t, "first", time.Minute)
+	rec.CreatedAt = rec.CreatedAt.Add(-time.Hour)
+	rec.ExpiresAt = rec.ExpiresAt.Add(-time.Hour)
+
+	if _, locked, err := s.Lock(ctx, rec); err != nil || !locked {
+		t.Fatalf("Lock() = %v, %v, want locked", locked, err)
+	}
+
+	// An expired key may be taken by another request.
+	retry := newRetry(rec)
+	retry.Fingerprint = "second"
+	if _, locked, err := s.Lock(ctx, retry); err != nil || !locked {
+		t.Fatalf("Lock() expired key = %v, %v, want locked", locked, err)
+	}
+
+	expired := newRecord(t, "first", time.Minute)
+	expired.CreatedAt = expired.CreatedAt.Add(-time.Hour)
+	expired.ExpiresAt = expired.ExpiresAt.Add(-time.Hour)
+	if _, locked, err := s.Lock(ctx, expired); err != nil || !locked {
+		t.Fatalf("Lock() = %v, %v, want locked", locked, err)
+	}
+
+	n, err := s.Purge(ctx, time.Now())
+	if err != nil || n < 1 {
+		t.Fatalf("Purge() = %d, %v, want at least one record", n, err)
+	}
+	if _, locked, err := s.Lock(ctx, newRetry(retry)); err != nil || locked {
+		t.Fatalf("Lock() live key after purge = %v, %v, want not locked", locked, err)
+	}
+}
+
+// newRecord returns a record with a random key, reserved for ttl.
+func newRecord(t *testing.T, fingerprint string, ttl time.Duration) idempotency.Record {
+	t.Helper()
+
+	key, err := uuid.NewV4()
+	if err != nil {
+		t.Fatalf("uuid.NewV4() error = %v", err)
+	}
+
+	now := time.Now().UTC().Truncate(time.Microsecond)
+
+	return idempotency.Record{Key: key.String(), Fingerprint: fingerprint, CreatedAt: now, ExpiresAt: now.Add(ttl)}
+}
+
+// newRetry returns the record a retry of rec would try to lock.
+func newRetry(rec idempotency.Record) idempotency.Record {
+	now := time.Now().UTC().Truncate(time.Microsecond)
+
+	return idempotency.Record{Key: rec.Key, Fingerprint: rec.Fingerprint, CreatedAt: now, ExpiresAt: now.Add(time.Minute)}
+}
@@ -0,0 +1,157 @@
+// Package keyset builds keyset (seek) pagination queries for cursors
+// issued by rest.Cursors, so listings never need OFFSET.
+package keyset
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Gympass/gcore/v3/gerror"
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/rest"
+	"github.com/pkg/errors"
+)
+
+// ErrKeysMismatch is returned when a cursor was issued for another sort order.
+var ErrKeysMismatch = errors.New("cursor sort keys do not match the listing")
+
+type key struct {
+	column string
+	desc   bool
+}
+
+// Builder writes the clauses selecting one page of a listing.
+type Builder struct {
+	keys  []key
+	names []string
+	page  rest.PageRequest
+}
+
+// New returns a Builder for page sorted by keys, most significant first.
+// Keys are column names, prefixed with "-" for descending order, and must
+// identify a row, usually by ending with the primary key. They are trusted
+// SQL and must never come from the request.
+func New(page rest.PageRequest, keys ...string) (*Builder, error) {
+	b := &Builder{page: page, names: keys}
+	for _, k := range keys {
+		b.keys = append(b.keys, key{column: strings.TrimPrefix(k, "-"), desc: strings.HasPrefix(k, "-")})
+	}
+
+	if page.Cursor == nil {
+		return b, nil
+	}
+
+	if strings.Join(page.Cursor.Keys, ",") != strings.Join(keys, ",") {
+		return nil, gerror.NewBadRequest(errors.Wrap(rest.ErrInvalidCursor, ErrKeysMismatch.Error())).
+			WithMessage("cursor does not belong to this listing")
+	}
+
+	return b, nil
+}
+
+// Where returns the condition selecting the rows past the cursor, with
+// placeholders numbered from firstArg, and its arguments. It is empty for
+// the first page.
+//
+// The condition is expanded as (a > x) OR (a = x AND b > y) so keys may
+// mix ascending and descending orders.
+func (b *Builder) Where(firstArg int) (string, []interface{}) {
+	cur := b.page.Cursor
+	if cur == nil {
+		return "", nil
+	}
+
+	args := make([]interface{}, len(cur.Values))
+	for i := range cur.Values {
+		args[i] = cur.Values[i]
+	}
+
+	ors := make([]string, len(b.keys))
+	for i := range b.keys {
+		ands := make([]string, 0, i+1)
+		for j := 0; j < i; j++ {
+			ands = append(ands, fmt.Sprintf("%s = \$%d", b.keys[j].column, firstArg+j))
+		}
+		ands = append(ands, fmt.Sprintf("%s %s \$%d", b.keys[i].column, b.operator(b.keys[i]), firstArg+i))
+		ors[i] = "(" + strings.Join(ands, " AND ") + ")"
+	}
+
+	return "(" + strings.Join(ors, " OR ") + ")", args
+}
+
+// OrderBy returns the ORDER BY list matching Where. Backward pages are
+// read in reverse order, which Paginate restores.
+func (b *Builder) OrderBy() string {
+	cols := make([]string, len(b.keys))
+	for i, k := range b.keys {
+		desc := k.desc != b.backward()
+		if desc {
+			cols[i] = k.column + " DESC"
+		} else {
+			cols[i] = k.column + " ASC"
+		}
+	}
+
+	return strings.Join(cols, ", ")
+}
+
+// Limit returns how many rows to fetch: one more than the page size, to
+// know whether another page follows.
+func (b *Builder) Limit() int {
+	return b.page.Limit + 1
+}
+
+func (b *Builder) backward() bool {
+	return b.page.Cursor != nil && b.page.Cursor.Direction == rest.Backward
+}
+
+func (b *Builder) operator(k key) string {
+	if k.desc != b.backward() {
+		return "<"
+	}
+
+	return ">"
+}
+
+func (b *Builder) cursor(values []string, d rest.Direction) *rest.Cursor {
+	return &rest.Cursor{Keys: b.names, Values: values, Direction: d}
+}
+
+// Paginate trims rows fetched with b to the page size, restores their
+// order on backward pages and returns the cursors of the next and previous
+// pages, nil at either end of the listing. values returns the sort key
+// values of a row, in the order of the builder keys.
+func Paginate[T any](b *Builder, rows []T, values func(T) []string) (page []T, next, prev *rest.Cursor) {
+	more := len(rows) > b.page.Limit
+	if more {
+		rows = rows[:b.page.Limit]
+	}
+
+	if b.backward() {
+		for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+			rows[i], rows[j] = rows[j], rows[i]
+		}
+	}
+
+	if len(rows) == 0 {
+		return rows, nil, nil
+	}
+
+	first, last := values(rows[0]), values(rows[len(rows)-1])
+
+	switch {
+	case b.backward():
+		next = b.cursor(last, rest.Forward)
+		if more {
+			prev = b.cursor(first, rest.Backward)
+		}
+	default:
+		if more {
+			next = b.cursor(last, rest.Forward)
+		}
+		if b.page.Cursor != nil {
+			prev = b.cursor(first, rest.Backward)
+		}
+	}
+
+	return rows, next, prev
+}
@@ -0,0 +1,135 @@
+package keyset
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/Gympass/gcore/v3/gtest"
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/rest"
+)
+
+func TestBuilder(t *testing.T) {
+	tt := []struct {
+		Name            string
+		Cursor          *rest.Cursor
+		ExpectedWhere   string
+		ExpectedOrderBy string
+	}{
+		{
+			Name:            "test first page",
+			ExpectedOrderBy: "created_at DESC, id ASC",
+		},
+		{
+			Name:            "test forward page",
+			Cursor:          &rest.Cursor{Keys: []string{"-created_at", "id"}, Values: []string{"t", "7"}, Direction: rest.Forward},
+			ExpectedWhere:   "((created_at < \$3) OR (created_at = \$3 AND id > \$4))",
+			ExpectedOrderBy: "created_at DESC, id ASC",
+		},
+		{
+			Name:            "test backward page",
+			Cursor:          &rest.Cursor{Keys: []string{"-created_at", "id"}, Values: []string{"t", "7"}, Direction: rest.Backward},
+			ExpectedWhere:   "((created_at > \$3) OR (created_at = \$3 AND id < \$4))",
+			ExpectedOrderBy: "created_at ASC, id DESC",
+		},
+	}
+
+	for _, testCase := range tt {
+		t.Run(testCase.Name, func(t *testing.T) {
+			b, err := New(rest.PageRequest{Cursor: testCase.Cursor, Limit: 10}, "-created_at", "id")
+			gtest.AssertNil(t, err)
+
+			where, args := b.Where(3)
+			if where != testCase.ExpectedWhere {
+				t.Fatalf("Expected where %s and got %s", testCase.ExpectedWhere, where)
+			}
+
+			if testCase.Cursor != nil && !reflect.DeepEqual(args, []interface{}{"t", "7"}) {
+				t.Fatalf("Unexpected args %v", args)
+			}
+
+			if orderBy := b.OrderBy(); orderBy != testCase.ExpectedOrderBy {
+				t.Fatalf("Expected order by %s and got %s", testCase.ExpectedOrderBy, orderBy)
+			}
+
+			if b.Limit() != 11 {
+				t.Fatalf("Expected limit 11 and got %d", b.Limit())
+			}
+		})
+	}
+}
+
+func TestNewRejectsForeignCursor(t *testing.T) {
+	cur := &rest.Cursor{Keys: []string{"name"}, Values: []string{"a"}, Direction: rest.Forward}
+
+	_, err := New(rest.PageRequest{Cursor: cur, Limit: 10}, "id")
+	if !errors.Is(err, rest.ErrInvalidCursor) {
+		t.Fatalf("Expected %v and got %v", rest.ErrInvalidCursor, err)
+	}
+}
+
+func TestPaginate(t *testing.T) {
+	values := func(i int) []string { return []string{strconv.Itoa(i)} }
+
+	tt := []struct {
+		Name         string
+		Cursor       *rest.Cursor
+		Rows         []int
+		ExpectedRows []int
+		ExpectedNext string
+		ExpectedPrev string
+	}{
+		{
+			Name:         "test first page with more rows",
+			Rows:         []int{1, 2, 3},
+			ExpectedRows: []int{1, 2},
+			ExpectedNext: "2",
+		},
+		{
+			Name:         "test last forward page",
+			Cursor:       &rest.Cursor{Keys: []string{"id"}, Values: []string{"2"}, Direction: rest.Forward},
+			Rows:         []int{3},
+			ExpectedRows: []int{3},
+			ExpectedPrev: "3",
+		},
+		{
+			Name:         "test backward page is reversed",
+			Cursor:       &rest.Cursor{Keys: []string{"id"}, Values: []string{"5"}, Direction: rest.Backward},
+			Rows:         []int{4, 3, 2},
+			ExpectedRows: []int{3, 4},
+			ExpectedNext: "4",
+			ExpectedPrev: "3",
+		},
+	}
+
+	for _, testCase := range tt {
+		t.Run(testCase.Name, func(t *testing.T) {
+			b, err := New(rest.PageRequest{Cursor: testCase.Cursor, Limit: 2}, "id")
+			gtest.AssertNil(t, err)
+
+			rows, next, prev := Paginate(b, testCase.Rows, values)
+			if !reflect.DeepEqual(rows, testCase.ExpectedRows) {
+				t.Fatalf("Expected rows %v and got %v", testCase.ExpectedRows, rows)
+			}
+
+			assertCursor(t, "next", next, testCase.ExpectedNext, rest.Forward)
+			assertCursor(t, "prev", prev, testCase.ExpectedPrev, rest.Backward)
+		})
+	}
+}
+
+func assertCursor(t *testing.T, name string, cur *rest.Cursor, value string, d rest.Direction) {
+	t.Helper()
+
+	if value == "" {
+		if cur != nil {
+			t.Fatalf("Expected no %s cursor and got %+v", name, cur)
+		}
+		return
+	}
+
+	if cur == nil || cur.Values[0] != value || cur.Direction != d {
+		t.Fatalf("Expected %s cursor at %s and got %+v", name, value, cur)
+	}
+}
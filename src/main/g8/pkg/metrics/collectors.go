@@ -0,0 +1,77 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/outbox"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+const defaultStatsTimeout = time.Second
+
+// RegisterDB adds the connection pool gauges of db, labeled with name.
+func (m *Metrics) RegisterDB(name string, db *sql.DB) error {
+	return m.Register(collectors.NewDBStatsCollector(db, name))
+}
+
+// OutboxStore is implemented by the outbox stores able to count their
+// events.
+type OutboxStore interface {
+	Stats(ctx context.Context) (outbox.Stats, error)
+}
+
+// RegisterOutbox adds the gauges of the events waiting in s, counted when
+// scraped within timeout. Failed counts leave the gauges out of the scrape.
+func (m *Metrics) RegisterOutbox(s OutboxStore, timeout time.Duration) error {
+	if timeout <= 0 {
+		timeout = defaultStatsTimeout
+	}
+
+	return m.Register(&outboxCollector{
+		store:   s,
+		timeout: timeout,
+		pending: prometheus.NewDesc(prometheus.BuildFQName(m.namespace, "outbox", "pending_events"),
+			"Events waiting to be relayed.", nil, nil),
+		dead: prometheus.NewDesc(prometheus.BuildFQName(m.namespace, "outbox", "dead_events"),
+			"Events parked after too many failed attempts.", nil, nil),
+		oldest: prometheus.NewDesc(prometheus.BuildFQName(m.namespace, "outbox", "oldest_pending_age_seconds"),
+			"Age of the oldest pending event, zero when none is pending.", nil, nil),
+	})
+}
+
+type outboxCollector struct {
+	store   OutboxStore
+	timeout time.Duration
+
+	pending *prometheus.Desc
+	dead    *prometheus.Desc
+	oldest  *prometheus.Desc
+}
+
+func (c *outboxCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.pending
+	ch <- c.dead
+	ch <- c.oldest
+}
+
+func (c *outboxCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	st, err := c.store.Stats(ctx)
+	if err != nil {
+		return
+	}
+
+	var age float64
+	if !st.OldestPending.IsZero() {
+		age = time.Since(st.OldestPending).Seconds()
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.pending, prometheus.GaugeValue, float64(st.Pending))
+	ch <- prometheus.MustNewConstMetric(c.dead, prometheus.GaugeValue, float64(st.Dead))
+	ch <- prometheus.MustNewConstMetric(c.oldest, prometheus.GaugeValue, age)
+}
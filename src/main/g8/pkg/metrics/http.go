@@ -0,0 +1,48 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/recorder"
+)
+
+// unmatchedRoute labels the requests served without a mux route.
+const unmatchedRoute = "unmatched"
+
+// Middleware records the request count, errors, latency and in-flight
+// requests. Requests are labeled by the template of their mux route, so
+// path parameters do not multiply the series; add it with Router.Use.
+func (m *Metrics) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := unmatchedRoute
+		if cr := mux.CurrentRoute(r); cr != nil {
+			if t, err := cr.GetPathTemplate(); err == nil {
+				route = t
+			}
+		}
+
+		m.inFlight.Inc()
+		defer m.inFlight.Dec()
+
+		sw := recorder.New(w)
+		start := time.Now()
+
+		next.ServeHTTP(sw, r)
+
+		code := strconv.Itoa(sw.Status())
+		m.requests.WithLabelValues(r.Method, route, code).Inc()
+		if sw.Status() >= http.StatusInternalServerError {
+			m.errors.WithLabelValues(r.Method, route, code).Inc()
+		}
+		m.latency.WithLabelValues(r.Method, route).Observe(time.Since(start).Seconds())
+	})
+}
+
+// Panicked counts a panic recovered from a handler of route, it implements
+// rest.PanicCounter.
+func (m *Metrics) Panicked(method, route string) {
+	m.panics.WithLabelValues(method, route).Inc()
+}
@@ -0,0 +1,214 @@
+// Package metrics exposes the service metrics in the Prometheus text
+// format: RED metrics of the HTTP routes, Go runtime and process stats,
+// dependency gauges and the counters and histograms services register.
+package metrics
+
+import (
+	"net/http"
+	"regexp"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// ErrInvalidName is returned for metric and label names Prometheus rejects.
+var ErrInvalidName = errors.New("invalid metric name")
+
+var nameRE = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*\$`)
+
+// Config used by metrics package
+type Config struct {
+	// Namespace prefixes every metric name, such as the service name with
+	// underscores.
+	Namespace string
+	// Buckets of the request latency histogram, in seconds. Defaults to
+	// prometheus.DefBuckets.
+	Buckets []float64
+}
+
+// Metrics is a registry of metrics served by Handler.
+type Metrics struct {
+	namespace string
+	registry  *prometheus.Registry
+
+	mu         sync.Mutex
+	collectors map[string]registered
+
+	requests *prometheus.CounterVec
+	errors   *prometheus.CounterVec
+	latency  *prometheus.HistogramVec
+	inFlight prometheus.Gauge
+	panics   *prometheus.CounterVec
+}
+
+// New creates a Metrics based on configuration properties, holding the
+// HTTP, Go runtime and process metrics.
+func New(c Config) *Metrics {
+	if len(c.Buckets) == 0 {
+		c.Buckets = prometheus.DefBuckets
+	}
+
+	m := &Metrics{
+		namespace:  c.Namespace,
+		registry:   prometheus.NewRegistry(),
+		collectors: make(map[string]registered),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{Namespace: c.Namespace}),
+	)
+
+	m.requests = m.MustCounter("http_requests_total", "HTTP requests served, by route template.", "method", "route", "code")
+	m.errors = m.MustCounter("http_request_errors_total", "HTTP requests answered with a 5xx status, by route template.", "method", "route", "code")
+	m.latency = m.MustHistogram("http_request_duration_seconds", "Latency of the HTTP requests, by route template.", c.Buckets, "method", "route")
+	m.inFlight = m.MustGauge("http_requests_in_flight", "HTTP requests being served.").WithLabelValues()
+	m.panics = m.MustCounter("http_panics_total", "Panics recovered from HTTP handlers, by route template.", "method", "route")
+
+	return m
+}
+
+// Counter returns the counter vector called name, registering it on the
+// first call. Later calls must use the same labels.
+func (m *Metrics) Counter(name, help string, labels ...string) (*prometheus.CounterVec, error) {
+	c, err := m.register(name, help, labels, func(opts prometheus.Opts) prometheus.Collector {
+		return prometheus.NewCounterVec(prometheus.CounterOpts(opts), labels)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	v, ok := c.(*prometheus.CounterVec)
+	if !ok {
+		return nil, errors.Errorf("metric %s is not a counter", name)
+	}
+
+	return v, nil
+}
+
+// Gauge returns the gauge vector called name, registering it on the first
+// call. Later calls must use the same labels.
+func (m *Metrics) Gauge(name, help string, labels ...string) (*prometheus.GaugeVec, error) {
+	c, err := m.register(name, help, labels, func(opts prometheus.Opts) prometheus.Collector {
+		return prometheus.NewGaugeVec(prometheus.GaugeOpts(opts), labels)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	v, ok := c.(*prometheus.GaugeVec)
+	if !ok {
+		return nil, errors.Errorf("metric %s is not a gauge", name)
+	}
+
+	return v, nil
+}
+
+// Histogram returns the histogram vector called name, registering it with
+// buckets on the first call. Later calls must use the same labels.
+func (m *Metrics) Histogram(name, help string, buckets []float64, labels ...string) (*prometheus.HistogramVec, error) {
+	c, err := m.register(name, help, labels, func(opts prometheus.Opts) prometheus.Collector {
+		return prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: opts.Namespace,
+			Name:      opts.Name,
+			Help:      opts.Help,
+			Buckets:   buckets,
+		}, labels)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	v, ok := c.(*prometheus.HistogramVec)
+	if !ok {
+		return nil, errors.Errorf("metric %s is not a histogram", name)
+	}
+
+	return v, nil
+}
+
+// MustCounter is like Counter but panics on error.
+func (m *Metrics) MustCounter(name, help string, labels ...string) *prometheus.CounterVec {
+	v, err := m.Counter(name, help, labels...)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// MustGauge is like Gauge but panics on error.
+func (m *Metrics) MustGauge(name, help string, labels ...string) *prometheus.GaugeVec {
+	v, err := m.Gauge(name, help, labels...)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// MustHistogram is like Histogram but panics on error.
+func (m *Metrics) MustHistogram(name, help string, buckets []float64, labels ...string) *prometheus.HistogramVec {
+	v, err := m.Histogram(name, help, buckets, labels...)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// Register adds a collector of its own, such as one reading a dependency
+// when scraped.
+func (m *Metrics) Register(c prometheus.Collector) error {
+	return errors.Wrap(m.registry.Register(c), "registering collector")
+}
+
+// Handler serves the metrics in the Prometheus text format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+type registered struct {
+	collector prometheus.Collector
+	labels    []string
+}
+
+// register returns the collector called name, created by build on the
+// first call.
+func (m *Metrics) register(name, help string, labels []string, build func(prometheus.Opts) prometheus.Collector) (prometheus.Collector, error) {
+	for _, n := range append([]string{name}, labels...) {
+		if !nameRE.MatchString(n) {
+			return nil, errors.Wrapf(ErrInvalidName, "%q", n)
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if r, ok := m.collectors[name]; ok {
+		if !equal(r.labels, labels) {
+			return nil, errors.Errorf("metric %s is registered with labels %v", name, r.labels)
+		}
+		return r.collector, nil
+	}
+
+	c := build(prometheus.Opts{Namespace: m.namespace, Name: name, Help: help})
+	if err := m.registry.Register(c); err != nil {
+		return nil, errors.Wrapf(err, "registering %s", name)
+	}
+	m.collectors[name] = registered{collector: c, labels: labels}
+
+	return c, nil
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,188 @@
+package metrics_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/metrics"
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/outbox"
+	"github.com/pkg/errors"
+)
+
+func scrape(t *testing.T, m *metrics.Metrics) string {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("scrape status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	b, err := io.ReadAll(rec.Body)
+	if err != nil {
+		t.Fatalf("reading scrape: %v", err)
+	}
+
+	return string(b)
+}
+
+func TestMiddleware(t *testing.T) {
+	m := metrics.New(metrics.Config{Namespace: "demo"})
+
+	router := mux.NewRouter()
+	router.Use(m.Middleware)
+	router.Path("/demos/{id}").Methods(http.MethodGet).HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if mux.Vars(r)["id"] == "broken" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	})
+
+	for _, path := range []string{"/demos/1", "/demos/2", "/demos/broken"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	m.Panicked(http.MethodGet, "/demos/{id}")
+
+	out := scrape(t, m)
+
+	expected := []string{
+		`demo_http_requests_total{code="200",method="GET",route="/demos/{id}"} 2`,
+		`demo_http_requests_total{code="500",method="GET",route="/demos/{id}"} 1`,
+		`demo_http_request_errors_total{code="500",method="GET",route="/demos/{id}"} 1`,
+		`demo_http_request_duration_seconds_count{method="GET",route="/demos/{id}"} 3`,
+		`demo_http_requests_in_flight 0`,
+		`demo_http_panics_total{method="GET",route="/demos/{id}"} 1`,
+		`go_goroutines`,
+	}
+	for _, e := range expected {
+		if !strings.Contains(out, e) {
+			t.Fatalf("scrape misses %s:\n%s", e, out)
+		}
+	}
+	if strings.Contains(out, "/demos/1") {
+		t.Fatalf("scrape has series per path:\n%s", out)
+	}
+}
+
+func TestRegistration(t *testing.T) {
+	m := metrics.New(metrics.Config{Namespace: "demo"})
+
+	tt := []struct {
+		Name          string
+		Register      func() error
+		ExpectedError error
+	}{
+		{
+			Name: "test counter registered",
+			Register: func() error {
+				c, err := m.Counter("jobs_total", "Jobs run.", "job")
+				if err == nil {
+					c.WithLabelValues("purge").Add(2)
+				}
+				return err
+			},
+		},
+		{
+			Name: "test counter returned again",
+			Register: func() error {
+				c, err := m.Counter("jobs_total", "Jobs run.", "job")
+				if err == nil {
+					c.WithLabelValues("purge").Inc()
+				}
+				return err
+			},
+		},
+		{
+			Name: "test histogram registered",
+			Register: func() error {
+				h, err := m.Histogram("job_duration_seconds", "Job latency.", []float64{1, 10}, "job")
+				if err == nil {
+					h.WithLabelValues("purge").Observe(2)
+				}
+				return err
+			},
+		},
+		{
+			Name: "test counter with other labels",
+			Register: func() error {
+				_, err := m.Counter("jobs_total", "Jobs run.", "queue")
+				return err
+			},
+			ExpectedError: errors.New("metric jobs_total is registered with labels [job]"),
+		},
+		{
+			Name: "test counter registered as a gauge",
+			Register: func() error {
+				_, err := m.Gauge("jobs_total", "Jobs run.", "job")
+				return err
+			},
+			ExpectedError: errors.New("metric jobs_total is not a gauge"),
+		},
+		{
+			Name: "test invalid name",
+			Register: func() error {
+				_, err := m.Counter("jobs-total", "Jobs run.")
+				return err
+			},
+			ExpectedError: metrics.ErrInvalidName,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			err := tc.Register()
+			if tc.ExpectedError == nil && err != nil {
+				t.Fatalf("register error = %v", err)
+			}
+			if tc.ExpectedError != nil && (err == nil || !strings.Contains(err.Error(), tc.ExpectedError.Error())) {
+				t.Fatalf("register error = %v, want %v", err, tc.ExpectedError)
+			}
+		})
+	}
+
+	out := scrape(t, m)
+	for _, e := range []string{
+		`demo_jobs_total{job="purge"} 3`,
+		`demo_job_duration_seconds_bucket{job="purge",le="10"} 1`,
+	} {
+		if !strings.Contains(out, e) {
+			t.Fatalf("scrape misses %s:\n%s", e, out)
+		}
+	}
+}
+
+func TestRegisterOutbox(t *testing.T) {
+	ctx := context.Background()
+	m := metrics.New(metrics.Config{Namespace: "demo"})
+
+	store := outbox.NewMemoryStore()
+	if err := m.RegisterOutbox(store, time.Second); err != nil {
+		t.Fatalf("RegisterOutbox() error = %v", err)
+	}
+
+	err := store.Enqueue(ctx,
+		outbox.Event{Topic: "demo-events", Key: "a", CreatedAt: time.Now().Add(-time.Minute)},
+		outbox.Event{Topic: "demo-events", Key: "b"},
+	)
+	if err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+
+	out := scrape(t, m)
+	for _, e := range []string{
+		"demo_outbox_pending_events 2",
+		"demo_outbox_dead_events 0",
+		"demo_outbox_oldest_pending_age_seconds 6",
+	} {
+		if !strings.Contains(out, e) {
+			t.Fatalf("scrape misses %s:\n%s", e, out)
+		}
+	}
+}
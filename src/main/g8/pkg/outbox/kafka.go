@@ -0,0 +1,78 @@
+package outbox
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/tracing"
+	"github.com/pkg/errors"
+	"github.com/segmentio/kafka-go"
+)
+
+// KafkaPublisher sends events to the topic they name, partitioned by key.
+type KafkaPublisher struct {
+	w      *kafka.Writer
+	client *kafka.Client
+}
+
+// NewKafkaPublisher creates a KafkaPublisher writing to brokers. Each event
+// is acknowledged by every in-sync replica before the next one is sent.
+func NewKafkaPublisher(brokers []string) *KafkaPublisher {
+	return &KafkaPublisher{
+		w: &kafka.Writer{
+			Addr:         kafka.TCP(brokers...),
+			Balancer:     &kafka.Hash{},
+			RequiredAcks: kafka.RequireAll,
+			BatchTimeout: 10 * time.Millisecond,
+		},
+		client: &kafka.Client{Addr: kafka.TCP(brokers...)},
+	}
+}
+
+// Name implements health.Checker.
+func (p *KafkaPublisher) Name() string {
+	return "kafka-publisher"
+}
+
+// Check implements health.Checker by asking the brokers for the cluster
+// metadata.
+func (p *KafkaPublisher) Check(ctx context.Context) error {
+	_, err := p.client.Metadata(ctx, &kafka.MetadataRequest{})
+	return errors.Wrap(err, "reading kafka metadata")
+}
+
+// Publish implements Publisher. The span of the publication continues the
+// trace held by the event headers.
+func (p *KafkaPublisher) Publish(ctx context.Context, e Event) (err error) {
+	values := map[string]string{
+		HeaderEventType: e.Type,
+		HeaderEventID:   strconv.FormatInt(e.ID, 10),
+	}
+	for k, v := range e.Headers {
+		values[k] = v
+	}
+
+	ctx, span := tracing.StartPublish(ctx, e.Topic, values)
+	defer func() { tracing.End(span, err) }()
+
+	headers := make([]kafka.Header, 0, len(values))
+	for k, v := range values {
+		headers = append(headers, kafka.Header{Key: k, Value: []byte(v)})
+	}
+
+	err = p.w.WriteMessages(ctx, kafka.Message{
+		Topic:   e.Topic,
+		Key:     []byte(e.Key),
+		Value:   e.Payload,
+		Headers: headers,
+		Time:    e.CreatedAt,
+	})
+
+	return errors.Wrapf(err, "writing to %s", e.Topic)
+}
+
+// Close flushes and closes the underlying writer.
+func (p *KafkaPublisher) Close() error {
+	return p.w.Close()
+}
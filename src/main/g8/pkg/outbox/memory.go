@@ -0,0 +1,188 @@
+package outbox
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore is an outbox kept in memory, meant for tests.
+type MemoryStore struct {
+	claim sync.Mutex
+
+	mu      sync.Mutex
+	lastID  int64
+	pending []Event
+	dead    []Event
+}
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{}
+}
+
+// Enqueue adds events to the outbox.
+func (s *MemoryStore) Enqueue(ctx context.Context, events ...Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, e := range events {
+		s.lastID++
+		e.ID = s.lastID
+		e.Headers = withContext(ctx, e.Headers)
+		if e.CreatedAt.IsZero() {
+			e.CreatedAt = time.Now()
+		}
+		if e.NextAttemptAt.IsZero() {
+			e.NextAttemptAt = e.CreatedAt
+		}
+		s.pending = append(s.pending, e)
+	}
+
+	return nil
+}
+
+// Pending returns the events waiting in the outbox, oldest first.
+func (s *MemoryStore) Pending() []Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Event(nil), s.pending...)
+}
+
+// Dead returns the dead-lettered events.
+func (s *MemoryStore) Dead() []Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Event(nil), s.dead...)
+}
+
+// Stats counts the pending and dead events.
+func (s *MemoryStore) Stats(context.Context) (Stats, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st := Stats{Pending: len(s.pending), Dead: len(s.dead)}
+	if len(s.pending) > 0 {
+		st.OldestPending = s.pending[0].CreatedAt
+	}
+
+	return st, nil
+}
+
+// Claim implements Store.
+func (s *MemoryStore) Claim(_ context.Context, limit int) (Batch, error) {
+	if !s.claim.TryLock() {
+		return &memoryBatch{}, nil
+	}
+
+	events := s.Pending()
+	if len(events) > limit {
+		events = events[:limit]
+	}
+
+	return &memoryBatch{store: s, events: events}, nil
+}
+
+type memoryBatch struct {
+	store  *MemoryStore
+	events []Event
+}
+
+func (b *memoryBatch) Events() []Event {
+	return b.events
+}
+
+func (b *memoryBatch) Sent(_ context.Context, e Event) error {
+	b.store.update(e.ID, func(Event) (Event, bool) { return Event{}, false })
+	return nil
+}
+
+func (b *memoryBatch) Retry(_ context.Context, e Event, at time.Time, cause error) error {
+	b.store.update(e.ID, func(cur Event) (Event, bool) {
+		cur.Attempts++
+		cur.NextAttemptAt, cur.LastError = at, cause.Error()
+		return cur, true
+	})
+	return nil
+}
+
+func (b *memoryBatch) Dead(_ context.Context, e Event, cause error) error {
+	b.store.update(e.ID, func(cur Event) (Event, bool) {
+		cur.Attempts++
+		cur.LastError = cause.Error()
+		b.store.dead = append(b.store.dead, cur)
+		return Event{}, false
+	})
+	return nil
+}
+
+func (b *memoryBatch) Close(context.Context) error {
+	if b.store != nil {
+		b.store.claim.Unlock()
+	}
+	return nil
+}
+
+// update replaces the pending event id by the result of fn, or removes it
+// when fn returns false.
+func (s *MemoryStore) update(id int64, fn func(Event) (Event, bool)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, e := range s.pending {
+		if e.ID != id {
+			continue
+		}
+		if e, keep := fn(e); keep {
+			s.pending[i] = e
+		} else {
+			s.pending = append(s.pending[:i], s.pending[i+1:]...)
+		}
+		return
+	}
+}
+
+// MemoryPublisher records the events it publishes, meant for tests.
+type MemoryPublisher struct {
+	mu     sync.Mutex
+	events []Event
+	fail   func(Event) error
+}
+
+// NewMemoryPublisher creates an empty MemoryPublisher.
+func NewMemoryPublisher() *MemoryPublisher {
+	return &MemoryPublisher{}
+}
+
+// FailWith makes Publish return the error of fn, when not nil.
+func (p *MemoryPublisher) FailWith(fn func(Event) error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.fail = fn
+}
+
+// Publish implements Publisher.
+func (p *MemoryPublisher) Publish(_ context.Context, e Event) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.fail != nil {
+		if err := p.fail(e); err != nil {
+			return err
+		}
+	}
+	p.events = append(p.events, e)
+
+	return nil
+}
+
+// Events returns the published events, in order.
+func (p *MemoryPublisher) Events() []Event {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([]Event(nil), p.events...)
+}
@@ -0,0 +1,111 @@
+// Package outbox publishes domain events reliably: repositories write them
+// in the transaction of the entity change and a Relay sends them in order
+// to a Publisher, retrying with backoff and dead-lettering the ones that
+// keep failing.
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/requestid"
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/tracing"
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Headers added to the events sent by the Relay.
+const (
+	HeaderEventType     = "event-type"
+	HeaderEventID       = "event-id"
+	HeaderOriginalTopic = "original-topic"
+	HeaderError         = "error"
+)
+
+// ErrPublish is returned by MemoryPublisher when told to fail.
+var ErrPublish = errors.New("publishing failed")
+
+// Event is a message waiting in the outbox.
+type Event struct {
+	// ID orders the events, it is assigned by the store.
+	ID      int64
+	Topic   string
+	Key     string
+	Type    string
+	Payload []byte
+	Headers map[string]string
+
+	CreatedAt time.Time
+	// Attempts counts the failed publications.
+	Attempts int
+	// NextAttemptAt delays the event, and those after it, after a failure.
+	NextAttemptAt time.Time
+	LastError     string
+}
+
+// NewEvent returns an event of type eventType for topic, carrying payload
+// encoded as JSON. Events with the same key are consumed in order.
+func NewEvent(topic, key, eventType string, payload interface{}) (Event, error) {
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return Event{}, errors.Wrapf(err, "encoding %s event", eventType)
+	}
+
+	return Event{Topic: topic, Key: key, Type: eventType, Payload: b}, nil
+}
+
+// withContext returns headers along with the trace context and the request
+// id of ctx, so the publication of an event continues the trace of the
+// change writing it and keeps its request id.
+func withContext(ctx context.Context, headers map[string]string) map[string]string {
+	id := requestid.FromContext(ctx)
+	if !trace.SpanContextFromContext(ctx).IsValid() && id == "" {
+		return headers
+	}
+
+	traced := make(map[string]string, len(headers)+2)
+	for k, v := range headers {
+		traced[k] = v
+	}
+	tracing.Inject(ctx, traced)
+	requestid.Inject(ctx, traced)
+
+	return traced
+}
+
+// Publisher sends events to a message broker.
+type Publisher interface {
+	Publish(ctx context.Context, e Event) error
+}
+
+// Store is the outbox read by the Relay.
+type Store interface {
+	// Claim returns a batch of up to limit pending events, oldest first.
+	// Only one batch is claimed at a time across every relay, the others
+	// get an empty batch, until it is closed or ctx is done.
+	Claim(ctx context.Context, limit int) (Batch, error)
+}
+
+// Stats describes the events waiting in an outbox.
+type Stats struct {
+	Pending int
+	Dead    int
+	// OldestPending is the creation time of the oldest pending event, zero
+	// when none is pending.
+	OldestPending time.Time
+}
+
+// Batch is a claim on pending events. The outcomes recorded on it are
+// saved by Close.
+type Batch interface {
+	Events() []Event
+	// Sent removes e from the outbox.
+	Sent(ctx context.Context, e Event) error
+	// Retry records a failed attempt and delays e until at.
+	Retry(ctx context.Context, e Event, at time.Time, cause error) error
+	// Dead parks e, which will not be sent again.
+	Dead(ctx context.Context, e Event, cause error) error
+	// Close saves the outcomes and releases the claim.
+	Close(ctx context.Context) error
+}
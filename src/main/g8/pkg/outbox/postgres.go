@@ -0,0 +1,222 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+)
+
+// defaultLease bounds the claims made with a context without deadline.
+const defaultLease = time.Minute
+
+const (
+	insertEvent = `INSERT INTO outbox (topic, key, type, payload, headers) VALUES (\$1, \$2, \$3, \$4, \$5)`
+	// claimLease takes the lease of the outbox_lease table, unless held by
+	// another relay, for a number of milliseconds.
+	claimLease = `UPDATE outbox_lease SET claim_id = \$1, claimed_until = now() + \$2 * interval '1 millisecond'
+WHERE id = 1 AND claimed_until <= now()`
+	releaseLease = `UPDATE outbox_lease SET claimed_until = '-infinity' WHERE id = 1 AND claim_id = \$1`
+	selectBatch  = `SELECT id, topic, key, type, payload, headers, created_at, attempts, next_attempt_at, COALESCE(last_error, '')
+FROM outbox WHERE status = 'pending' ORDER BY id LIMIT \$1`
+	deleteEvent = `DELETE FROM outbox WHERE id = \$1`
+	retryEvent  = `UPDATE outbox SET attempts = attempts + 1, next_attempt_at = \$2, last_error = \$3 WHERE id = \$1`
+	deadEvent   = `UPDATE outbox SET attempts = attempts + 1, status = 'dead', last_error = \$2 WHERE id = \$1`
+	selectStats = `SELECT COUNT(*) FILTER (WHERE status = 'pending'), COUNT(*) FILTER (WHERE status = 'dead'),
+MIN(created_at) FILTER (WHERE status = 'pending') FROM outbox`
+)
+
+// Execer is implemented by *sql.DB and *sql.Tx.
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// Enqueue writes events into the outbox table through tx, which should
+// be the transaction changing the entity the events describe.
+func Enqueue(ctx context.Context, tx Execer, events ...Event) error {
+	for _, e := range events {
+		var headers []byte
+		if h := withContext(ctx, e.Headers); len(h) > 0 {
+			var err error
+			if headers, err = json.Marshal(h); err != nil {
+				return errors.Wrap(err, "encoding outbox headers")
+			}
+		}
+
+		if _, err := tx.ExecContext(ctx, insertEvent, e.Topic, e.Key, e.Type, e.Payload, headers); err != nil {
+			return errors.Wrapf(err, "inserting %s event", e.Type)
+		}
+	}
+
+	return nil
+}
+
+// PostgresStore is the outbox table.
+type PostgresStore struct {
+	db *sql.DB
+}
+
+// NewPostgresStore creates a PostgresStore using db.
+func NewPostgresStore(db *sql.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// Claim implements Store. The batch is leased in the outbox_lease table
+// until the deadline of ctx, or for a minute, so that no transaction stays
+// open while the events are published. Its outcomes are saved by Close in
+// a second transaction, which releases the lease; events sent before a
+// crash are sent again by the next relay once the lease expired.
+func (s *PostgresStore) Claim(ctx context.Context, limit int) (Batch, error) {
+	id, err := uuid.NewV4()
+	if err != nil {
+		return nil, errors.Wrap(err, "generating claim id")
+	}
+
+	lease := defaultLease
+	if d, ok := ctx.Deadline(); ok {
+		lease = time.Until(d)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "beginning outbox claim")
+	}
+
+	b := &postgresBatch{db: s.db, claimID: id.String()}
+	if b.leased, b.events, err = claim(ctx, tx, b.claimID, lease, limit); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, errors.Wrap(err, "committing outbox claim")
+	}
+
+	return b, nil
+}
+
+// Stats counts the pending and dead events.
+func (s *PostgresStore) Stats(ctx context.Context) (Stats, error) {
+	var (
+		st     Stats
+		oldest sql.NullTime
+	)
+	if err := s.db.QueryRowContext(ctx, selectStats).Scan(&st.Pending, &st.Dead, &oldest); err != nil {
+		return Stats{}, errors.Wrap(err, "counting outbox events")
+	}
+	st.OldestPending = oldest.Time
+
+	return st, nil
+}
+
+// claim takes the lease and selects the batch, returning false when the
+// lease is held by another relay.
+func claim(ctx context.Context, tx *sql.Tx, claimID string, lease time.Duration, limit int) (bool, []Event, error) {
+	res, err := tx.ExecContext(ctx, claimLease, claimID, lease.Milliseconds())
+	if err != nil {
+		return false, nil, errors.Wrap(err, "leasing outbox")
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, nil, errors.Wrap(err, "leasing outbox")
+	}
+	if n == 0 {
+		return false, nil, nil
+	}
+
+	rows, err := tx.QueryContext(ctx, selectBatch, limit)
+	if err != nil {
+		return false, nil, errors.Wrap(err, "selecting outbox events")
+	}
+	defer rows.Close()
+
+	var events []Event
+	for rows.Next() {
+		var (
+			e       Event
+			headers []byte
+		)
+		err := rows.Scan(&e.ID, &e.Topic, &e.Key, &e.Type, &e.Payload, &headers,
+			&e.CreatedAt, &e.Attempts, &e.NextAttemptAt, &e.LastError)
+		if err != nil {
+			return false, nil, errors.Wrap(err, "scanning outbox event")
+		}
+		if len(headers) > 0 {
+			if err := json.Unmarshal(headers, &e.Headers); err != nil {
+				return false, nil, errors.Wrap(err, "decoding outbox headers")
+			}
+		}
+		events = append(events, e)
+	}
+
+	return true, events, errors.Wrap(rows.Err(), "selecting outbox events")
+}
+
+// postgresBatch records the outcomes of its events until Close.
+type postgresBatch struct {
+	db      *sql.DB
+	claimID string
+	leased  bool
+	events  []Event
+	updates []update
+}
+
+// update is a statement saving the outcome of an event.
+type update struct {
+	query string
+	args  []interface{}
+}
+
+func (b *postgresBatch) Events() []Event {
+	return b.events
+}
+
+func (b *postgresBatch) Sent(_ context.Context, e Event) error {
+	b.updates = append(b.updates, update{deleteEvent, []interface{}{e.ID}})
+	return nil
+}
+
+func (b *postgresBatch) Retry(_ context.Context, e Event, at time.Time, cause error) error {
+	b.updates = append(b.updates, update{retryEvent, []interface{}{e.ID, at, cause.Error()}})
+	return nil
+}
+
+func (b *postgresBatch) Dead(_ context.Context, e Event, cause error) error {
+	b.updates = append(b.updates, update{deadEvent, []interface{}{e.ID, cause.Error()}})
+	return nil
+}
+
+func (b *postgresBatch) Close(ctx context.Context) error {
+	if !b.leased {
+		return nil
+	}
+	if len(b.updates) == 0 {
+		return b.release(ctx, b.db)
+	}
+
+	tx, err := b.db.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, "beginning outbox update")
+	}
+
+	for _, u := range b.updates {
+		if _, err := tx.ExecContext(ctx, u.query, u.args...); err != nil {
+			_ = tx.Rollback()
+			return errors.Wrap(err, "updating outbox event")
+		}
+	}
+	if err := b.release(ctx, tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	return errors.Wrap(tx.Commit(), "committing outbox update")
+}
+
+// release gives the lease up, unless it expired and was claimed again.
+func (b *postgresBatch) release(ctx context.Context, ex Execer) error {
+	_, err := ex.ExecContext(ctx, releaseLease, b.claimID)
+	return errors.Wrap(err, "releasing outbox lease")
+}
@@ -0,0 +1,202 @@
+package outbox
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/Gympass/gcore/v3/gcontext"
+	"github.com/Gympass/gcore/v3/glog"
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultInterval    = time.Second
+	defaultBatchSize   = 100
+	defaultMaxAttempts = 10
+	defaultMinBackoff  = time.Second
+	defaultMaxBackoff  = 5 * time.Minute
+	defaultBatchTime   = 30 * time.Second
+)
+
+// Config used by the Relay
+type Config struct {
+	Store     Store
+	Publisher Publisher
+	Logger    glog.Logger
+	// DeadLetterTopic receives the events still failing after MaxAttempts,
+	// which are only parked in the store when it is empty.
+	DeadLetterTopic string
+	// Interval between two polls of an idle outbox, 1s when zero.
+	Interval time.Duration
+	// BatchSize is the number of events claimed at once, 100 when zero.
+	BatchSize int
+	// BatchTimeout bounds the publication of a batch, for which stores
+	// lease it, 30s when zero.
+	BatchTimeout time.Duration
+	// MaxAttempts before an event is dead-lettered, 10 when zero.
+	MaxAttempts int
+	// MinBackoff and MaxBackoff bound the delay between two attempts,
+	// doubled after each failure. They default to 1s and 5m.
+	MinBackoff, MaxBackoff time.Duration
+	// Now returns the current time, time.Now when nil.
+	Now func() time.Time
+}
+
+// Relay moves the events of the outbox to the publisher.
+type Relay struct {
+	cfg Config
+}
+
+// NewRelay creates a Relay based on configuration properties
+func NewRelay(c Config) *Relay {
+	if c.Interval <= 0 {
+		c.Interval = defaultInterval
+	}
+	if c.BatchSize <= 0 {
+		c.BatchSize = defaultBatchSize
+	}
+	if c.BatchTimeout <= 0 {
+		c.BatchTimeout = defaultBatchTime
+	}
+	if c.MaxAttempts <= 0 {
+		c.MaxAttempts = defaultMaxAttempts
+	}
+	if c.MinBackoff <= 0 {
+		c.MinBackoff = defaultMinBackoff
+	}
+	if c.MaxBackoff < c.MinBackoff {
+		c.MaxBackoff = defaultMaxBackoff
+	}
+	if c.Now == nil {
+		c.Now = time.Now
+	}
+
+	return &Relay{cfg: c}
+}
+
+// Run relays the events until ctx is done. Full batches are followed by
+// another one at once, otherwise the outbox is polled every interval.
+func (r *Relay) Run(ctx context.Context) {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		n, err := r.Flush(ctx)
+		if err != nil && ctx.Err() == nil {
+			lctx := gcontext.NewContext(context.Background())
+			gcontext.AddError(lctx, err)
+			r.cfg.Logger.Error(lctx, "Could not relay outbox events.")
+		}
+
+		if n == r.cfg.BatchSize && err == nil {
+			timer.Reset(0)
+		} else {
+			timer.Reset(r.cfg.Interval)
+		}
+	}
+}
+
+// Flush relays one batch and returns how many events left the outbox,
+// sent or dead-lettered. Events are sent in order: a failure delays the
+// rest of the batch until the failed event is retried.
+func (r *Relay) Flush(ctx context.Context) (int, error) {
+	bctx, cancel := context.WithTimeout(ctx, r.cfg.BatchTimeout)
+	defer cancel()
+
+	b, err := r.cfg.Store.Claim(bctx, r.cfg.BatchSize)
+	if err != nil {
+		return 0, errors.Wrap(err, "claiming outbox events")
+	}
+
+	// The outcomes are saved past the timeout, which stops the publication.
+	n, err := r.relay(bctx, b)
+	if cerr := b.Close(ctx); cerr != nil && err == nil {
+		err = errors.Wrap(cerr, "saving outbox events")
+	}
+
+	return n, err
+}
+
+func (r *Relay) relay(ctx context.Context, b Batch) (int, error) {
+	var n int
+	for _, e := range b.Events() {
+		now := r.cfg.Now()
+		if e.NextAttemptAt.After(now) {
+			return n, nil
+		}
+
+		perr := r.cfg.Publisher.Publish(ctx, e)
+		if perr == nil {
+			n++
+			if err := b.Sent(ctx, e); err != nil {
+				return n, err
+			}
+			continue
+		}
+
+		if e.Attempts+1 < r.cfg.MaxAttempts {
+			r.log(e, perr, "Outbox event not published, retrying.")
+			return n, b.Retry(ctx, e, now.Add(r.backoff(e.Attempts+1)), perr)
+		}
+
+		if r.cfg.DeadLetterTopic != "" {
+			if err := r.cfg.Publisher.Publish(ctx, deadLetter(e, r.cfg.DeadLetterTopic, perr)); err != nil {
+				r.log(e, err, "Outbox event not dead-lettered, retrying.")
+				return n, b.Retry(ctx, e, now.Add(r.cfg.MaxBackoff), perr)
+			}
+		}
+
+		r.log(e, perr, "Outbox event dead-lettered.")
+		n++
+		if err := b.Dead(ctx, e, perr); err != nil {
+			return n, err
+		}
+	}
+
+	return n, nil
+}
+
+// backoff returns the delay after the given number of failed attempts.
+func (r *Relay) backoff(attempts int) time.Duration {
+	d := r.cfg.MinBackoff
+	for i := 1; i < attempts && d < r.cfg.MaxBackoff; i++ {
+		d *= 2
+	}
+	if d > r.cfg.MaxBackoff {
+		d = r.cfg.MaxBackoff
+	}
+
+	return d
+}
+
+func (r *Relay) log(e Event, err error, msg string) {
+	lctx := gcontext.NewContext(context.Background())
+	gcontext.AddError(lctx, err)
+	gcontext.AddString(lctx, "outbox.event_id", strconv.FormatInt(e.ID, 10))
+	gcontext.AddString(lctx, "outbox.topic", e.Topic)
+	gcontext.AddString(lctx, "outbox.attempts", strconv.Itoa(e.Attempts+1))
+
+	r.cfg.Logger.Warn(lctx, msg)
+}
+
+// deadLetter returns e redirected to topic, with the original topic and
+// the cause of the failure in its headers.
+func deadLetter(e Event, topic string, cause error) Event {
+	headers := make(map[string]string, len(e.Headers)+2)
+	for k, v := range e.Headers {
+		headers[k] = v
+	}
+	headers[HeaderOriginalTopic] = e.Topic
+	headers[HeaderError] = cause.Error()
+
+	e.Topic, e.Headers = topic, headers
+
+	return e
+}
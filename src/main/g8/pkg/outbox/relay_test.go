@@ -0,0 +1,169 @@
+package outbox_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Gympass/gcore/v3/glog"
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/outbox"
+)
+
+func TestRelay(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	tt := []struct {
+		Name            string
+		Fail            map[string]int
+		DeadLetterTopic string
+		Flushes         int
+		ExpectedKeys    []string
+		ExpectedPending int
+		ExpectedDead    int
+	}{
+		{
+			Name:         "test events sent in order",
+			Flushes:      1,
+			ExpectedKeys: []string{"a", "b", "c"},
+		},
+		{
+			Name:            "test failure delays later events",
+			Fail:            map[string]int{"b": 1},
+			Flushes:         1,
+			ExpectedKeys:    []string{"a"},
+			ExpectedPending: 2,
+		},
+		{
+			Name:         "test retried after backoff",
+			Fail:         map[string]int{"b": 2},
+			Flushes:      3,
+			ExpectedKeys: []string{"a", "b", "c"},
+		},
+		{
+			Name:         "test dead-lettered after max attempts",
+			Fail:         map[string]int{"b": 10},
+			Flushes:      3,
+			ExpectedKeys: []string{"a", "c"},
+			ExpectedDead: 1,
+		},
+		{
+			Name:            "test dead-letter topic",
+			Fail:            map[string]int{"b": 3},
+			DeadLetterTopic: "demo-events-dlq",
+			Flushes:         3,
+			ExpectedKeys:    []string{"a", "b", "c"},
+			ExpectedDead:    1,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			clock := now
+			store, pub := outbox.NewMemoryStore(), outbox.NewMemoryPublisher()
+			for _, key := range []string{"a", "b", "c"} {
+				e, err := outbox.NewEvent("demo-events", key, "demo.created", map[string]string{"id": key})
+				if err != nil {
+					t.Fatalf("NewEvent() error = %v", err)
+				}
+				e.CreatedAt = now
+				if err := store.Enqueue(ctx, e); err != nil {
+					t.Fatalf("Enqueue() error = %v", err)
+				}
+			}
+
+			pub.FailWith(func(e outbox.Event) error {
+				if e.Topic == tc.DeadLetterTopic || tc.Fail[e.Key] == 0 {
+					return nil
+				}
+				tc.Fail[e.Key]--
+				return outbox.ErrPublish
+			})
+
+			relay := outbox.NewRelay(outbox.Config{
+				Store:           store,
+				Publisher:       pub,
+				Logger:          glog.Noop(),
+				DeadLetterTopic: tc.DeadLetterTopic,
+				MaxAttempts:     3,
+				MinBackoff:      time.Second,
+				MaxBackoff:      time.Minute,
+				Now:             func() time.Time { return clock },
+			})
+
+			for i := 0; i < tc.Flushes; i++ {
+				if _, err := relay.Flush(ctx); err != nil {
+					t.Fatalf("Flush() error = %v", err)
+				}
+				clock = clock.Add(time.Minute)
+			}
+
+			var keys []string
+			for _, e := range pub.Events() {
+				if e.Topic == "demo-events" {
+					keys = append(keys, e.Key)
+				} else if e.Topic == tc.DeadLetterTopic && e.Headers[outbox.HeaderOriginalTopic] == "demo-events" {
+					keys = append(keys, e.Key)
+				}
+			}
+			if len(keys) != len(tc.ExpectedKeys) {
+				t.Fatalf("published %v, want %v", keys, tc.ExpectedKeys)
+			}
+			for i := range keys {
+				if keys[i] != tc.ExpectedKeys[i] {
+					t.Fatalf("published %v, want %v", keys, tc.ExpectedKeys)
+				}
+			}
+
+			if got := len(store.Pending()); got != tc.ExpectedPending {
+				t.Fatalf("pending = %d, want %d", got, tc.ExpectedPending)
+			}
+			if got := len(store.Dead()); got != tc.ExpectedDead {
+				t.Fatalf("dead = %d, want %d", got, tc.ExpectedDead)
+			}
+		})
+	}
+}
+
+func TestRelayBackoff(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	store, pub := outbox.NewMemoryStore(), outbox.NewMemoryPublisher()
+	pub.FailWith(func(outbox.Event) error { return outbox.ErrPublish })
+	if err := store.Enqueue(ctx, outbox.Event{Topic: "demo-events", Key: "a", CreatedAt: now}); err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+
+	clock := now
+	relay := outbox.NewRelay(outbox.Config{
+		Store:       store,
+		Publisher:   pub,
+		Logger:      glog.Noop(),
+		MaxAttempts: 10,
+		MinBackoff:  time.Second,
+		MaxBackoff:  5 * time.Second,
+		Now:         func() time.Time { return clock },
+	})
+
+	for _, expected := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second} {
+		if _, err := relay.Flush(ctx); err != nil {
+			t.Fatalf("Flush() error = %v", err)
+		}
+
+		e := store.Pending()[0]
+		if got := e.NextAttemptAt.Sub(clock); got != expected {
+			t.Fatalf("attempt %d delayed by %v, want %v", e.Attempts, got, expected)
+		}
+
+		// Too early: nothing is attempted.
+		if _, err := relay.Flush(ctx); err != nil {
+			t.Fatalf("Flush() error = %v", err)
+		}
+		if store.Pending()[0].Attempts != e.Attempts {
+			t.Fatalf("event attempted before its backoff elapsed")
+		}
+
+		clock = e.NextAttemptAt
+	}
+}
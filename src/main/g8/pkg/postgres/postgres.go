@@ -0,0 +1,175 @@
+// Package postgres opens pooled PostgreSQL connections and reports their
+// health and pool statistics.
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"net"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/Gympass/gcore/v3/gcontext"
+	"github.com/Gympass/gcore/v3/glog"
+	"github.com/pkg/errors"
+
+	// pq registers the postgres driver used by database/sql.
+	_ "github.com/lib/pq"
+)
+
+const driverName = "postgres"
+
+// Config is used to receive all parameters to open a connection pool.
+type Config struct {
+	Host, Port string
+	User, Pass string
+	Database   string
+	// SSLMode is the libpq sslmode, disable when empty.
+	SSLMode string
+
+	// ConnectTimeout bounds the opening of each connection.
+	ConnectTimeout time.Duration
+	// StatementTimeout makes the server cancel any statement running for
+	// longer, whatever the context of the caller.
+	StatementTimeout time.Duration
+
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+
+	Logger glog.Logger
+}
+
+// DSN returns the connection URL described by c.
+func (c Config) DSN() string {
+	return c.dsn(url.UserPassword(c.User, c.Pass))
+}
+
+// String returns the connection URL without the password, for logging.
+func (c Config) String() string {
+	return c.dsn(url.User(c.User))
+}
+
+func (c Config) dsn(user *url.Userinfo) string {
+	q := url.Values{}
+
+	sslMode := c.SSLMode
+	if sslMode == "" {
+		sslMode = "disable"
+	}
+	q.Set("sslmode", sslMode)
+
+	if c.ConnectTimeout > 0 {
+		q.Set("connect_timeout", strconv.Itoa(int(c.ConnectTimeout.Seconds())))
+	}
+
+	// Parameters unknown to lib/pq are sent to the server as run-time settings.
+	if c.StatementTimeout > 0 {
+		q.Set("statement_timeout", strconv.FormatInt(c.StatementTimeout.Milliseconds(), 10))
+	}
+
+	u := url.URL{
+		Scheme:   "postgres",
+		User:     user,
+		Host:     net.JoinHostPort(c.Host, c.Port),
+		Path:     "/" + c.Database,
+		RawQuery: q.Encode(),
+	}
+
+	return u.String()
+}
+
+// DB is a pool of connections to a PostgreSQL database.
+type DB struct {
+	*sql.DB
+
+	cfg    Config
+	logger glog.Logger
+}
+
+// Open returns a pool configured by c. Connections are opened lazily, use
+// PingContext or the health check to know whether the server is reachable.
+func Open(c Config) (*DB, error) {
+	db, err := sql.Open(driverName, c.DSN())
+	if err != nil {
+		return nil, errors.Wrapf(err, "opening %s", c)
+	}
+
+	db.SetMaxOpenConns(c.MaxOpenConns)
+	db.SetMaxIdleConns(c.MaxIdleConns)
+	db.SetConnMaxLifetime(c.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(c.ConnMaxIdleTime)
+
+	return &DB{DB: db, cfg: c, logger: c.Logger}, nil
+}
+
+// Name implements health.Checker.
+func (db *DB) Name() string {
+	return driverName
+}
+
+// Check implements health.Checker.
+func (db *DB) Check(ctx context.Context) error {
+	return db.PingContext(ctx)
+}
+
+// Details implements health.Detailer with the pool statistics.
+func (db *DB) Details() interface{} {
+	return NewStats(db.Stats())
+}
+
+// Stats is the JSON view of sql.DBStats.
+type Stats struct {
+	MaxOpenConnections int     `json:"max_open_connections"`
+	OpenConnections    int     `json:"open_connections"`
+	InUse              int     `json:"in_use"`
+	Idle               int     `json:"idle"`
+	WaitCount          int64   `json:"wait_count"`
+	WaitDuration       float64 `json:"wait_duration_ms"`
+	MaxIdleClosed      int64   `json:"max_idle_closed"`
+	MaxIdleTimeClosed  int64   `json:"max_idle_time_closed"`
+	MaxLifetimeClosed  int64   `json:"max_lifetime_closed"`
+}
+
+// NewStats converts s into Stats.
+func NewStats(s sql.DBStats) Stats {
+	return Stats{
+		MaxOpenConnections: s.MaxOpenConnections,
+		OpenConnections:    s.OpenConnections,
+		InUse:              s.InUse,
+		Idle:               s.Idle,
+		WaitCount:          s.WaitCount,
+		WaitDuration:       float64(s.WaitDuration.Microseconds()) / 1000,
+		MaxIdleClosed:      s.MaxIdleClosed,
+		MaxIdleTimeClosed:  s.MaxIdleTimeClosed,
+		MaxLifetimeClosed:  s.MaxLifetimeClosed,
+	}
+}
+
+// LogStats logs the pool statistics every interval until ctx is done.
+func (db *DB) LogStats(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s := db.Stats()
+
+			lctx := gcontext.NewContext(context.Background())
+			gcontext.AddString(lctx, "db.data_source_name", db.cfg.String())
+			gcontext.AddString(lctx, "db.open_connections", strconv.Itoa(s.OpenConnections))
+			gcontext.AddString(lctx, "db.in_use", strconv.Itoa(s.InUse))
+			gcontext.AddString(lctx, "db.idle", strconv.Itoa(s.Idle))
+			gcontext.AddString(lctx, "db.wait_count", strconv.FormatInt(s.WaitCount, 10))
+			gcontext.AddString(lctx, "db.wait_duration", s.WaitDuration.String())
+			gcontext.AddString(lctx, "db.max_lifetime_closed", strconv.FormatInt(s.MaxLifetimeClosed, 10))
+
+			db.logger.Info(lctx, "Database pool statistics.")
+		}
+	}
+}
@@ -0,0 +1,50 @@
+// Package recorder records the status of the responses written by
+// handlers, for the middlewares reporting on them.
+package recorder
+
+import "net/http"
+
+// Recorder wraps a ResponseWriter, recording the status written through
+// it.
+type Recorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+// New returns a Recorder writing to w.
+func New(w http.ResponseWriter) *Recorder {
+	return &Recorder{ResponseWriter: w, status: http.StatusOK}
+}
+
+// Status returns the status of the response, 200 until written.
+func (rw *Recorder) Status() int {
+	return rw.status
+}
+
+// Started reports whether the status or part of the body was written.
+func (rw *Recorder) Started() bool {
+	return rw.wroteHeader
+}
+
+// WriteHeader records the first status written.
+func (rw *Recorder) WriteHeader(code int) {
+	if !rw.wroteHeader {
+		rw.status = code
+		rw.wroteHeader = true
+	}
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+func (rw *Recorder) Write(b []byte) (int, error) {
+	rw.wroteHeader = true
+	return rw.ResponseWriter.Write(b)
+}
+
+// Flush flushes w when it supports it, so streaming handlers keep working
+// behind the middlewares.
+func (rw *Recorder) Flush() {
+	if f, ok := rw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
@@ -0,0 +1,63 @@
+package recorder_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/recorder"
+)
+
+func TestRecorder(t *testing.T) {
+	tt := []struct {
+		Name            string
+		Handler         func(http.ResponseWriter)
+		ExpectedStatus  int
+		ExpectedStarted bool
+	}{
+		{
+			Name:           "test nothing written",
+			Handler:        func(http.ResponseWriter) {},
+			ExpectedStatus: http.StatusOK,
+		},
+		{
+			Name:            "test body only",
+			Handler:         func(w http.ResponseWriter) { _, _ = w.Write([]byte("ok")) },
+			ExpectedStatus:  http.StatusOK,
+			ExpectedStarted: true,
+		},
+		{
+			Name: "test first status kept",
+			Handler: func(w http.ResponseWriter) {
+				w.WriteHeader(http.StatusCreated)
+				w.WriteHeader(http.StatusInternalServerError)
+			},
+			ExpectedStatus:  http.StatusCreated,
+			ExpectedStarted: true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			dst := httptest.NewRecorder()
+			rw := recorder.New(dst)
+			tc.Handler(rw)
+
+			if rw.Status() != tc.ExpectedStatus {
+				t.Fatalf("status = %d, want %d", rw.Status(), tc.ExpectedStatus)
+			}
+			if rw.Started() != tc.ExpectedStarted {
+				t.Fatalf("started = %v, want %v", rw.Started(), tc.ExpectedStarted)
+			}
+		})
+	}
+}
+
+func TestRecorderFlush(t *testing.T) {
+	dst := httptest.NewRecorder()
+	recorder.New(dst).Flush()
+
+	if !dst.Flushed {
+		t.Fatalf("flush did not reach the wrapped writer")
+	}
+}
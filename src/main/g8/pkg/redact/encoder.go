@@ -0,0 +1,131 @@
+package redact
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// RegisterEncoding registers with zap a JSON encoding named name masking
+// the secrets of r, so loggers built from a zap.Config redact their
+// entries, whatever builds them:
+//
+//	redact.RegisterEncoding("redacted-json", r)
+//	cfg.Encoding = "redacted-json"
+//	glog.SetLogger(gzap.New(cfg))
+//
+// Names are registered once per process.
+func RegisterEncoding(name string, r *Redactor) error {
+	err := zap.RegisterEncoder(name, func(c zapcore.EncoderConfig) (zapcore.Encoder, error) {
+		return r.Encoder(zapcore.NewJSONEncoder(c)), nil
+	})
+
+	return errors.Wrapf(err, "registering %s encoding", name)
+}
+
+// Encoder wraps enc so that it masks the message and the fields of the
+// entries it encodes. Fields named like credentials are masked whole,
+// string, error and fmt.Stringer values have their secrets masked, other
+// values are left as they are.
+func (r *Redactor) Encoder(enc zapcore.Encoder) zapcore.Encoder {
+	return &encoder{Encoder: enc, r: r}
+}
+
+type encoder struct {
+	zapcore.Encoder
+	r *Redactor
+}
+
+func (e *encoder) Clone() zapcore.Encoder {
+	return &encoder{Encoder: e.Encoder.Clone(), r: e.r}
+}
+
+// AddString masks the fields added with Logger.With.
+func (e *encoder) AddString(key, value string) {
+	e.Encoder.AddString(key, e.value(key, value))
+}
+
+func (e *encoder) AddByteString(key string, value []byte) {
+	e.Encoder.AddString(key, e.value(key, string(value)))
+}
+
+func (e *encoder) AddReflected(key string, value interface{}) error {
+	if e.r.Field(key) {
+		e.Encoder.AddString(key, Masked)
+		return nil
+	}
+
+	return e.Encoder.AddReflected(key, value)
+}
+
+func (e *encoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	ent.Message = e.r.String(ent.Message)
+
+	masked := make([]zapcore.Field, len(fields))
+	for i, f := range fields {
+		masked[i] = e.field(f)
+	}
+
+	return e.Encoder.EncodeEntry(ent, masked)
+}
+
+func (e *encoder) value(key, value string) string {
+	if e.r.Field(key) {
+		return Masked
+	}
+
+	return e.r.String(value)
+}
+
+func (e *encoder) field(f zapcore.Field) zapcore.Field {
+	switch f.Type {
+	case zapcore.NamespaceType, zapcore.SkipType:
+		return f
+	}
+
+	if e.r.Field(f.Key) {
+		return zap.String(f.Key, Masked)
+	}
+
+	switch f.Type {
+	case zapcore.StringType:
+		f.String = e.r.String(f.String)
+	case zapcore.ByteStringType:
+		if b, ok := f.Interface.([]byte); ok {
+			return zap.String(f.Key, e.r.String(string(b)))
+		}
+	case zapcore.ErrorType:
+		if err, ok := f.Interface.(error); ok && err != nil {
+			return zap.NamedError(f.Key, &maskedError{err: err, r: e.r})
+		}
+	case zapcore.StringerType:
+		if s, ok := f.Interface.(fmt.Stringer); ok && s != nil {
+			return zap.String(f.Key, e.r.String(fmt.Sprint(s)))
+		}
+	}
+
+	return f
+}
+
+// maskedError masks the message of err, and its verbose form holding the
+// stack of errors built with github.com/pkg/errors.
+type maskedError struct {
+	err error
+	r   *Redactor
+}
+
+func (e *maskedError) Error() string {
+	return e.r.String(e.err.Error())
+}
+
+func (e *maskedError) Format(s fmt.State, verb rune) {
+	if verb == 'v' && s.Flag('+') {
+		fmt.Fprint(s, e.r.String(fmt.Sprintf("%+v", e.err)))
+		return
+	}
+
+	fmt.Fprint(s, e.Error())
+}
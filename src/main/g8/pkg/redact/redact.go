@@ -0,0 +1,233 @@
+// Package redact keeps secrets out of the logs. A Redactor masks the
+// fields named like credentials, the secret values registered with it and
+// the text matching patterns such as bearer tokens and card numbers; its
+// zap encoder applies it to every entry.
+package redact
+
+import (
+	"path"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Masked replaces the redacted values.
+const Masked = "[REDACTED]"
+
+// minSecretLen is the length under which registered values are ignored,
+// masking them would mangle unrelated text.
+const minSecretLen = 4
+
+var (
+	// DefaultFieldPatterns match, ignoring case, the names of the fields
+	// masked whatever their value.
+	DefaultFieldPatterns = []string{
+		"*password*", "*passwd*", "*secret*", "*token*", "*api_key*", "*apikey*",
+		"authorization", "*.authorization", "cookie", "*.cookie", "set-cookie", "*.set-cookie",
+	}
+	// DefaultPatterns match the values masked wherever they appear.
+	DefaultPatterns = []*regexp.Regexp{
+		// Bearer and basic credentials of Authorization headers.
+		regexp.MustCompile(`(?i)\b(?:bearer|basic)\s+[a-z0-9._~+/=-]{8,}`),
+		// JSON Web Tokens.
+		regexp.MustCompile(`\beyJ[a-zA-Z0-9_-]+\.eyJ[a-zA-Z0-9_-]+\.[a-zA-Z0-9_-]*`),
+		// Passwords of URLs, such as data source names.
+		regexp.MustCompile(`(?i)\b([a-z][a-z0-9+.-]*://[^:/@\s]*:)[^@/\s]+(@)`),
+	}
+	// cardNumber matches 13 to 19 digits, optionally grouped by spaces or
+	// dashes, masked when they pass the Luhn check.
+	cardNumber = regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`)
+)
+
+// Config used by redact package.
+type Config struct {
+	// FieldPatterns are path.Match patterns of the field names masked, in
+	// addition to DefaultFieldPatterns.
+	FieldPatterns []string
+	// Patterns match the values masked, in addition to DefaultPatterns and
+	// card numbers. Patterns with groups keep the text of the first one
+	// before the mask and of the second one after it.
+	Patterns []*regexp.Regexp
+	// Secrets are masked wherever they appear.
+	Secrets []string
+}
+
+// Redactor masks secrets in log entries.
+type Redactor struct {
+	fields   []string
+	patterns []*regexp.Regexp
+
+	mu       sync.RWMutex
+	secrets  map[string]bool
+	replacer *strings.Replacer
+}
+
+// New creates a Redactor based on configuration properties.
+func New(c Config) *Redactor {
+	r := &Redactor{
+		patterns: append(append([]*regexp.Regexp(nil), DefaultPatterns...), c.Patterns...),
+		secrets:  make(map[string]bool),
+		replacer: strings.NewReplacer(),
+	}
+
+	for _, f := range append(append([]string(nil), DefaultFieldPatterns...), c.FieldPatterns...) {
+		r.fields = append(r.fields, strings.ToLower(f))
+	}
+
+	r.Register(c.Secrets...)
+
+	return r
+}
+
+// Register adds secrets masked wherever they appear. Values shorter than
+// four characters are ignored.
+func (r *Redactor) Register(secrets ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, s := range secrets {
+		if len(s) >= minSecretLen {
+			r.secrets[s] = true
+		}
+	}
+
+	// Longer secrets first, so one containing another is masked whole.
+	all := r.registered()
+	sort.Slice(all, func(i, j int) bool { return len(all[i]) > len(all[j]) })
+
+	pairs := make([]string, 0, 2*len(all))
+	for _, s := range all {
+		pairs = append(pairs, s, Masked)
+	}
+	r.replacer = strings.NewReplacer(pairs...)
+}
+
+// RegisterStruct registers the string fields of the struct held or pointed
+// by v tagged secret:"true", looking into nested structs.
+func (r *Redactor) RegisterStruct(v interface{}) {
+	r.Register(secretFields(reflect.ValueOf(v))...)
+}
+
+func secretFields(v reflect.Value) []string {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var secrets []string
+	for i := 0; i < v.NumField(); i++ {
+		sf, fv := v.Type().Field(i), v.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+
+		switch {
+		case sf.Tag.Get("secret") == "true" && fv.Kind() == reflect.String:
+			secrets = append(secrets, fv.String())
+		case sf.Tag.Get("secret") == "true" && fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() == reflect.String:
+			for j := 0; j < fv.Len(); j++ {
+				secrets = append(secrets, fv.Index(j).String())
+			}
+		default:
+			secrets = append(secrets, secretFields(fv)...)
+		}
+	}
+
+	return secrets
+}
+
+// Field tells whether the values of the field name are masked.
+func (r *Redactor) Field(name string) bool {
+	name = strings.ToLower(name)
+	for _, p := range r.fields {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// String masks the registered secrets and the text matching the patterns
+// in s.
+func (r *Redactor) String(s string) string {
+	if s == "" {
+		return s
+	}
+
+	r.mu.RLock()
+	s = r.replacer.Replace(s)
+	r.mu.RUnlock()
+
+	for _, p := range r.patterns {
+		switch p.NumSubexp() {
+		case 0:
+			s = p.ReplaceAllLiteralString(s, Masked)
+		case 1:
+			s = p.ReplaceAllString(s, "\${1}"+Masked)
+		default:
+			s = p.ReplaceAllString(s, "\${1}"+Masked+"\${2}")
+		}
+	}
+
+	return cardNumber.ReplaceAllStringFunc(s, func(m string) string {
+		if luhn(m) {
+			return Masked
+		}
+		return m
+	})
+}
+
+// Leaks returns how many registered secrets appear in s.
+func (r *Redactor) Leaks(s string) int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	n := 0
+	for secret := range r.secrets {
+		if strings.Contains(s, secret) {
+			n++
+		}
+	}
+
+	return n
+}
+
+func (r *Redactor) registered() []string {
+	all := make([]string, 0, len(r.secrets))
+	for s := range r.secrets {
+		all = append(all, s)
+	}
+
+	return all
+}
+
+// luhn tells whether the digits of s pass the Luhn checksum of card
+// numbers.
+func luhn(s string) bool {
+	sum, double := 0, false
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+
+		d := int(c - '0')
+		if double {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+
+	return sum%10 == 0
+}
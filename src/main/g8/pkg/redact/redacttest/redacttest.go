@@ -0,0 +1,63 @@
+// Package redacttest fails tests whose logs hold the secrets registered
+// with a redact.Redactor.
+package redacttest
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/redact"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// AssertNoLeaks fails t when output holds a secret registered with r. The
+// secrets are not printed, to keep them out of the test logs as well.
+func AssertNoLeaks(t testing.TB, r *redact.Redactor, output string) {
+	t.Helper()
+
+	if n := r.Leaks(output); n > 0 {
+		t.Fatalf("%d registered secrets leaked into the log output", n)
+	}
+}
+
+// Output is the log output captured by Logger.
+type Output struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+// Write implements io.Writer.
+func (o *Output) Write(p []byte) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return o.buf.Write(p)
+}
+
+// String returns the lines written so far.
+func (o *Output) String() string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return o.buf.String()
+}
+
+// Logger returns a debug logger writing JSON entries masked by r, as the
+// service does, and their output. The test fails when it ends with a
+// registered secret in the output.
+func Logger(t testing.TB, r *redact.Redactor) (*zap.Logger, *Output) {
+	t.Helper()
+
+	out := &Output{}
+	enc := r.Encoder(zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()))
+	logger := zap.New(zapcore.NewCore(enc, zapcore.AddSync(out), zapcore.DebugLevel))
+
+	t.Cleanup(func() {
+		_ = logger.Sync()
+		AssertNoLeaks(t, r, out.String())
+	})
+
+	return logger, out
+}
@@ -0,0 +1,116 @@
+// Package requestid correlates the log lines, spans and messages of a
+// request through its X-Request-ID.
+package requestid
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/Gympass/gcore/v3/gcontext"
+	"github.com/gofrs/uuid"
+)
+
+// Header carries the request id, on HTTP requests, responses and Kafka
+// messages alike.
+const Header = "X-Request-ID"
+
+// Field names the request id in log entries and span attributes.
+const Field = "request_id"
+
+// maxLen bounds the ids accepted from clients.
+const maxLen = 128
+
+type contextKey struct{}
+
+// NewContext returns ctx carrying id.
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the request id carried by ctx, empty without one.
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
+
+// Middleware takes the request id from the X-Request-ID header, or
+// generates a UUIDv4 when missing or malformed, echoes it in the response
+// and stores it in the request context and its log fields. Wrap it around
+// the CORS handler so that every response carries it.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(Header)
+		if !valid(id) {
+			u, err := uuid.NewV4()
+			if err != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			id = u.String()
+			r.Header.Set(Header, id)
+		}
+		w.Header().Set(Header, id)
+
+		ctx := gcontext.NewContext(NewContext(r.Context(), id))
+		gcontext.AddString(ctx, Field, id)
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// Transport returns a RoundTripper forwarding the request id of the
+// request context through base, or http.DefaultTransport when nil.
+func Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	return &transport{base: base}
+}
+
+type transport struct {
+	base http.RoundTripper
+}
+
+func (t *transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	if id := FromContext(r.Context()); id != "" && r.Header.Get(Header) == "" {
+		// RoundTrip must not change the request, the headers are copied.
+		r = r.Clone(r.Context())
+		r.Header.Set(Header, id)
+	}
+
+	return t.base.RoundTrip(r)
+}
+
+// Inject writes the request id of ctx, if any, to the headers of a
+// message.
+func Inject(ctx context.Context, headers map[string]string) {
+	if id := FromContext(ctx); id != "" {
+		headers[Header] = id
+	}
+}
+
+// Extract returns ctx carrying the request id held by the headers of a
+// message, or ctx itself without a valid one.
+func Extract(ctx context.Context, headers map[string]string) context.Context {
+	if id := headers[Header]; valid(id) {
+		return NewContext(ctx, id)
+	}
+
+	return ctx
+}
+
+// valid reports whether id is short and printable ASCII, so that it is
+// safe to echo and to log.
+func valid(id string) bool {
+	if id == "" || len(id) > maxLen {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,115 @@
+package requestid_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/requestid"
+)
+
+func TestMiddleware(t *testing.T) {
+	tt := []struct {
+		Name      string
+		Header    string
+		Expected  string
+		Generated bool
+	}{
+		{
+			Name:     "test incoming id kept",
+			Header:   "client-42",
+			Expected: "client-42",
+		},
+		{
+			Name:      "test missing id generated",
+			Generated: true,
+		},
+		{
+			Name:      "test malformed id replaced",
+			Header:    "bad id\n",
+			Generated: true,
+		},
+		{
+			Name:      "test long id replaced",
+			Header:    strings.Repeat("a", 129),
+			Generated: true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			var got, forwarded string
+			h := requestid.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = requestid.FromContext(r.Context())
+				forwarded = r.Header.Get(requestid.Header)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tc.Header != "" {
+				req.Header.Set(requestid.Header, tc.Header)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if tc.Generated {
+				u, err := uuid.FromString(got)
+				if err != nil || u.Version() != uuid.V4 {
+					t.Fatalf("request id = %q, want a UUIDv4", got)
+				}
+			} else if got != tc.Expected {
+				t.Fatalf("request id = %q, want %q", got, tc.Expected)
+			}
+			if echoed := rec.Header().Get(requestid.Header); echoed != got {
+				t.Fatalf("response id = %q, want %q", echoed, got)
+			}
+			if forwarded != got {
+				t.Fatalf("request header = %q, want %q", forwarded, got)
+			}
+		})
+	}
+}
+
+func TestTransport(t *testing.T) {
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get(requestid.Header)
+	}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: requestid.Transport(nil)}
+
+	req, _ := http.NewRequestWithContext(requestid.NewContext(context.Background(), "req-1"), http.MethodGet, srv.URL, nil)
+	res, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	res.Body.Close()
+
+	if got != "req-1" {
+		t.Fatalf("forwarded id = %q, want req-1", got)
+	}
+	if req.Header.Get(requestid.Header) != "" {
+		t.Fatalf("Transport changed the request headers")
+	}
+}
+
+func TestMessageHeaders(t *testing.T) {
+	headers := map[string]string{}
+	requestid.Inject(context.Background(), headers)
+	if len(headers) != 0 {
+		t.Fatalf("headers = %v, want none without a request id", headers)
+	}
+
+	requestid.Inject(requestid.NewContext(context.Background(), "req-1"), headers)
+	if id := requestid.FromContext(requestid.Extract(context.Background(), headers)); id != "req-1" {
+		t.Fatalf("extracted id = %q, want req-1", id)
+	}
+
+	headers[requestid.Header] = "bad id"
+	if id := requestid.FromContext(requestid.Extract(context.Background(), headers)); id != "" {
+		t.Fatalf("extracted id = %q, want none", id)
+	}
+}
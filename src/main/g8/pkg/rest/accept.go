@@ -0,0 +1,99 @@
+package rest
+
+import (
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// mediaRange is one entry of an Accept header.
+type mediaRange struct {
+	typ, subtype string
+	q            float64
+}
+
+// parseAccept returns the media ranges of an Accept header ordered by
+// preference. An empty header accepts anything.
+func parseAccept(header string) []mediaRange {
+	if strings.TrimSpace(header) == "" {
+		return []mediaRange{{typ: "*", subtype: "*", q: 1}}
+	}
+
+	var ranges []mediaRange
+	for _, part := range strings.Split(header, ",") {
+		mt, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		typ, subtype, _ := strings.Cut(mt, "/")
+		mr := mediaRange{typ: typ, subtype: subtype, q: 1}
+		if q, ok := params["q"]; ok {
+			if v, err := strconv.ParseFloat(q, 64); err == nil {
+				mr.q = v
+			}
+		}
+
+		ranges = append(ranges, mr)
+	}
+
+	// More specific ranges win between equal weights, as in RFC 7231.
+	sort.SliceStable(ranges, func(i, j int) bool {
+		if ranges[i].q != ranges[j].q {
+			return ranges[i].q > ranges[j].q
+		}
+		return ranges[i].specificity() > ranges[j].specificity()
+	})
+
+	return ranges
+}
+
+func (m mediaRange) specificity() int {
+	switch {
+	case m.typ == "*":
+		return 0
+	case m.subtype == "*":
+		return 1
+	default:
+		return 2
+	}
+}
+
+func (m mediaRange) matches(mediaType string) bool {
+	typ, subtype, _ := strings.Cut(mediaType, "/")
+
+	return (m.typ == "*" || m.typ == typ) && (m.subtype == "*" || m.subtype == subtype)
+}
+
+// weight returns the q value the ranges give to mediaType, taken from the
+// most specific matching range.
+func weight(ranges []mediaRange, mediaType string) float64 {
+	best, q := -1, 0.0
+	for _, m := range ranges {
+		if m.matches(mediaType) && m.specificity() > best {
+			best, q = m.specificity(), m.q
+		}
+	}
+
+	return q
+}
+
+// negotiate returns the offer preferred by the request Accept header, or an
+// empty string when none is acceptable. Ties go to the first offer.
+func negotiate(r *http.Request, offers ...string) string {
+	ranges := parseAccept(r.Header.Get("Accept"))
+
+	var (
+		best  string
+		bestQ float64
+	)
+	for _, o := range offers {
+		if q := weight(ranges, o); q > bestQ {
+			best, bestQ = o, q
+		}
+	}
+
+	return best
+}
@@ -0,0 +1,353 @@
+// Package auth authenticates requests by their JWT bearer token and checks
+// the scopes each route requires.
+package auth
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Gympass/gcore/v3/gcontext"
+	"github.com/Gympass/gcore/v3/glog"
+	jwt "github.com/form3tech-oss/jwt-go"
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/rest"
+	"github.com/pkg/errors"
+)
+
+const (
+	// ProblemTypeUnauthorized identifies requests without a valid token.
+	ProblemTypeUnauthorized = "urn:problem-type:unauthorized"
+	// ProblemTypeForbidden identifies requests whose token lacks a scope.
+	ProblemTypeForbidden = "urn:problem-type:forbidden"
+)
+
+const (
+	defaultClockSkew       = time.Minute
+	defaultRefreshInterval = time.Hour
+	defaultMinRefresh      = time.Minute
+	defaultClientTimeout   = 10 * time.Second
+)
+
+// DefaultAlgorithms are accepted when Config.Algorithms is empty. A token
+// is only verified by a key of the type its algorithm calls for.
+var DefaultAlgorithms = []string{"RS256", "ES256", "HS256"}
+
+var (
+	// ErrUnauthorized is returned for requests without a valid bearer
+	// token, answered 401.
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrForbidden is returned for tokens lacking a scope required by the
+	// route, answered 403.
+	ErrForbidden = errors.New("forbidden")
+)
+
+func init() {
+	rest.RegisterProblem(ErrUnauthorized, rest.ProblemType{
+		Type:   ProblemTypeUnauthorized,
+		Title:  "Unauthorized",
+		Status: http.StatusUnauthorized,
+	})
+	rest.RegisterProblem(ErrForbidden, rest.ProblemType{
+		Type:   ProblemTypeForbidden,
+		Title:  "Insufficient scope",
+		Status: http.StatusForbidden,
+	})
+}
+
+// Config used by auth package.
+type Config struct {
+	Logger glog.Logger
+	// JWKSURL serves the JSON Web Key Set of the issuer, cached for
+	// RefreshInterval and fetched again when a token names an unknown key.
+	JWKSURL string
+	// KeyFile holds a JSON Web Key Set, or a PEM encoded RSA or EC public
+	// key or certificate.
+	KeyFile string
+	// Secret verifies HS256 tokens.
+	Secret string
+	// Issuer is required in the iss claim, when set.
+	Issuer string
+	// Audience lists the values one of which is required in the aud claim,
+	// when set.
+	Audience []string
+	// Algorithms accepted, DefaultAlgorithms when empty.
+	Algorithms []string
+	// ClockSkew tolerated on exp, nbf and iat, one minute by default.
+	ClockSkew time.Duration
+	// RefreshInterval is the time the key set of JWKSURL is cached, one
+	// hour by default.
+	RefreshInterval time.Duration
+	// MinRefreshInterval bounds the fetches of the key set of JWKSURL, one
+	// minute by default.
+	MinRefreshInterval time.Duration
+	// Client fetches the key set of JWKSURL, within its Timeout or ten
+	// seconds.
+	Client *http.Client
+}
+
+// Authenticator validates bearer tokens.
+type Authenticator struct {
+	cfg    Config
+	keys   map[string]key
+	secret []byte
+	remote *remoteKeys
+	parser *jwt.Parser
+}
+
+// New creates an Authenticator based on configuration properties, reading
+// KeyFile at once. The key set of JWKSURL is fetched with the first token.
+func New(c Config) (*Authenticator, error) {
+	if c.JWKSURL == "" && c.KeyFile == "" && c.Secret == "" {
+		return nil, errors.New("no key configured, set a JWKS URL, a key file or a secret")
+	}
+	if c.Logger == nil {
+		c.Logger = glog.Noop()
+	}
+	if len(c.Algorithms) == 0 {
+		c.Algorithms = DefaultAlgorithms
+	}
+	if c.ClockSkew <= 0 {
+		c.ClockSkew = defaultClockSkew
+	}
+	if c.RefreshInterval <= 0 {
+		c.RefreshInterval = defaultRefreshInterval
+	}
+	if c.MinRefreshInterval <= 0 {
+		c.MinRefreshInterval = defaultMinRefresh
+	}
+	if c.Client == nil {
+		c.Client = &http.Client{Timeout: defaultClientTimeout}
+	}
+
+	a := &Authenticator{
+		cfg:  c,
+		keys: map[string]key{},
+		parser: &jwt.Parser{
+			ValidMethods:  c.Algorithms,
+			UseJSONNumber: true,
+			// The time claims are checked by validate, with clock skew.
+			SkipClaimsValidation: true,
+		},
+	}
+
+	if c.KeyFile != "" {
+		keys, err := readKeyFile(c.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		for kid, k := range keys {
+			a.keys[kid] = k
+		}
+	}
+	if c.Secret != "" {
+		a.secret = []byte(c.Secret)
+	}
+	if c.JWKSURL != "" {
+		timeout := c.Client.Timeout
+		if timeout <= 0 {
+			timeout = defaultClientTimeout
+		}
+		a.remote = &remoteKeys{
+			url:        c.JWKSURL,
+			client:     c.Client,
+			timeout:    timeout,
+			refresh:    c.RefreshInterval,
+			minRefresh: c.MinRefreshInterval,
+			logger:     c.Logger,
+		}
+	}
+
+	return a, nil
+}
+
+// Authenticate returns the claims of the bearer token of r, or an error
+// matching ErrUnauthorized.
+func (a *Authenticator) Authenticate(r *http.Request) (*Claims, error) {
+	raw, ok := bearer(r)
+	if !ok {
+		return nil, errors.Wrap(ErrUnauthorized, "missing bearer token")
+	}
+
+	mc := jwt.MapClaims{}
+	_, err := a.parser.ParseWithClaims(raw, mc, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); ok && a.secret != nil {
+			return a.secret, nil
+		}
+
+		kid, _ := t.Header["kid"].(string)
+		k, err := a.key(kid, t.Method.Alg())
+		if err != nil {
+			return nil, err
+		}
+
+		return k.value, nil
+	})
+	if err != nil {
+		return nil, errors.Wrapf(ErrUnauthorized, "invalid token: %v", validationCause(err))
+	}
+
+	c, err := newClaims(mc)
+	if err != nil {
+		return nil, errors.Wrapf(ErrUnauthorized, "invalid token: %v", err)
+	}
+	if err := a.validate(c); err != nil {
+		return nil, errors.Wrapf(ErrUnauthorized, "invalid token: %v", err)
+	}
+
+	return c, nil
+}
+
+// key returns the key named kid verifying alg, from the key file first.
+func (a *Authenticator) key(kid, alg string) (key, error) {
+	if k, ok := lookup(a.keys, kid); ok && k.fits(alg) {
+		return k, nil
+	}
+	if a.remote == nil {
+		return key{}, errUnknownKey
+	}
+
+	k, err := a.remote.get(kid)
+	if err != nil {
+		return key{}, err
+	}
+	if !k.fits(alg) {
+		return key{}, errors.Errorf("key %s is not meant for %s", kid, alg)
+	}
+
+	return k, nil
+}
+
+// validate checks the issuer, the audience and the time claims of c.
+func (a *Authenticator) validate(c *Claims) error {
+	now := time.Now()
+	skew := a.cfg.ClockSkew
+
+	if c.ExpiresAt.IsZero() {
+		return errors.New("missing exp claim")
+	}
+	if now.After(c.ExpiresAt.Add(skew)) {
+		return errors.Errorf("token expired %s ago", now.Sub(c.ExpiresAt).Truncate(time.Second))
+	}
+	if !c.IssuedAt.IsZero() && c.IssuedAt.After(now.Add(skew)) {
+		return errors.New("token issued in the future")
+	}
+	nbf, err := timeClaim(c.Raw, "nbf")
+	if err != nil {
+		return err
+	}
+	if !nbf.IsZero() && nbf.After(now.Add(skew)) {
+		return errors.New("token not valid yet")
+	}
+
+	if a.cfg.Issuer != "" && c.Issuer != a.cfg.Issuer {
+		return errors.Errorf("unexpected issuer %q", c.Issuer)
+	}
+	if len(a.cfg.Audience) > 0 && !intersects(a.cfg.Audience, c.Audience) {
+		return errors.New("unexpected audience")
+	}
+
+	return nil
+}
+
+// Requirement of a route.
+type Requirement struct {
+	// Scopes are all required.
+	Scopes []string
+}
+
+// Scopes requires each of scopes from the tokens of a route.
+func Scopes(scopes ...string) Requirement {
+	return Requirement{Scopes: scopes}
+}
+
+// Authenticated only requires a valid token.
+var Authenticated = Requirement{}
+
+// Require wraps a handler of a middleware.GMiddlewareHandlerError, inside
+// rest.Problems so its errors are written as problem details, and serves
+// it only to requests whose token meets req:
+//
+//	mw.HandlerError(rest.Problems(authn.Require(auth.Scopes("demo:read"), handler.Demos)))
+//
+// Handlers read the claims with FromContext. A nil Authenticator leaves
+// the routes open.
+func (a *Authenticator) Require(req Requirement, next func(http.ResponseWriter, *http.Request) error) func(http.ResponseWriter, *http.Request) error {
+	if a == nil {
+		return next
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) error {
+		c, err := a.Authenticate(r)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", challenge(r, "invalid_token", nil))
+			return err
+		}
+
+		var missing []string
+		for _, s := range req.Scopes {
+			if !c.HasScope(s) {
+				missing = append(missing, s)
+			}
+		}
+		if len(missing) > 0 {
+			w.Header().Set("WWW-Authenticate", challenge(r, "insufficient_scope", req.Scopes))
+			return errors.Wrapf(ErrForbidden, "missing scope %s", strings.Join(missing, " "))
+		}
+
+		ctx := NewContext(r.Context(), c)
+		gcontext.AddString(ctx, "auth.subject", c.Subject)
+
+		return next(w, r.WithContext(ctx))
+	}
+}
+
+// bearer returns the token of the Authorization header of r.
+func bearer(r *http.Request) (string, bool) {
+	const prefix = "bearer "
+
+	h := r.Header.Get("Authorization")
+	if len(h) <= len(prefix) || !strings.EqualFold(h[:len(prefix)], prefix) {
+		return "", false
+	}
+
+	token := strings.TrimSpace(h[len(prefix):])
+	return token, token != ""
+}
+
+// challenge builds the WWW-Authenticate header of RFC 6750. Requests
+// without credentials get no error code.
+func challenge(r *http.Request, code string, scopes []string) string {
+	if _, ok := bearer(r); !ok {
+		return "Bearer"
+	}
+
+	c := `Bearer error="` + code + `"`
+	if len(scopes) > 0 {
+		c += `, scope="` + strings.Join(scopes, " ") + `"`
+	}
+
+	return c
+}
+
+// validationCause unwraps the error of the key function, or of the
+// signature check, from the ValidationError of jwt-go.
+func validationCause(err error) error {
+	var ve *jwt.ValidationError
+	if errors.As(err, &ve) && ve.Inner != nil {
+		return ve.Inner
+	}
+
+	return err
+}
+
+func intersects(a, b []string) bool {
+	for _, x := range a {
+		for _, y := range b {
+			if x == y {
+				return true
+			}
+		}
+	}
+
+	return false
+}
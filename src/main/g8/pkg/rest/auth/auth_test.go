@@ -0,0 +1,446 @@
+package auth_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	jwt "github.com/form3tech-oss/jwt-go"
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/rest"
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/rest/auth"
+	"github.com/pkg/errors"
+)
+
+const (
+	issuer   = "https://issuer.test/"
+	audience = "demo-api"
+	secret   = "test-secret"
+)
+
+// issuerKeys stands in for an identity provider, serving the JSON Web Key
+// Set of its RSA keys.
+type issuerKeys struct {
+	keys    map[string]*rsa.PrivateKey
+	fetches int32
+}
+
+func newIssuerKeys(t *testing.T, kids ...string) *issuerKeys {
+	t.Helper()
+
+	ik := &issuerKeys{keys: map[string]*rsa.PrivateKey{}}
+	for _, kid := range kids {
+		ik.add(t, kid)
+	}
+
+	return ik
+}
+
+func (ik *issuerKeys) add(t *testing.T, kid string) {
+	t.Helper()
+
+	k, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("rsa.GenerateKey() error = %v", err)
+	}
+	ik.keys[kid] = k
+}
+
+func (ik *issuerKeys) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	atomic.AddInt32(&ik.fetches, 1)
+
+	keys := []map[string]string{}
+	for kid, k := range ik.keys {
+		keys = append(keys, map[string]string{
+			"kid": kid,
+			"kty": "RSA",
+			"alg": "RS256",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(k.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.E)).Bytes()),
+		})
+	}
+
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"keys": keys})
+}
+
+// claims returns valid claims, changed by the given overrides.
+func claims(overrides jwt.MapClaims) jwt.MapClaims {
+	c := jwt.MapClaims{
+		"sub":   "user-1",
+		"iss":   issuer,
+		"aud":   audience,
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"iat":   time.Now().Unix(),
+		"scope": "demo:read demo:write",
+	}
+	for k, v := range overrides {
+		if v == nil {
+			delete(c, k)
+			continue
+		}
+		c[k] = v
+	}
+
+	return c
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, kid string, key interface{}, c jwt.MapClaims) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(method, c)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+
+	s, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("SignedString() error = %v", err)
+	}
+
+	return s
+}
+
+func request(token string) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/v1/demo", nil)
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	return r
+}
+
+func TestAuthenticate(t *testing.T) {
+	ik := newIssuerKeys(t, "key-1")
+	srv := httptest.NewServer(ik)
+	defer srv.Close()
+
+	ec, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("ecdsa.GenerateKey() error = %v", err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&ec.PublicKey)
+	if err != nil {
+		t.Fatalf("x509.MarshalPKIXPublicKey() error = %v", err)
+	}
+	keyFile := filepath.Join(t.TempDir(), "key.pem")
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+
+	a, err := auth.New(auth.Config{
+		JWKSURL:   srv.URL,
+		KeyFile:   keyFile,
+		Secret:    secret,
+		Issuer:    issuer,
+		Audience:  []string{audience},
+		ClockSkew: time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	rs := ik.keys["key-1"]
+
+	tt := []struct {
+		Name          string
+		Token         string
+		ExpectedError bool
+	}{
+		{
+			Name:  "test RS256 token from the key set",
+			Token: sign(t, jwt.SigningMethodRS256, "key-1", rs, claims(nil)),
+		},
+		{
+			Name:  "test ES256 token from the key file",
+			Token: sign(t, jwt.SigningMethodES256, "", ec, claims(nil)),
+		},
+		{
+			Name:  "test HS256 token from the secret",
+			Token: sign(t, jwt.SigningMethodHS256, "", []byte(secret), claims(nil)),
+		},
+		{
+			Name:  "test audience array",
+			Token: sign(t, jwt.SigningMethodHS256, "", []byte(secret), claims(jwt.MapClaims{"aud": []string{"other", audience}})),
+		},
+		{
+			Name:  "test expiry within the clock skew",
+			Token: sign(t, jwt.SigningMethodRS256, "key-1", rs, claims(jwt.MapClaims{"exp": time.Now().Add(-30 * time.Second).Unix()})),
+		},
+		{
+			Name:          "test expired token",
+			Token:         sign(t, jwt.SigningMethodRS256, "key-1", rs, claims(jwt.MapClaims{"exp": time.Now().Add(-2 * time.Minute).Unix()})),
+			ExpectedError: true,
+		},
+		{
+			Name:          "test token without expiry",
+			Token:         sign(t, jwt.SigningMethodRS256, "key-1", rs, claims(jwt.MapClaims{"exp": nil})),
+			ExpectedError: true,
+		},
+		{
+			Name:          "test token not valid yet",
+			Token:         sign(t, jwt.SigningMethodRS256, "key-1", rs, claims(jwt.MapClaims{"nbf": time.Now().Add(5 * time.Minute).Unix()})),
+			ExpectedError: true,
+		},
+		{
+			Name:          "test wrong issuer",
+			Token:         sign(t, jwt.SigningMethodRS256, "key-1", rs, claims(jwt.MapClaims{"iss": "https://other.test/"})),
+			ExpectedError: true,
+		},
+		{
+			Name:          "test wrong audience",
+			Token:         sign(t, jwt.SigningMethodRS256, "key-1", rs, claims(jwt.MapClaims{"aud": "other"})),
+			ExpectedError: true,
+		},
+		{
+			Name:          "test wrong secret",
+			Token:         sign(t, jwt.SigningMethodHS256, "", []byte("other-secret"), claims(nil)),
+			ExpectedError: true,
+		},
+		{
+			Name:          "test unsigned token",
+			Token:         sign(t, jwt.SigningMethodNone, "", jwt.UnsafeAllowNoneSignatureType, claims(nil)),
+			ExpectedError: true,
+		},
+		{
+			Name:          "test malformed token",
+			Token:         "not-a-token",
+			ExpectedError: true,
+		},
+		{
+			Name:          "test missing token",
+			ExpectedError: true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			c, err := a.Authenticate(request(tc.Token))
+			if tc.ExpectedError {
+				if !errors.Is(err, auth.ErrUnauthorized) {
+					t.Fatalf("Authenticate() error = %v, want ErrUnauthorized", err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Authenticate() error = %v", err)
+			}
+			if c.Subject != "user-1" || !c.HasScope("demo:write") {
+				t.Fatalf("claims = %+v, want user-1 with demo:write", c)
+			}
+		})
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	ik := newIssuerKeys(t, "key-1")
+	srv := httptest.NewServer(ik)
+	defer srv.Close()
+
+	a, err := auth.New(auth.Config{JWKSURL: srv.URL, MinRefreshInterval: time.Nanosecond})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := a.Authenticate(request(sign(t, jwt.SigningMethodRS256, "key-1", ik.keys["key-1"], claims(nil)))); err != nil {
+			t.Fatalf("Authenticate() error = %v", err)
+		}
+	}
+	if n := atomic.LoadInt32(&ik.fetches); n != 1 {
+		t.Fatalf("key set fetched %d times, want it cached", n)
+	}
+
+	ik.add(t, "key-2")
+	if _, err := a.Authenticate(request(sign(t, jwt.SigningMethodRS256, "key-2", ik.keys["key-2"], claims(nil)))); err != nil {
+		t.Fatalf("Authenticate() error = %v, want the rotated key fetched", err)
+	}
+	if n := atomic.LoadInt32(&ik.fetches); n != 2 {
+		t.Fatalf("key set fetched %d times, want 2", n)
+	}
+
+	// A public key of the set must not verify HMAC signatures.
+	pub := x509.MarshalPKCS1PublicKey(&ik.keys["key-1"].PublicKey)
+	if _, err := a.Authenticate(request(sign(t, jwt.SigningMethodHS256, "key-1", pub, claims(nil)))); !errors.Is(err, auth.ErrUnauthorized) {
+		t.Fatalf("Authenticate() error = %v, want ErrUnauthorized", err)
+	}
+}
+
+func TestKeySetFetch(t *testing.T) {
+	ik := newIssuerKeys(t, "key-1")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+		ik.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+
+	a, err := auth.New(auth.Config{JWKSURL: srv.URL})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	token := sign(t, jwt.SigningMethodRS256, "key-1", ik.keys["key-1"], claims(nil))
+
+	// A request given up by its client does not cancel the fetch shared
+	// by the others.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		r := request(token)
+		if i == 0 {
+			r = r.WithContext(ctx)
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := a.Authenticate(r)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("Authenticate() error = %v", err)
+		}
+	}
+	if n := atomic.LoadInt32(&ik.fetches); n != 1 {
+		t.Fatalf("key set fetched %d times, want a single shared fetch", n)
+	}
+}
+
+func TestRequire(t *testing.T) {
+	a, err := auth.New(auth.Config{Secret: secret})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	h := rest.Problems(a.Require(auth.Scopes("demo:write"), func(w http.ResponseWriter, r *http.Request) error {
+		c, ok := auth.FromContext(r.Context())
+		if !ok {
+			return errors.New("no claims")
+		}
+		_, _ = w.Write([]byte(c.Subject))
+		return nil
+	}))
+
+	tt := []struct {
+		Name              string
+		Token             string
+		Accept            string
+		ExpectedStatus    int
+		ExpectedChallenge string
+		ExpectedBody      string
+	}{
+		{
+			Name:           "test claims exposed to the handler",
+			Token:          sign(t, jwt.SigningMethodHS256, "", []byte(secret), claims(nil)),
+			ExpectedStatus: http.StatusOK,
+			ExpectedBody:   "user-1",
+		},
+		{
+			Name:              "test missing token",
+			ExpectedStatus:    http.StatusUnauthorized,
+			ExpectedChallenge: "Bearer",
+			ExpectedBody:      auth.ProblemTypeUnauthorized,
+		},
+		{
+			Name:              "test invalid token",
+			Token:             sign(t, jwt.SigningMethodHS256, "", []byte("other-secret"), claims(nil)),
+			ExpectedStatus:    http.StatusUnauthorized,
+			ExpectedChallenge: `Bearer error="invalid_token"`,
+			ExpectedBody:      auth.ProblemTypeUnauthorized,
+		},
+		{
+			Name:              "test missing scope",
+			Token:             sign(t, jwt.SigningMethodHS256, "", []byte(secret), claims(jwt.MapClaims{"scope": "demo:read"})),
+			ExpectedStatus:    http.StatusForbidden,
+			ExpectedChallenge: `Bearer error="insufficient_scope", scope="demo:write"`,
+			ExpectedBody:      auth.ProblemTypeForbidden,
+		},
+		{
+			Name:              "test missing token in the legacy format",
+			Accept:            rest.ContentTypeJSON,
+			ExpectedStatus:    http.StatusUnauthorized,
+			ExpectedChallenge: "Bearer",
+			ExpectedBody:      `{"error":"missing bearer token: unauthorized"}`,
+		},
+		{
+			Name:              "test missing scope in the legacy format",
+			Token:             sign(t, jwt.SigningMethodHS256, "", []byte(secret), claims(jwt.MapClaims{"scope": "demo:read"})),
+			Accept:            rest.ContentTypeJSON,
+			ExpectedStatus:    http.StatusForbidden,
+			ExpectedChallenge: `Bearer error="insufficient_scope", scope="demo:write"`,
+			ExpectedBody:      `{"error":"missing scope demo:write: forbidden"}`,
+		},
+		{
+			Name:           "test scp claim",
+			Token:          sign(t, jwt.SigningMethodHS256, "", []byte(secret), claims(jwt.MapClaims{"scope": nil, "scp": []string{"demo:write"}})),
+			ExpectedStatus: http.StatusOK,
+			ExpectedBody:   "user-1",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			r := request(tc.Token)
+			if tc.Accept != "" {
+				r.Header.Set("Accept", tc.Accept)
+			}
+			rec := httptest.NewRecorder()
+			if err := h(rec, r); err != nil {
+				t.Fatalf("handler error = %v", err)
+			}
+
+			if rec.Code != tc.ExpectedStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tc.ExpectedStatus)
+			}
+			if got := rec.Header().Get("WWW-Authenticate"); got != tc.ExpectedChallenge {
+				t.Fatalf("WWW-Authenticate = %q, want %q", got, tc.ExpectedChallenge)
+			}
+			if !strings.Contains(rec.Body.String(), tc.ExpectedBody) {
+				t.Fatalf("body = %s, want %s", rec.Body, tc.ExpectedBody)
+			}
+		})
+	}
+}
+
+func TestRequireWithoutAuthenticator(t *testing.T) {
+	var a *auth.Authenticator
+
+	called := false
+	h := a.Require(auth.Authenticated, func(http.ResponseWriter, *http.Request) error {
+		called = true
+		return nil
+	})
+
+	if err := h(httptest.NewRecorder(), request("")); err != nil || !called {
+		t.Fatalf("handler called = %t (%v), want the route open", called, err)
+	}
+}
+
+func TestNewWithoutKeys(t *testing.T) {
+	if _, err := auth.New(auth.Config{}); err == nil {
+		t.Fatalf("New() error = nil, want an error without keys")
+	}
+}
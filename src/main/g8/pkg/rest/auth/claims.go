@@ -0,0 +1,138 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Claims of a validated token.
+type Claims struct {
+	Subject  string
+	Issuer   string
+	Audience []string
+	// Scopes lists the space separated scope claim, or the scp array.
+	Scopes    []string
+	ExpiresAt time.Time
+	// IssuedAt is zero for tokens without iat.
+	IssuedAt time.Time
+	// Raw holds every claim of the token, custom ones included. Numbers
+	// are json.Number.
+	Raw map[string]interface{}
+}
+
+// HasScope reports whether the token was granted scope.
+func (c *Claims) HasScope(scope string) bool {
+	for _, s := range c.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+
+	return false
+}
+
+type contextKey struct{}
+
+// NewContext returns ctx carrying c.
+func NewContext(ctx context.Context, c *Claims) context.Context {
+	return context.WithValue(ctx, contextKey{}, c)
+}
+
+// FromContext returns the claims of the token authenticating the request
+// of ctx, false on routes without authentication.
+func FromContext(ctx context.Context) (*Claims, bool) {
+	c, ok := ctx.Value(contextKey{}).(*Claims)
+	return c, ok
+}
+
+// newClaims reads the registered claims of raw.
+func newClaims(raw map[string]interface{}) (*Claims, error) {
+	c := &Claims{Raw: raw}
+
+	var err error
+	if c.Subject, err = stringClaim(raw, "sub"); err != nil {
+		return nil, err
+	}
+	if c.Issuer, err = stringClaim(raw, "iss"); err != nil {
+		return nil, err
+	}
+	if c.Audience, err = stringsClaim(raw, "aud"); err != nil {
+		return nil, err
+	}
+	if c.ExpiresAt, err = timeClaim(raw, "exp"); err != nil {
+		return nil, err
+	}
+	if c.IssuedAt, err = timeClaim(raw, "iat"); err != nil {
+		return nil, err
+	}
+
+	scope, err := stringClaim(raw, "scope")
+	if err != nil {
+		return nil, err
+	}
+	if c.Scopes = strings.Fields(scope); len(c.Scopes) == 0 {
+		if c.Scopes, err = stringsClaim(raw, "scp"); err != nil {
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
+
+func stringClaim(raw map[string]interface{}, name string) (string, error) {
+	switch v := raw[name].(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	default:
+		return "", errors.Errorf("claim %s is not a string", name)
+	}
+}
+
+// stringsClaim reads a claim holding either a string or an array of them.
+func stringsClaim(raw map[string]interface{}, name string) ([]string, error) {
+	switch v := raw[name].(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []string{v}, nil
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, e := range v {
+			s, ok := e.(string)
+			if !ok {
+				return nil, errors.Errorf("claim %s is not an array of strings", name)
+			}
+			values = append(values, s)
+		}
+		return values, nil
+	default:
+		return nil, errors.Errorf("claim %s is not a string", name)
+	}
+}
+
+// timeClaim reads a NumericDate claim, zero when missing.
+func timeClaim(raw map[string]interface{}, name string) (time.Time, error) {
+	var secs float64
+	switch v := raw[name].(type) {
+	case nil:
+		return time.Time{}, nil
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			return time.Time{}, errors.Errorf("claim %s is not a date", name)
+		}
+		secs = f
+	case float64:
+		secs = v
+	default:
+		return time.Time{}, errors.Errorf("claim %s is not a date", name)
+	}
+
+	return time.Unix(int64(secs), 0), nil
+}
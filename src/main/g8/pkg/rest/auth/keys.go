@@ -0,0 +1,313 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Gympass/gcore/v3/gcontext"
+	"github.com/Gympass/gcore/v3/glog"
+	jwt "github.com/form3tech-oss/jwt-go"
+	"github.com/pkg/errors"
+	"golang.org/x/sync/singleflight"
+)
+
+// maxKeySetSize bounds the key sets read from JWKS URLs.
+const maxKeySetSize = 1 << 20
+
+// errUnknownKey is returned for tokens signed with a key missing from the
+// configured ones.
+var errUnknownKey = errors.New("unknown signing key")
+
+// jwk is a JSON Web Key, as defined by RFC 7517, limited to the members
+// needed to verify signatures.
+type jwk struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	K   string `json:"k"`
+}
+
+type jwks struct {
+	Keys []jwk `json:"keys"`
+}
+
+// key is a verification key along with the algorithm it is restricted to,
+// if any.
+type key struct {
+	alg   string
+	value interface{}
+}
+
+// fits reports whether k verifies the signatures of alg.
+func (k key) fits(alg string) bool {
+	if k.alg != "" && k.alg != alg {
+		return false
+	}
+
+	switch k.value.(type) {
+	case *rsa.PublicKey:
+		return strings.HasPrefix(alg, "RS") || strings.HasPrefix(alg, "PS")
+	case *ecdsa.PublicKey:
+		return strings.HasPrefix(alg, "ES")
+	case []byte:
+		return strings.HasPrefix(alg, "HS")
+	default:
+		return false
+	}
+}
+
+// parseJWKS decodes the keys of a JSON Web Key Set by kid. Encryption keys
+// and key types other than RSA, EC and oct are skipped.
+func parseJWKS(b []byte) (map[string]key, error) {
+	var set jwks
+	if err := json.Unmarshal(b, &set); err != nil {
+		return nil, errors.Wrap(err, "decoding key set")
+	}
+
+	keys := make(map[string]key, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+
+		var (
+			value interface{}
+			err   error
+		)
+		switch k.Kty {
+		case "RSA":
+			value, err = k.rsa()
+		case "EC":
+			value, err = k.ecdsa()
+		case "oct":
+			value, err = base64.RawURLEncoding.DecodeString(k.K)
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "decoding key %s", k.Kid)
+		}
+
+		keys[k.Kid] = key{alg: k.Alg, value: value}
+	}
+
+	return keys, nil
+}
+
+func (k jwk) rsa() (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, errors.Wrap(err, "decoding modulus")
+	}
+	e, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil {
+		return nil, errors.Wrap(err, "decoding exponent")
+	}
+
+	exp := new(big.Int).SetBytes(e)
+	if !exp.IsInt64() || exp.Int64() > 1<<31-1 || exp.Int64() < 3 {
+		return nil, errors.New("invalid exponent")
+	}
+
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exp.Int64())}, nil
+}
+
+func (k jwk) ecdsa() (*ecdsa.PublicKey, error) {
+	var curve elliptic.Curve
+	switch k.Crv {
+	case "P-256":
+		curve = elliptic.P256()
+	case "P-384":
+		curve = elliptic.P384()
+	case "P-521":
+		curve = elliptic.P521()
+	default:
+		return nil, errors.Errorf("unsupported curve %q", k.Crv)
+	}
+
+	x, err := base64.RawURLEncoding.DecodeString(k.X)
+	if err != nil {
+		return nil, errors.Wrap(err, "decoding x")
+	}
+	y, err := base64.RawURLEncoding.DecodeString(k.Y)
+	if err != nil {
+		return nil, errors.Wrap(err, "decoding y")
+	}
+
+	pub := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+	if !curve.IsOnCurve(pub.X, pub.Y) {
+		return nil, errors.New("point is not on the curve")
+	}
+
+	return pub, nil
+}
+
+// readKeyFile reads a JSON Web Key Set, or a PEM encoded RSA or EC public
+// key or certificate, which is then used whatever the kid of tokens.
+func readKeyFile(path string) (map[string]key, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading key file")
+	}
+
+	if bytes.HasPrefix(bytes.TrimSpace(b), []byte("{")) {
+		return parseJWKS(b)
+	}
+
+	if pub, err := jwt.ParseRSAPublicKeyFromPEM(b); err == nil {
+		return map[string]key{"": {value: pub}}, nil
+	}
+	pub, err := jwt.ParseECPublicKeyFromPEM(b)
+	if err != nil {
+		return nil, errors.Errorf("%s holds neither a key set nor an RSA or EC public key", path)
+	}
+
+	return map[string]key{"": {value: pub}}, nil
+}
+
+// remoteKeys caches the key set served at a JWKS URL. The set is fetched
+// again once older than refresh, or when a token names an unknown key,
+// at most every minRefresh so that a failing endpoint is not hammered.
+// Concurrent requests share a single fetch, made outside of the lock and
+// of their contexts so that a canceled request does not count as a failed
+// attempt.
+type remoteKeys struct {
+	url        string
+	client     *http.Client
+	timeout    time.Duration
+	refresh    time.Duration
+	minRefresh time.Duration
+	logger     glog.Logger
+
+	group     singleflight.Group
+	mu        sync.RWMutex
+	keys      map[string]key
+	fetched   time.Time
+	attempted time.Time
+}
+
+// get returns the key named kid, refreshing the set when stale.
+func (rk *remoteKeys) get(kid string) (key, error) {
+	rk.mu.RLock()
+	keys, stale := rk.keys, time.Since(rk.fetched) >= rk.refresh
+	rk.mu.RUnlock()
+
+	loaded := false
+	if keys == nil || stale {
+		keys, loaded = rk.load(), true
+	}
+
+	k, ok := lookup(keys, kid)
+	if !ok && !loaded {
+		// The issuer may have rotated its keys since the last fetch.
+		keys = rk.load()
+		k, ok = lookup(keys, kid)
+	}
+	if !ok {
+		if keys == nil {
+			return key{}, errors.Errorf("key set of %s unavailable", rk.url)
+		}
+		return key{}, errUnknownKey
+	}
+
+	return k, nil
+}
+
+// load fetches the key set unless attempted within minRefresh, keeping
+// the cached one on failure, and returns the set in use.
+func (rk *remoteKeys) load() map[string]key {
+	v, _, _ := rk.group.Do(rk.url, func() (interface{}, error) {
+		rk.mu.Lock()
+		if time.Since(rk.attempted) < rk.minRefresh {
+			keys := rk.keys
+			rk.mu.Unlock()
+			return keys, nil
+		}
+		rk.attempted = time.Now()
+		rk.mu.Unlock()
+
+		ctx, cancel := context.WithTimeout(context.Background(), rk.timeout)
+		defer cancel()
+
+		keys, err := rk.fetch(ctx)
+
+		rk.mu.Lock()
+		defer rk.mu.Unlock()
+
+		if err != nil {
+			lctx := gcontext.NewContext(context.Background())
+			gcontext.AddError(lctx, err)
+			gcontext.AddString(lctx, "auth.jwks_url", rk.url)
+			rk.logger.Warn(lctx, "Key set not refreshed, keeping the cached one.")
+			return rk.keys, nil
+		}
+
+		rk.keys, rk.fetched = keys, time.Now()
+		return keys, nil
+	})
+
+	keys, _ := v.(map[string]key)
+	return keys
+}
+
+func (rk *remoteKeys) fetch(ctx context.Context) (map[string]key, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rk.url, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "building key set request")
+	}
+	req.Header.Set("Accept", "application/json")
+
+	res, err := rk.client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "fetching key set")
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("fetching key set: status %d", res.StatusCode)
+	}
+
+	var b bytes.Buffer
+	if _, err := b.ReadFrom(io.LimitReader(res.Body, maxKeySetSize)); err != nil {
+		return nil, errors.Wrap(err, "reading key set")
+	}
+
+	return parseJWKS(b.Bytes())
+}
+
+// lookup finds kid in keys. Tokens without kid match the only key of a
+// set, and the key of a PEM file, stored without kid, matches any token.
+func lookup(keys map[string]key, kid string) (key, bool) {
+	if k, ok := keys[kid]; ok {
+		return k, true
+	}
+	if len(keys) != 1 {
+		return key{}, false
+	}
+
+	for id, k := range keys {
+		if kid == "" || id == "" {
+			return k, true
+		}
+	}
+
+	return key{}, false
+}
@@ -0,0 +1,321 @@
+package rest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/Gympass/gcore/v3/gerror"
+	"github.com/gorilla/mux"
+
+	uuid "github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+)
+
+// Parameter sources understood by Bind, in the order they are looked up.
+const (
+	InPath   = "path"
+	InQuery  = "query"
+	InHeader = "header"
+)
+
+var (
+	// ErrBindTarget is returned when Bind does not receive a pointer to a struct.
+	ErrBindTarget = errors.New("bind target must be a pointer to struct")
+
+	timeType = reflect.TypeOf(time.Time{})
+	uuidType = reflect.TypeOf(uuid.UUID{})
+)
+
+// FieldError describes a single request field that could not be accepted.
+type FieldError struct {
+	Field   string `json:"field"`
+	In      string `json:"in,omitempty"`
+	Rule    string `json:"rule,omitempty"`
+	Message string `json:"message"`
+
+	err error
+}
+
+func (e *FieldError) Error() string {
+	return e.Message
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.err
+}
+
+func fieldErr(err error, field, format string, any ...interface{}) *FieldError {
+	return &FieldError{Field: field, Message: fmt.Sprintf(format, any...), err: err}
+}
+
+// FieldErrors collects every field rejected while parsing a request.
+type FieldErrors []*FieldError
+
+func (fe FieldErrors) Error() string {
+	msgs := make([]string, len(fe))
+	for i := range fe {
+		msgs[i] = fe[i].Message
+	}
+
+	return strings.Join(msgs, "; ")
+}
+
+func (fe FieldErrors) has(field string) bool {
+	for i := range fe {
+		if fe[i].Field == field {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Bind fills the struct pointed by dst from the request.
+//
+// A JSON body, when present, is decoded first using the usual json tags.
+// Afterwards each field tagged with path, query or header is parsed from
+// the matching request source, overriding anything the body carried:
+//
+//	type listRequest struct {
+//		UID    uuid.UUID `path:"uid"`
+//		Limit  int       `query:"limit,default=20"`
+//		From   time.Time `query:"from,layout=2006-01-02"`
+//		Tenant string    `header:"X-Tenant,required"`
+//	}
+//
+// Path parameters are always required; query and header parameters only
+// when tagged with required. Missing optional parameters keep the default
+// option, if any, or the value decoded from the body. Supported types are
+// string, bool, int, int64, uuid.UUID, time.Time (RFC3339 unless a layout
+// is given), pointers to them and slices of them, read from repeated or
+// comma separated values.
+//
+// Once bound, the struct is checked against its validate tags (see
+// Validate). Every invalid or rule breaking field is reported in a single
+// gerror.NewBadRequest whose cause is a FieldErrors.
+func Bind(r *http.Request, dst interface{}) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return errors.Wrapf(ErrBindTarget, "got %T", dst)
+	}
+
+	if err := decodeBody(r, dst); err != nil {
+		return err
+	}
+
+	var errs FieldErrors
+	if err := bindStruct(r, v.Elem(), &errs); err != nil {
+		return err
+	}
+
+	var verrs FieldErrors
+	if err := validateStruct(v.Elem(), "", &verrs); err != nil {
+		return err
+	}
+
+	// A field that could not be parsed is not reported again by its rules.
+	for _, fe := range verrs {
+		if !errs.has(fe.Field) {
+			errs = append(errs, fe)
+		}
+	}
+
+	if len(errs) > 0 {
+		return gerror.NewBadRequest(errs).WithMessage(errs.Error())
+	}
+
+	return nil
+}
+
+func decodeBody(r *http.Request, dst interface{}) error {
+	if r.Body == nil || r.Body == http.NoBody || r.ContentLength == 0 {
+		return nil
+	}
+
+	err := json.NewDecoder(r.Body).Decode(dst)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return gerror.NewBadRequest(err).WithMessage("bad json format")
+	}
+
+	return nil
+}
+
+// bindStruct walks the fields of v, appending parse failures to errs. The
+// returned error is reserved for programming mistakes such as unsupported
+// field types.
+func bindStruct(r *http.Request, v reflect.Value, errs *FieldErrors) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		fv := v.Field(i)
+
+		if sf.Anonymous && sf.Type.Kind() == reflect.Struct {
+			if err := bindStruct(r, fv, errs); err != nil {
+				return err
+			}
+			continue
+		}
+
+		tag, ok := lookupParamTag(sf)
+		if !ok || !sf.IsExported() {
+			continue
+		}
+
+		raw, found := tag.values(r)
+		if !found {
+			switch {
+			case tag.def != "":
+				raw = []string{tag.def}
+			case tag.required:
+				fe := ruleErr(tag.name, RuleRequired, "missing %s parameter", tag.name)
+				fe.In = tag.in
+				*errs = append(*errs, fe)
+				continue
+			default:
+				continue
+			}
+		}
+
+		if err := setField(fv, tag, raw); err != nil {
+			var fe *FieldError
+			if !errors.As(err, &fe) {
+				return errors.Wrapf(err, "binding field %s", sf.Name)
+			}
+			fe.In, fe.Rule = tag.in, RuleType
+			*errs = append(*errs, fe)
+		}
+	}
+
+	return nil
+}
+
+type paramTag struct {
+	in, name string
+	def      string
+	layout   string
+	required bool
+}
+
+func lookupParamTag(sf reflect.StructField) (paramTag, bool) {
+	for _, in := range []string{InPath, InQuery, InHeader} {
+		value, ok := sf.Tag.Lookup(in)
+		if !ok {
+			continue
+		}
+
+		opts := strings.Split(value, ",")
+		tag := paramTag{in: in, name: opts[0], layout: time.RFC3339, required: in == InPath}
+		if tag.name == "" {
+			tag.name = sf.Name
+		}
+
+		for _, opt := range opts[1:] {
+			key, val, _ := strings.Cut(strings.TrimSpace(opt), "=")
+			switch key {
+			case "default":
+				tag.def = val
+			case "layout":
+				tag.layout = val
+			case "required":
+				tag.required = true
+			}
+		}
+
+		return tag, true
+	}
+
+	return paramTag{}, false
+}
+
+// values returns the raw values of the parameter and whether it was sent.
+func (t paramTag) values(r *http.Request) ([]string, bool) {
+	var vals []string
+	switch t.in {
+	case InPath:
+		v, ok := mux.Vars(r)[t.name]
+		if !ok {
+			return nil, false
+		}
+		vals = []string{v}
+	case InQuery:
+		vals = r.URL.Query()[t.name]
+	case InHeader:
+		vals = r.Header.Values(t.name)
+	}
+
+	if len(vals) == 0 || (len(vals) == 1 && vals[0] == "") {
+		return nil, false
+	}
+
+	return vals, true
+}
+
+func setField(fv reflect.Value, tag paramTag, raw []string) error {
+	switch {
+	case fv.Kind() == reflect.Slice && fv.Type() != uuidType:
+		var parts []string
+		for _, r := range raw {
+			parts = append(parts, strings.Split(r, ",")...)
+		}
+
+		s := reflect.MakeSlice(fv.Type(), len(parts), len(parts))
+		for i := range parts {
+			if err := setScalar(s.Index(i), tag, parts[i]); err != nil {
+				return err
+			}
+		}
+		fv.Set(s)
+
+		return nil
+	case fv.Kind() == reflect.Pointer:
+		p := reflect.New(fv.Type().Elem())
+		if err := setField(p.Elem(), tag, raw); err != nil {
+			return err
+		}
+		fv.Set(p)
+
+		return nil
+	default:
+		return setScalar(fv, tag, raw[0])
+	}
+}
+
+func setScalar(fv reflect.Value, tag paramTag, raw string) error {
+	var (
+		v   interface{}
+		err error
+	)
+
+	switch fv.Type() {
+	case uuidType:
+		v, err = parseUUID(tag.name, raw)
+	case timeType:
+		v, err = parseTime(tag.name, raw, tag.layout)
+	default:
+		switch fv.Kind() {
+		case reflect.String:
+			v = raw
+		case reflect.Bool:
+			v, err = parseBool(tag.name, raw)
+		case reflect.Int:
+			v, err = parseInt(tag.name, raw)
+		case reflect.Int64:
+			v, err = parseInt64(tag.name, raw)
+		default:
+			return errors.Errorf("unsupported type %s", fv.Type())
+		}
+	}
+
+	if err != nil {
+		return err
+	}
+
+	fv.Set(reflect.ValueOf(v).Convert(fv.Type()))
+
+	return nil
+}
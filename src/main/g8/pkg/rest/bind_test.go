@@ -0,0 +1,117 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Gympass/gcore/v3/gtest"
+	"github.com/gofrs/uuid"
+	"github.com/gorilla/mux"
+)
+
+type bindTestRequest struct {
+	UID    uuid.UUID   `path:"uid"`
+	Limit  int         `query:"limit,default=20"`
+	Active *bool       `query:"active"`
+	From   time.Time   `query:"from,layout=2006-01-02"`
+	IDs    []uuid.UUID `query:"ids"`
+	Tenant string      `header:"X-Tenant,required"`
+	Name   string      `json:"name"`
+}
+
+func TestBind(t *testing.T) {
+	const uid = "df7d4231-0c0e-4d80-a72d-98cb276ea754"
+
+	tt := []struct {
+		Name           string
+		URL            string
+		Vars           map[string]string
+		Header         map[string]string
+		Body           string
+		ExpectedFields []string
+		Check          func(t *testing.T, req bindTestRequest)
+	}{
+		{
+			Name:   "test bind every source with success",
+			URL:    "/demo?limit=5&active=true&from=2023-01-02&ids=" + uid + "," + uid,
+			Vars:   map[string]string{"uid": uid},
+			Header: map[string]string{"X-Tenant": "acme"},
+			Body:   `{"name":"demo"}`,
+			Check: func(t *testing.T, req bindTestRequest) {
+				if req.UID.String() != uid || req.Limit != 5 || req.Active == nil || !*req.Active {
+					t.Fatalf("unexpected binding %+v", req)
+				}
+				if req.From != time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC) || len(req.IDs) != 2 {
+					t.Fatalf("unexpected binding %+v", req)
+				}
+				if req.Tenant != "acme" || req.Name != "demo" {
+					t.Fatalf("unexpected binding %+v", req)
+				}
+			},
+		},
+		{
+			Name:   "test bind applies defaults and leaves optional fields empty",
+			URL:    "/demo",
+			Vars:   map[string]string{"uid": uid},
+			Header: map[string]string{"X-Tenant": "acme"},
+			Check: func(t *testing.T, req bindTestRequest) {
+				if req.Limit != 20 || req.Active != nil || req.IDs != nil {
+					t.Fatalf("unexpected binding %+v", req)
+				}
+			},
+		},
+		{
+			Name:           "test bind reports every invalid field",
+			URL:            "/demo?limit=abc&active=maybe&from=yesterday",
+			Vars:           map[string]string{"uid": "abc"},
+			ExpectedFields: []string{"uid", "limit", "active", "from", "X-Tenant"},
+		},
+	}
+
+	for _, testCase := range tt {
+		t.Run(testCase.Name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, testCase.URL, strings.NewReader(testCase.Body))
+			r = mux.SetURLVars(r, testCase.Vars)
+			for k, v := range testCase.Header {
+				r.Header.Set(k, v)
+			}
+
+			var req bindTestRequest
+			err := Bind(r, &req)
+
+			if len(testCase.ExpectedFields) == 0 {
+				gtest.AssertNil(t, err)
+				testCase.Check(t, req)
+				return
+			}
+
+			var fe FieldErrors
+			if !errors.As(err, &fe) {
+				t.Fatalf("Expected FieldErrors and got %v", err)
+			}
+
+			if len(fe) != len(testCase.ExpectedFields) {
+				t.Fatalf("Expected %d field errors and got %v", len(testCase.ExpectedFields), fe)
+			}
+
+			for i := range fe {
+				if fe[i].Field != testCase.ExpectedFields[i] {
+					t.Fatalf("Expected field %s and got %s", testCase.ExpectedFields[i], fe[i].Field)
+				}
+			}
+		})
+	}
+}
+
+func TestBindRejectsNonStructTarget(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/demo", nil)
+
+	var limit int
+	if err := Bind(r, &limit); !errors.Is(err, ErrBindTarget) {
+		t.Fatalf("Expected %v and got %v", ErrBindTarget, err)
+	}
+}
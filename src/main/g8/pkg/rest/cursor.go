@@ -0,0 +1,236 @@
+package rest
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/Gympass/gcore/v3/gerror"
+	"github.com/pkg/errors"
+)
+
+// Direction tells on which side of a cursor a page lies.
+type Direction string
+
+const (
+	// Forward pages hold the rows after the cursor.
+	Forward Direction = "next"
+	// Backward pages hold the rows before the cursor.
+	Backward Direction = "prev"
+
+	// ProblemTypeInvalidCursor identifies requests with a cursor that was
+	// not issued by this service or was tampered with.
+	ProblemTypeInvalidCursor = "urn:problem-type:invalid-cursor"
+)
+
+var (
+	// ErrInvalidCursor is returned when a cursor cannot be decoded.
+	ErrInvalidCursor = errors.New("invalid cursor")
+	// ErrMissingCursorKey is returned by NewCursors without a key.
+	ErrMissingCursorKey = errors.New("cursor key is empty")
+)
+
+func init() {
+	RegisterProblem(ErrInvalidCursor, ProblemType{
+		Type:   ProblemTypeInvalidCursor,
+		Title:  "Invalid cursor",
+		Status: http.StatusBadRequest,
+	})
+}
+
+// Cursor marks a position in a sorted listing.
+type Cursor struct {
+	// Keys are the sort keys of the listing, most significant first. A key
+	// prefixed with "-" is sorted in descending order.
+	Keys []string `json:"k"`
+	// Values are the sort key values of the row the cursor points to.
+	Values []string `json:"v"`
+	// Direction tells whether the cursor asks for the rows after or before
+	// the row it points to.
+	Direction Direction `json:"d"`
+}
+
+// Cursors encodes and decodes opaque cursors sealed with AES-GCM, so
+// clients can neither read nor forge them.
+type Cursors struct {
+	aead cipher.AEAD
+}
+
+// NewCursors returns Cursors sealed with a key derived from secret,
+// usually config.ServiceConfig.CursorKey.
+func NewCursors(secret string) (*Cursors, error) {
+	if secret == "" {
+		return nil, ErrMissingCursorKey
+	}
+
+	key := sha256.Sum256([]byte(secret))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Cursors{aead: aead}, nil
+}
+
+// Encode seals cur into an URL safe string.
+func (c *Cursors) Encode(cur Cursor) (string, error) {
+	plain, err := json.Marshal(cur)
+	if err != nil {
+		return "", errors.Wrap(err, "encoding cursor")
+	}
+
+	nonce := make([]byte, c.aead.NonceSize(), c.aead.NonceSize()+len(plain)+c.aead.Overhead())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", errors.Wrap(err, "generating cursor nonce")
+	}
+
+	return base64.RawURLEncoding.EncodeToString(c.aead.Seal(nonce, nonce, plain, nil)), nil
+}
+
+// Decode opens a cursor sealed by Encode. Anything else fails with a bad
+// request wrapping ErrInvalidCursor.
+func (c *Cursors) Decode(s string) (Cursor, error) {
+	var cur Cursor
+
+	sealed, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(sealed) < c.aead.NonceSize() {
+		return cur, invalidCursorErr()
+	}
+
+	nonce, box := sealed[:c.aead.NonceSize()], sealed[c.aead.NonceSize():]
+	plain, err := c.aead.Open(nil, nonce, box, nil)
+	if err != nil {
+		return cur, invalidCursorErr()
+	}
+
+	if err := json.Unmarshal(plain, &cur); err != nil || len(cur.Keys) != len(cur.Values) {
+		return cur, invalidCursorErr()
+	}
+
+	if cur.Direction != Forward && cur.Direction != Backward {
+		return cur, invalidCursorErr()
+	}
+
+	return cur, nil
+}
+
+func invalidCursorErr() error {
+	return gerror.NewBadRequest(ErrInvalidCursor).WithMessage("cursor is invalid")
+}
+
+// PageRequest holds the pagination parameters of a listing request.
+type PageRequest struct {
+	// Cursor is nil for the first page.
+	Cursor *Cursor
+	Limit  int
+}
+
+// ParsePage reads the cursor and limit query parameters. The limit falls
+// back to defaultLimit and must lie between 1 and maxLimit. Both are
+// reported together as FieldErrors when rejected.
+func (c *Cursors) ParsePage(r *http.Request, defaultLimit, maxLimit int) (PageRequest, error) {
+	var (
+		page PageRequest
+		errs FieldErrors
+	)
+
+	q := r.URL.Query()
+	page.Limit = defaultLimit
+
+	if s := q.Get("limit"); s != "" {
+		limit, err := parseInt("limit", s)
+		switch {
+		case err != nil:
+			errs = append(errs, ruleErr("limit", RuleType, "%s", err.Error()))
+		case limit < 1:
+			errs = append(errs, ruleErr("limit", RuleMin, "limit must be at least 1"))
+		case limit > maxLimit:
+			errs = append(errs, ruleErr("limit", RuleMax, "limit must be at most %d", maxLimit))
+		default:
+			page.Limit = limit
+		}
+	}
+
+	if s := q.Get("cursor"); s != "" {
+		cur, err := c.Decode(s)
+		if err != nil {
+			errs = append(errs, fieldErr(ErrInvalidCursor, "cursor", "cursor is invalid"))
+		} else {
+			page.Cursor = &cur
+		}
+	}
+
+	if len(errs) > 0 {
+		return page, gerror.NewBadRequest(errs).WithMessage(errs.Error())
+	}
+
+	return page, nil
+}
+
+// Page is the envelope of paginated listings.
+type Page struct {
+	Data interface{} `json:"data"`
+	// Next is the URL of the following page, if any.
+	Next string `json:"next,omitempty"`
+	// Prev is the URL of the preceding page, if any.
+	Prev string `json:"prev,omitempty"`
+}
+
+// SendPage sends data inside a Page envelope with links to the pages at
+// the next and prev cursors, which are nil at either end of the listing.
+// The links are also written as an RFC 8288 Link header.
+func (c *Cursors) SendPage(w http.ResponseWriter, r *http.Request, data interface{}, next, prev *Cursor) error {
+	var (
+		page  = Page{Data: data}
+		links []string
+		err   error
+	)
+
+	if next != nil {
+		if page.Next, err = c.pageURL(r, *next); err != nil {
+			return err
+		}
+		links = append(links, fmt.Sprintf(`<%s>; rel="next"`, page.Next))
+	}
+
+	if prev != nil {
+		if page.Prev, err = c.pageURL(r, *prev); err != nil {
+			return err
+		}
+		links = append(links, fmt.Sprintf(`<%s>; rel="prev"`, page.Prev))
+	}
+
+	if len(links) > 0 {
+		w.Header().Set("Link", strings.Join(links, ", "))
+	}
+
+	return Send(w, r, http.StatusOK, page)
+}
+
+// pageURL returns the request URL pointing at cur, keeping every other
+// query parameter.
+func (c *Cursors) pageURL(r *http.Request, cur Cursor) (string, error) {
+	s, err := c.Encode(cur)
+	if err != nil {
+		return "", err
+	}
+
+	u := *r.URL
+	q := u.Query()
+	q.Set("cursor", s)
+	u.RawQuery = q.Encode()
+
+	return u.RequestURI(), nil
+}
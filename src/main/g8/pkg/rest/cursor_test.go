@@ -0,0 +1,115 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Gympass/gcore/v3/gtest"
+)
+
+func TestCursorsRoundTrip(t *testing.T) {
+	c, err := NewCursors("test-key")
+	gtest.AssertNil(t, err)
+
+	cur := Cursor{Keys: []string{"-created_at", "id"}, Values: []string{"2023-05-01T10:00:00Z", "7"}, Direction: Forward}
+	s, err := c.Encode(cur)
+	gtest.AssertNil(t, err)
+
+	if strings.Contains(s, "created_at") {
+		t.Fatalf("Expected an opaque cursor and got %s", s)
+	}
+
+	got, err := c.Decode(s)
+	gtest.AssertNil(t, err)
+
+	if got.Values[1] != "7" || got.Keys[0] != "-created_at" || got.Direction != Forward {
+		t.Fatalf("Unexpected cursor %+v", got)
+	}
+}
+
+func TestCursorsRejectForgedCursors(t *testing.T) {
+	c, err := NewCursors("test-key")
+	gtest.AssertNil(t, err)
+
+	other, err := NewCursors("other-key")
+	gtest.AssertNil(t, err)
+
+	s, err := other.Encode(Cursor{Keys: []string{"id"}, Values: []string{"1"}, Direction: Forward})
+	gtest.AssertNil(t, err)
+
+	valid, err := c.Encode(Cursor{Keys: []string{"id"}, Values: []string{"1"}, Direction: Forward})
+	gtest.AssertNil(t, err)
+	tampered := []byte(valid)
+	tampered[len(tampered)/2] ^= 1
+
+	for _, s := range []string{s, string(tampered), "not-a-cursor", ""} {
+		if _, err := c.Decode(s); !errors.Is(err, ErrInvalidCursor) {
+			t.Fatalf("Expected %v for %q and got %v", ErrInvalidCursor, s, err)
+		}
+	}
+
+	if _, err := NewCursors(""); !errors.Is(err, ErrMissingCursorKey) {
+		t.Fatalf("Expected %v and got %v", ErrMissingCursorKey, err)
+	}
+}
+
+func TestParsePage(t *testing.T) {
+	c, err := NewCursors("test-key")
+	gtest.AssertNil(t, err)
+
+	page, err := c.ParsePage(httptest.NewRequest(http.MethodGet, "/v1/demo", nil), 20, 100)
+	gtest.AssertNil(t, err)
+	if page.Limit != 20 || page.Cursor != nil {
+		t.Fatalf("Unexpected page %+v", page)
+	}
+
+	_, err = c.ParsePage(httptest.NewRequest(http.MethodGet, "/v1/demo?limit=500", nil), 20, 100)
+	assertFieldErrors(t, err, []ExpectedFieldError{{"limit", RuleMax}})
+
+	_, err = c.ParsePage(httptest.NewRequest(http.MethodGet, "/v1/demo?limit=abc", nil), 20, 100)
+	assertFieldErrors(t, err, []ExpectedFieldError{{"limit", RuleType}})
+
+	_, err = c.ParsePage(httptest.NewRequest(http.MethodGet, "/v1/demo?limit=0&cursor=forged", nil), 20, 100)
+	assertFieldErrors(t, err, []ExpectedFieldError{{"limit", RuleMin}, {"cursor", ""}})
+}
+
+func TestSendPage(t *testing.T) {
+	c, err := NewCursors("test-key")
+	gtest.AssertNil(t, err)
+
+	r := httptest.NewRequest(http.MethodGet, "/v1/demo?limit=2&status=active", nil)
+	rr := httptest.NewRecorder()
+
+	next := &Cursor{Keys: []string{"id"}, Values: []string{"2"}, Direction: Forward}
+	gtest.AssertNil(t, c.SendPage(rr, r, []int{1, 2}, next, nil))
+
+	var page struct {
+		Data []int  `json:"data"`
+		Next string `json:"next"`
+		Prev string `json:"prev"`
+	}
+	gtest.AssertNil(t, json.NewDecoder(rr.Body).Decode(&page))
+
+	if len(page.Data) != 2 || page.Prev != "" || !strings.HasPrefix(page.Next, "/v1/demo?cursor=") {
+		t.Fatalf("Unexpected page %+v", page)
+	}
+
+	if !strings.Contains(page.Next, "limit=2") || !strings.Contains(page.Next, "status=active") {
+		t.Fatalf("Expected next link to keep the query and got %s", page.Next)
+	}
+
+	if link := rr.Header().Get("Link"); link != "<"+page.Next+`>; rel="next"` {
+		t.Fatalf("Unexpected Link header %s", link)
+	}
+
+	r = httptest.NewRequest(http.MethodGet, page.Next, nil)
+	got, err := c.ParsePage(r, 20, 100)
+	gtest.AssertNil(t, err)
+	if got.Limit != 2 || got.Cursor == nil || got.Cursor.Values[0] != "2" {
+		t.Fatalf("Unexpected page request %+v", got)
+	}
+}
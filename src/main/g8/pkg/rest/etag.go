@@ -0,0 +1,117 @@
+package rest
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// ProblemTypePreconditionFailed identifies writes whose If-Match header
+	// does not match the current entity tag of the resource.
+	ProblemTypePreconditionFailed = "urn:problem-type:precondition-failed"
+	// ProblemTypePreconditionRequired identifies writes sent without the
+	// If-Match header an endpoint requires.
+	ProblemTypePreconditionRequired = "urn:problem-type:precondition-required"
+)
+
+var (
+	// ErrPreconditionFailed is returned by IfMatch on a mismatch.
+	ErrPreconditionFailed = errors.New("resource was modified, fetch it again")
+	// ErrPreconditionRequired is returned by IfMatch when the header is
+	// required but missing.
+	ErrPreconditionRequired = errors.New("if-match header is required")
+)
+
+func init() {
+	RegisterProblem(ErrPreconditionFailed, ProblemType{
+		Type:   ProblemTypePreconditionFailed,
+		Title:  "Precondition failed",
+		Status: http.StatusPreconditionFailed,
+	})
+	RegisterProblem(ErrPreconditionRequired, ProblemType{
+		Type:   ProblemTypePreconditionRequired,
+		Title:  "Precondition required",
+		Status: http.StatusPreconditionRequired,
+	})
+}
+
+// ETagger is implemented by single resources carrying a version. Send and
+// SendJSON write their tag in the ETag header.
+type ETagger interface {
+	// ETag returns the quoted entity tag, see StrongETag.
+	ETag() string
+}
+
+// StrongETag quotes version as a strong entity tag.
+func StrongETag(version string) string {
+	return `"` + version + `"`
+}
+
+// IfMatch checks the If-Match header of r against etag, the current entity
+// tag of the resource about to be changed. It fails with
+// ErrPreconditionFailed when no listed tag matches, or with
+// ErrPreconditionRequired when the header is missing and required.
+// Weak tags never match, as RFC 9110 asks for a strong comparison.
+func IfMatch(r *http.Request, etag string, required bool) error {
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		if required {
+			return ErrPreconditionRequired
+		}
+		return nil
+	}
+
+	for _, tag := range splitETags(header) {
+		if tag == "*" || (tag == etag && !strings.HasPrefix(tag, "W/")) {
+			return nil
+		}
+	}
+
+	return ErrPreconditionFailed
+}
+
+// NotModified tells whether the If-None-Match header of r lists etag, in
+// which case a GET can be answered with 304. Weak and strong tags compare
+// by their opaque part.
+func NotModified(r *http.Request, etag string) bool {
+	if etag == "" {
+		return false
+	}
+
+	for _, tag := range splitETags(r.Header.Get("If-None-Match")) {
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// writeETag sets the ETag header for payloads implementing ETagger and
+// returns the tag.
+func writeETag(w http.ResponseWriter, payload interface{}) string {
+	e, ok := payload.(ETagger)
+	if !ok {
+		return ""
+	}
+
+	etag := e.ETag()
+	if etag != "" {
+		w.Header().Set("ETag", etag)
+	}
+
+	return etag
+}
+
+func splitETags(header string) []string {
+	var tags []string
+	for _, tag := range strings.Split(header, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+
+	return tags
+}
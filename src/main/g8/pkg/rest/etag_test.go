@@ -0,0 +1,98 @@
+package rest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+type etagTestEntity struct {
+	ID      string `json:"id"`
+	Version string `json:"version"`
+}
+
+func (e etagTestEntity) ETag() string { return StrongETag(e.Version) }
+
+func TestIfMatch(t *testing.T) {
+	tt := []struct {
+		Name        string
+		IfMatch     string
+		Required    bool
+		ExpectedErr error
+	}{
+		{Name: "test missing optional header"},
+		{Name: "test missing required header", Required: true, ExpectedErr: ErrPreconditionRequired},
+		{Name: "test matching tag", IfMatch: `"2"`, Required: true},
+		{Name: "test matching tag in list", IfMatch: `"1", "2"`},
+		{Name: "test wildcard", IfMatch: `*`},
+		{Name: "test stale tag", IfMatch: `"1"`, ExpectedErr: ErrPreconditionFailed},
+		{Name: "test weak tag never matches", IfMatch: `W/"2"`, ExpectedErr: ErrPreconditionFailed},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPut, "/v1/demo/1", nil)
+			if tc.IfMatch != "" {
+				r.Header.Set("If-Match", tc.IfMatch)
+			}
+
+			if err := IfMatch(r, `"2"`, tc.Required); !errors.Is(err, tc.ExpectedErr) {
+				t.Fatalf("IfMatch() error = %v, want %v", err, tc.ExpectedErr)
+			}
+		})
+	}
+}
+
+func TestSendETag(t *testing.T) {
+	entity := etagTestEntity{ID: "1", Version: "2"}
+
+	tt := []struct {
+		Name           string
+		Method         string
+		IfNoneMatch    string
+		Payload        interface{}
+		ExpectedStatus int
+		ExpectedETag   string
+	}{
+		{Name: "test etag on entity", Method: http.MethodGet, Payload: entity, ExpectedStatus: http.StatusOK, ExpectedETag: `"2"`},
+		{Name: "test etag on entity pointer", Method: http.MethodGet, Payload: &entity, ExpectedStatus: http.StatusOK, ExpectedETag: `"2"`},
+		{Name: "test no etag on listing", Method: http.MethodGet, Payload: []etagTestEntity{entity}, ExpectedStatus: http.StatusOK},
+		{Name: "test not modified", Method: http.MethodGet, IfNoneMatch: `"1", "2"`, Payload: entity, ExpectedStatus: http.StatusNotModified, ExpectedETag: `"2"`},
+		{Name: "test not modified weak", Method: http.MethodGet, IfNoneMatch: `W/"2"`, Payload: entity, ExpectedStatus: http.StatusNotModified, ExpectedETag: `"2"`},
+		{Name: "test modified", Method: http.MethodGet, IfNoneMatch: `"1"`, Payload: entity, ExpectedStatus: http.StatusOK, ExpectedETag: `"2"`},
+		{Name: "test if-none-match ignored on writes", Method: http.MethodPut, IfNoneMatch: `"2"`, Payload: entity, ExpectedStatus: http.StatusOK, ExpectedETag: `"2"`},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			r := httptest.NewRequest(tc.Method, "/v1/demo/1", nil)
+			if tc.IfNoneMatch != "" {
+				r.Header.Set("If-None-Match", tc.IfNoneMatch)
+			}
+			w := httptest.NewRecorder()
+
+			if err := Send(w, r, http.StatusOK, tc.Payload); err != nil {
+				t.Fatalf("Send() error = %v", err)
+			}
+			if w.Code != tc.ExpectedStatus {
+				t.Fatalf("Send() status = %d, want %d", w.Code, tc.ExpectedStatus)
+			}
+			if got := w.Header().Get("ETag"); got != tc.ExpectedETag {
+				t.Fatalf("Send() etag = %q, want %q", got, tc.ExpectedETag)
+			}
+			if w.Code == http.StatusNotModified && w.Body.Len() > 0 {
+				t.Fatalf("Send() body = %s, want none", w.Body)
+			}
+		})
+	}
+
+	w := httptest.NewRecorder()
+	if err := SendJSON(w, entity); err != nil {
+		t.Fatalf("SendJSON() error = %v", err)
+	}
+	if got := w.Header().Get("ETag"); got != `"2"` {
+		t.Fatalf("SendJSON() etag = %q, want %q", got, `"2"`)
+	}
+}
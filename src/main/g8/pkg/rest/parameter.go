@@ -15,8 +15,10 @@ import (
 )
 
 var (
+	errParamMissing = errors.New("param is missing")
+
 	// ErrMissingParameter is returned when parameter key is not found on Query Parameters.
-	ErrMissingParameter = gerror.NewBadRequest(errors.New("param is missing"))
+	ErrMissingParameter = gerror.NewBadRequest(errParamMissing)
 )
 
 // GetInt gets a query or path parameter as integer.
@@ -66,9 +68,9 @@ func GetUUID(r *http.Request, param string) (uuid.UUID, error) {
 		return uuid.Nil, err
 	}
 
-	id, err := uuid.FromString(p)
+	id, err := parseUUID(param, p)
 	if err != nil {
-		return uuid.Nil, badRequestErr(err, "%s must be an UUIDv4", param)
+		return uuid.Nil, badRequest(err)
 	}
 
 	return id, nil
@@ -98,9 +100,9 @@ func GetBool(r *http.Request, param string) (bool, error) {
 		return false, err
 	}
 
-	b, err := strconv.ParseBool(p)
+	b, err := parseBool(param, p)
 	if err != nil {
-		return false, badRequestErr(err, "%s must be a bool", param)
+		return false, badRequest(err)
 	}
 
 	return b, nil
@@ -170,32 +172,81 @@ func getTime(r *http.Request, param, timeLayout string) (time.Time, error) {
 		return empty, err
 	}
 
-	t, err := time.Parse(timeLayout, p)
+	t, err := parseTime(param, p, timeLayout)
 	if err != nil {
-		return empty, badRequestErr(err, "%s must be a valid data format (%s)", param, timeLayout)
+		return empty, badRequest(err)
 	}
 
 	return t, nil
 }
 
 func atoi(param, value string) (int, error) {
-	v, err := strconv.Atoi(value)
+	v, err := parseInt(param, value)
 	if err != nil {
-		return 0, badRequestErr(err, "%s must be an int", param)
+		return 0, badRequest(err)
 	}
 
 	return v, nil
 }
 
 func atoi64(param, value string) (int64, error) {
+	v, err := parseInt64(param, value)
+	if err != nil {
+		return 0, badRequest(err)
+	}
+
+	return v, nil
+}
+
+// The parse* functions convert a raw parameter value and are shared by the
+// Get* helpers and Bind. They fail with a *FieldError so callers can either
+// report it alone or collect it with the errors of other fields.
+
+func parseInt(param, value string) (int, error) {
+	v, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fieldErr(err, param, "%s must be an int", param)
+	}
+
+	return v, nil
+}
+
+func parseInt64(param, value string) (int64, error) {
 	v, err := strconv.ParseInt(value, 10, 64)
 	if err != nil {
-		return 0, badRequestErr(err, "%s must be an int64", param)
+		return 0, fieldErr(err, param, "%s must be an int64", param)
 	}
 
 	return v, nil
 }
 
+func parseBool(param, value string) (bool, error) {
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fieldErr(err, param, "%s must be a bool", param)
+	}
+
+	return b, nil
+}
+
+func parseUUID(param, value string) (uuid.UUID, error) {
+	id, err := uuid.FromString(value)
+	if err != nil {
+		return uuid.Nil, fieldErr(err, param, "%s must be an UUIDv4", param)
+	}
+
+	return id, nil
+}
+
+func parseTime(param, value, timeLayout string) (time.Time, error) {
+	t, err := time.Parse(timeLayout, value)
+	if err != nil {
+		return time.Time{}, fieldErr(err, param, "%s must be a valid data format (%s)", param, timeLayout)
+	}
+
+	return t, nil
+}
+
 func missingParameterErr(param string) error {
 	return ErrMissingParameter.WithMessage(fmt.Sprintf("missing %s parameter", param))
 }
@@ -204,6 +255,17 @@ func badRequestErr(err error, format string, any ...interface{}) error {
 	return gerror.NewBadRequest(err).WithMessage(fmt.Sprintf(format, any...))
 }
 
+// badRequest turns a *FieldError returned by a parse* function into the
+// same bad request the Get* helpers have always returned.
+func badRequest(err error) error {
+	var fe *FieldError
+	if errors.As(err, &fe) {
+		return badRequestErr(fe.err, "%s", fe.Message)
+	}
+
+	return gerror.NewBadRequest(err).WithMessage(err.Error())
+}
+
 func getParam(r *http.Request, param string) (string, error) {
 	v := r.URL.Query().Get(param)
 	if v == "" {
@@ -0,0 +1,126 @@
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+
+	"github.com/Gympass/gcore/v3/gerror"
+	"github.com/pkg/errors"
+)
+
+const (
+	// ContentTypeMergePatch is the media type of RFC 7396 JSON merge patches.
+	ContentTypeMergePatch = "application/merge-patch+json"
+
+	// ProblemTypeUnsupportedMediaType identifies requests whose body is not
+	// in a media type the endpoint accepts.
+	ProblemTypeUnsupportedMediaType = "urn:problem-type:unsupported-media-type"
+)
+
+// ErrUnsupportedMediaType is returned by BindMergePatch for bodies that are
+// not merge patches.
+var ErrUnsupportedMediaType = errors.New("unsupported media type")
+
+func init() {
+	RegisterProblem(ErrUnsupportedMediaType, ProblemType{
+		Type:   ProblemTypeUnsupportedMediaType,
+		Title:  "Unsupported media type",
+		Status: http.StatusUnsupportedMediaType,
+	})
+}
+
+// MergePatch applies the RFC 7396 merge patch to the JSON document doc:
+// members of patch objects replace those of doc, recursively, and null
+// members remove them. Any patch that is not an object replaces the whole
+// document.
+func MergePatch(doc, patch []byte) ([]byte, error) {
+	var p interface{}
+	if err := json.Unmarshal(patch, &p); err != nil {
+		return nil, errors.Wrap(err, "decoding merge patch")
+	}
+
+	var d interface{}
+	if len(bytes.TrimSpace(doc)) > 0 {
+		if err := json.Unmarshal(doc, &d); err != nil {
+			return nil, errors.Wrap(err, "decoding patched document")
+		}
+	}
+
+	return json.Marshal(mergeValue(d, p))
+}
+
+func mergeValue(doc, patch interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	d, ok := doc.(map[string]interface{})
+	if !ok {
+		d = map[string]interface{}{}
+	}
+
+	for k, v := range p {
+		if v == nil {
+			delete(d, k)
+			continue
+		}
+		d[k] = mergeValue(d[k], v)
+	}
+
+	return d
+}
+
+// BindMergePatch applies the merge patch in the body of r to the struct
+// pointed by dst, which holds the current state of the resource:
+//
+//	in := current.Input()
+//	if err := rest.BindMergePatch(r, &in); err != nil {
+//		return err
+//	}
+//
+// The body must be sent as application/merge-patch+json, or
+// application/json for clients that cannot set it. Members removed by the
+// patch leave their field zeroed. The result is checked against the
+// validate tags of dst, so invalid or rule breaking values are reported as
+// Bind does.
+func BindMergePatch(r *http.Request, dst interface{}) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return errors.Wrapf(ErrBindTarget, "got %T", dst)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != ContentTypeMergePatch && mediaType != ContentTypeJSON {
+		return errors.Wrapf(ErrUnsupportedMediaType, "got %q, want %s", mediaType, ContentTypeMergePatch)
+	}
+
+	var patch []byte
+	if r.Body != nil {
+		var err error
+		if patch, err = io.ReadAll(r.Body); err != nil {
+			return errors.Wrap(err, "reading merge patch")
+		}
+	}
+
+	doc, err := json.Marshal(dst)
+	if err != nil {
+		return errors.Wrap(err, "encoding patched document")
+	}
+
+	merged, err := MergePatch(doc, patch)
+	if err != nil {
+		return gerror.NewBadRequest(err).WithMessage("bad json format")
+	}
+
+	v.Elem().Set(reflect.Zero(v.Elem().Type()))
+	if err := json.Unmarshal(merged, dst); err != nil {
+		return gerror.NewBadRequest(err).WithMessage("bad json format")
+	}
+
+	return Validate(dst)
+}
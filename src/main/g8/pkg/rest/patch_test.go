@@ -0,0 +1,124 @@
+package rest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestMergePatch(t *testing.T) {
+	// Cases from RFC 7396, appendix A.
+	tt := []struct {
+		Name     string
+		Doc      string
+		Patch    string
+		Expected string
+	}{
+		{Name: "test replace member", Doc: `{"a":"b"}`, Patch: `{"a":"c"}`, Expected: `{"a":"c"}`},
+		{Name: "test add member", Doc: `{"a":"b"}`, Patch: `{"b":"c"}`, Expected: `{"a":"b","b":"c"}`},
+		{Name: "test remove member", Doc: `{"a":"b"}`, Patch: `{"a":null}`, Expected: `{}`},
+		{Name: "test nested object", Doc: `{"a":{"b":"c"}}`, Patch: `{"a":{"b":"d","c":null}}`, Expected: `{"a":{"b":"d"}}`},
+		{Name: "test arrays are replaced", Doc: `{"a":[{"b":"c"}]}`, Patch: `{"a":[1]}`, Expected: `{"a":[1]}`},
+		{Name: "test non object patch", Doc: `{"a":"foo"}`, Patch: `["c"]`, Expected: `["c"]`},
+		{Name: "test null patch", Doc: `{"a":"foo"}`, Patch: `null`, Expected: `null`},
+		{Name: "test object over scalar", Doc: `{"e":null}`, Patch: `{"a":1}`, Expected: `{"a":1,"e":null}`},
+		{Name: "test empty document", Doc: ``, Patch: `{"a":{"bb":{"ccc":null}}}`, Expected: `{"a":{"bb":{}}}`},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			got, err := MergePatch([]byte(tc.Doc), []byte(tc.Patch))
+			if err != nil {
+				t.Fatalf("MergePatch() error = %v", err)
+			}
+			if string(got) != tc.Expected {
+				t.Fatalf("MergePatch() = %s, want %s", got, tc.Expected)
+			}
+		})
+	}
+}
+
+func TestBindMergePatch(t *testing.T) {
+	type demo struct {
+		Name   string   `json:"name" validate:"required"`
+		Status string   `json:"status" validate:"omitempty,oneof=active|inactive"`
+		Tags   []string `json:"tags,omitempty"`
+	}
+
+	current := demo{Name: "demo", Status: "active", Tags: []string{"a"}}
+
+	tt := []struct {
+		Name           string
+		ContentType    string
+		Body           string
+		Expected       demo
+		ExpectedErr    error
+		ExpectedStatus int
+	}{
+		{
+			Name:        "test merge patch",
+			ContentType: ContentTypeMergePatch,
+			Body:        `{"status":"inactive","tags":null}`,
+			Expected:    demo{Name: "demo", Status: "inactive"},
+		},
+		{
+			Name:        "test json content type",
+			ContentType: ContentTypeJSON + "; charset=utf-8",
+			Body:        `{"name":"renamed"}`,
+			Expected:    demo{Name: "renamed", Status: "active", Tags: []string{"a"}},
+		},
+		{
+			Name:        "test unsupported media type",
+			ContentType: "text/plain",
+			Body:        `{"name":"renamed"}`,
+			ExpectedErr: ErrUnsupportedMediaType,
+		},
+		{
+			Name:           "test invalid json",
+			ContentType:    ContentTypeMergePatch,
+			Body:           `{"name":`,
+			ExpectedStatus: http.StatusBadRequest,
+		},
+		{
+			Name:           "test type mismatch",
+			ContentType:    ContentTypeMergePatch,
+			Body:           `{"name":1}`,
+			ExpectedStatus: http.StatusBadRequest,
+		},
+		{
+			Name:           "test removed required member",
+			ContentType:    ContentTypeMergePatch,
+			Body:           `{"name":null}`,
+			ExpectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPatch, "/v1/demo/1", strings.NewReader(tc.Body))
+			r.Header.Set("Content-Type", tc.ContentType)
+
+			got := current
+			got.Tags = append([]string(nil), current.Tags...)
+			err := BindMergePatch(r, &got)
+
+			switch {
+			case tc.ExpectedErr != nil:
+				if !errors.Is(err, tc.ExpectedErr) {
+					t.Fatalf("BindMergePatch() error = %v, want %v", err, tc.ExpectedErr)
+				}
+			case tc.ExpectedStatus != 0:
+				if status, _ := statusOf(err); status != tc.ExpectedStatus {
+					t.Fatalf("BindMergePatch() status = %d, want %d (%v)", status, tc.ExpectedStatus, err)
+				}
+			case err != nil:
+				t.Fatalf("BindMergePatch() error = %v", err)
+			case got.Name != tc.Expected.Name || got.Status != tc.Expected.Status || strings.Join(got.Tags, ",") != strings.Join(tc.Expected.Tags, ","):
+				t.Fatalf("BindMergePatch() = %+v, want %+v", got, tc.Expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,234 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"github.com/Gympass/gcore/v3/gcontext"
+	"github.com/Gympass/gcore/v3/ghandler"
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// ContentTypeProblemJSON is the media type of RFC 7807 problem details.
+	ContentTypeProblemJSON = "application/problem+json"
+	// ContentTypeJSON is the media type of plain JSON documents.
+	ContentTypeJSON = "application/json"
+
+	// ProblemTypeBlank is the RFC 7807 type of problems with no further
+	// semantics than their HTTP status.
+	ProblemTypeBlank = "about:blank"
+	// ProblemTypeInvalidRequest identifies requests rejected by Bind or Validate.
+	ProblemTypeInvalidRequest = "urn:problem-type:invalid-request"
+	// ProblemTypeMissingParameter identifies requests missing a parameter.
+	ProblemTypeMissingParameter = "urn:problem-type:missing-parameter"
+)
+
+// Problem is an RFC 7807 problem details object.
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	// Errors lists the fields rejected by Bind or Validate.
+	Errors FieldErrors `json:"errors,omitempty"`
+	// TraceID is the identifier of the active trace, if any.
+	TraceID string `json:"trace_id,omitempty"`
+}
+
+// ProblemType describes a kind of error clients can tell apart by its Type.
+type ProblemType struct {
+	Type   string
+	Title  string
+	Status int
+}
+
+type problemEntry struct {
+	target error
+	pt     ProblemType
+}
+
+var problems = struct {
+	sync.RWMutex
+	entries []problemEntry
+}{
+	entries: []problemEntry{
+		{
+			target: errParamMissing,
+			pt: ProblemType{
+				Type:   ProblemTypeMissingParameter,
+				Title:  "Missing parameter",
+				Status: http.StatusBadRequest,
+			},
+		},
+	},
+}
+
+// RegisterProblem maps every error matching target (see errors.Is) to pt.
+// Errors matching several targets use the first registered one.
+func RegisterProblem(target error, pt ProblemType) {
+	problems.Lock()
+	defer problems.Unlock()
+
+	problems.entries = append(problems.entries, problemEntry{target: target, pt: pt})
+}
+
+// NewProblem describes err as a Problem for the request r.
+//
+// Errors carrying FieldErrors become invalid request problems listing each
+// field, registered errors take their ProblemType and any other error is an
+// about:blank problem. The status of a gerror wrapping err always wins, as
+// it does for the middleware, so a problem only falls back to the status of
+// its type, or 500, for other errors. The detail of server errors is only
+// kept for gerror values, whose message was written for clients.
+func NewProblem(r *http.Request, err error) Problem {
+	status, fromGError := statusOf(err)
+	p := Problem{
+		Type:     ProblemTypeBlank,
+		Title:    http.StatusText(status),
+		Status:   status,
+		Instance: r.URL.Path,
+		TraceID:  traceID(r),
+	}
+
+	if errors.As(err, &p.Errors) {
+		p.Type, p.Title, p.Status = ProblemTypeInvalidRequest, "Invalid request", http.StatusBadRequest
+	} else if pt, ok := registered(err); ok {
+		p.Type, p.Title, p.Status = pt.Type, pt.Title, pt.Status
+	}
+
+	if fromGError {
+		p.Status = status
+	}
+
+	if fromGError || p.Status < http.StatusInternalServerError {
+		p.Detail = err.Error()
+	}
+
+	return p
+}
+
+// registered returns the ProblemType of the first registered target err
+// matches.
+func registered(err error) (ProblemType, bool) {
+	problems.RLock()
+	defer problems.RUnlock()
+
+	for _, e := range problems.entries {
+		if errors.Is(err, e.target) {
+			return e.pt, true
+		}
+	}
+
+	return ProblemType{}, false
+}
+
+// WriteProblem writes err to w as application/problem+json.
+func WriteProblem(w http.ResponseWriter, r *http.Request, err error) {
+	p := NewProblem(r, err)
+
+	w.Header().Set("Content-Type", ContentTypeProblemJSON)
+	w.WriteHeader(p.Status)
+
+	if err := json.NewEncoder(w).Encode(p); err != nil {
+		gcontext.AddError(r.Context(), errors.Wrap(err, "encoding problem"))
+	}
+}
+
+// WriteError writes err to w in the format the request prefers: problem
+// details, or the former ghandler.HTTPError body for clients preferring
+// application/json. Responses written outside of Problems, such as those of
+// middlewares, use it to honour the same negotiation.
+func WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	if acceptsProblem(r) {
+		WriteProblem(w, r, err)
+
+		return
+	}
+
+	writeHTTPError(w, r, err)
+}
+
+// Problems wraps a handler of a middleware.GMiddlewareHandlerError so that
+// the errors it returns are written as problem details:
+//
+//	mw.HandlerError(rest.Problems(handler.Demo))
+//
+// Clients opt into the former ghandler.HTTPError body by preferring
+// application/json over application/problem+json in their Accept header,
+// in which case gerror values and unexpected errors are left for the
+// middleware to write. The middleware only knows the status of gerror
+// values, so the other registered errors are written here with the status
+// of their ProblemType.
+func Problems(next func(http.ResponseWriter, *http.Request) error) func(http.ResponseWriter, *http.Request) error {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		err := next(w, r)
+		if err == nil {
+			return nil
+		}
+
+		if !acceptsProblem(r) {
+			if _, fromGError := statusOf(err); fromGError {
+				return err
+			}
+			if _, ok := registered(err); !ok {
+				return err
+			}
+		}
+
+		gcontext.AddError(r.Context(), err)
+		WriteError(w, r, err)
+
+		return nil
+	}
+}
+
+// writeHTTPError writes err to w as a ghandler.HTTPError, with the status
+// of its problem.
+func writeHTTPError(w http.ResponseWriter, r *http.Request, err error) {
+	p := NewProblem(r, err)
+
+	msg := p.Detail
+	if msg == "" {
+		msg = p.Title
+	}
+
+	w.Header().Set("Content-Type", ContentTypeJSON)
+	w.WriteHeader(p.Status)
+
+	if err := json.NewEncoder(w).Encode(ghandler.HTTPError{Error: msg}); err != nil {
+		gcontext.AddError(r.Context(), errors.Wrap(err, "encoding error"))
+	}
+}
+
+func acceptsProblem(r *http.Request) bool {
+	return negotiate(r, ContentTypeProblemJSON, ContentTypeJSON) != ContentTypeJSON
+}
+
+// statusCoder is implemented by gerror values.
+type statusCoder interface {
+	StatusCode() int
+}
+
+// statusOf returns the HTTP status carried by err and whether it came from
+// a gerror.
+func statusOf(err error) (int, bool) {
+	var sc statusCoder
+	if errors.As(err, &sc) {
+		return sc.StatusCode(), true
+	}
+
+	return http.StatusInternalServerError, false
+}
+
+func traceID(r *http.Request) string {
+	sc := trace.SpanContextFromContext(r.Context())
+	if !sc.IsValid() {
+		return ""
+	}
+
+	return sc.TraceID().String()
+}
@@ -0,0 +1,233 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Gympass/gcore/v3/gerror"
+	"github.com/pkg/errors"
+)
+
+var (
+	errProblemTest            = errors.New("demo not found")
+	errProblemTestUnavailable = errors.New("demo store unavailable")
+)
+
+func init() {
+	RegisterProblem(errProblemTest, ProblemType{
+		Type:   "urn:problem-type:test:not-found",
+		Title:  "Demo not found",
+		Status: http.StatusNotFound,
+	})
+	RegisterProblem(errProblemTestUnavailable, ProblemType{
+		Type:   "urn:problem-type:test:unavailable",
+		Title:  "Demo store unavailable",
+		Status: http.StatusServiceUnavailable,
+	})
+}
+
+func TestProblems(t *testing.T) {
+	tt := []struct {
+		Name           string
+		Accept         string
+		Err            error
+		ExpectedStatus int
+		ExpectedType   string
+		ExpectedDetail string
+		ExpectedFields int
+	}{
+		{
+			Name:           "test missing parameter problem",
+			Err:            missingParameterErr("uid"),
+			ExpectedStatus: http.StatusBadRequest,
+			ExpectedType:   ProblemTypeMissingParameter,
+			ExpectedDetail: "missing uid parameter",
+		},
+		{
+			Name:           "test invalid request problem lists fields",
+			Accept:         "application/problem+json, application/json;q=0.9",
+			Err:            gerror.NewBadRequest(FieldErrors{fieldErr(nil, "a", "a is bad"), fieldErr(nil, "b", "b is bad")}),
+			ExpectedStatus: http.StatusBadRequest,
+			ExpectedType:   ProblemTypeInvalidRequest,
+			ExpectedFields: 2,
+		},
+		{
+			Name:           "test registered problem",
+			Accept:         "*/*",
+			Err:            errors.Wrap(errProblemTest, "no demo with this id"),
+			ExpectedStatus: http.StatusNotFound,
+			ExpectedType:   "urn:problem-type:test:not-found",
+			ExpectedDetail: "no demo with this id: demo not found",
+		},
+		{
+			Name:           "test gerror status wins over registered problem",
+			Accept:         "*/*",
+			Err:            gerror.NewBadRequest(errProblemTest).WithMessage("no demo with this id"),
+			ExpectedStatus: http.StatusBadRequest,
+			ExpectedType:   "urn:problem-type:test:not-found",
+			ExpectedDetail: "no demo with this id",
+		},
+		{
+			Name:           "test unknown error hides its detail",
+			Err:            errors.New("pq: connection refused"),
+			ExpectedStatus: http.StatusInternalServerError,
+			ExpectedType:   ProblemTypeBlank,
+		},
+	}
+
+	for _, testCase := range tt {
+		t.Run(testCase.Name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/v1/demo", nil)
+			r.Header.Set("Accept", testCase.Accept)
+			rr := httptest.NewRecorder()
+
+			err := Problems(func(http.ResponseWriter, *http.Request) error {
+				return testCase.Err
+			})(rr, r)
+			if err != nil {
+				t.Fatalf("Expected error to be written and got %v", err)
+			}
+
+			if rr.Code != testCase.ExpectedStatus {
+				t.Fatalf("Expected status %d and got %d", testCase.ExpectedStatus, rr.Code)
+			}
+
+			if ct := rr.Header().Get("Content-Type"); ct != ContentTypeProblemJSON {
+				t.Fatalf("Expected content type %s and got %s", ContentTypeProblemJSON, ct)
+			}
+
+			var p Problem
+			if err := json.NewDecoder(rr.Body).Decode(&p); err != nil {
+				t.Fatal(err)
+			}
+
+			if p.Type != testCase.ExpectedType || p.Status != testCase.ExpectedStatus || p.Instance != "/v1/demo" {
+				t.Fatalf("Unexpected problem %+v", p)
+			}
+
+			if p.Detail != testCase.ExpectedDetail && testCase.ExpectedFields == 0 {
+				t.Fatalf("Expected detail %q and got %q", testCase.ExpectedDetail, p.Detail)
+			}
+
+			if len(p.Errors) != testCase.ExpectedFields {
+				t.Fatalf("Expected %d field errors and got %v", testCase.ExpectedFields, p.Errors)
+			}
+		})
+	}
+}
+
+func TestProblemsLegacyFormat(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/v1/demo", nil)
+	r.Header.Set("Accept", "application/json")
+	rr := httptest.NewRecorder()
+
+	expected := missingParameterErr("uid")
+	err := Problems(func(http.ResponseWriter, *http.Request) error {
+		return expected
+	})(rr, r)
+
+	if err != expected {
+		t.Fatalf("Expected error to be left to the middleware and got %v", err)
+	}
+
+	if rr.Body.Len() != 0 {
+		t.Fatalf("Expected no body and got %s", rr.Body.String())
+	}
+}
+
+func TestProblemsLegacyRegistered(t *testing.T) {
+	tt := []struct {
+		Name           string
+		Err            error
+		ExpectedStatus int
+		ExpectedError  string
+	}{
+		{
+			Name:           "test registered error keeps its status",
+			Err:            errors.Wrap(errProblemTest, "no demo with this id"),
+			ExpectedStatus: http.StatusNotFound,
+			ExpectedError:  "no demo with this id: demo not found",
+		},
+		{
+			Name:           "test registered server error hides its detail",
+			Err:            errors.Wrap(errProblemTestUnavailable, "querying demos"),
+			ExpectedStatus: http.StatusServiceUnavailable,
+			ExpectedError:  "Demo store unavailable",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/v1/demo", nil)
+			r.Header.Set("Accept", "application/json")
+			rr := httptest.NewRecorder()
+
+			err := Problems(func(http.ResponseWriter, *http.Request) error {
+				return tc.Err
+			})(rr, r)
+			if err != nil {
+				t.Fatalf("Expected error to be written and got %v", err)
+			}
+
+			if rr.Code != tc.ExpectedStatus {
+				t.Fatalf("Expected status %d and got %d", tc.ExpectedStatus, rr.Code)
+			}
+			if ct := rr.Header().Get("Content-Type"); ct != ContentTypeJSON {
+				t.Fatalf("Expected content type %s and got %s", ContentTypeJSON, ct)
+			}
+
+			var body struct {
+				Error string `json:"error"`
+			}
+			if err := json.NewDecoder(rr.Body).Decode(&body); err != nil || body.Error != tc.ExpectedError {
+				t.Fatalf("Expected error %q and got %q (%v)", tc.ExpectedError, body.Error, err)
+			}
+		})
+	}
+}
+
+func TestNewProblemRegisteredDetail(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/v1/demo", nil)
+
+	p := NewProblem(r, errors.Wrap(errProblemTest, "no demo with this id"))
+	if p.Status != http.StatusNotFound || p.Detail != "no demo with this id: demo not found" {
+		t.Fatalf("Unexpected problem %+v", p)
+	}
+}
+
+func TestWriteError(t *testing.T) {
+	tt := []struct {
+		Name                string
+		Accept              string
+		ExpectedContentType string
+	}{
+		{
+			Name:                "test problem details by default",
+			ExpectedContentType: ContentTypeProblemJSON,
+		},
+		{
+			Name:                "test legacy format when preferred",
+			Accept:              "application/json",
+			ExpectedContentType: ContentTypeJSON,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/v1/demo", nil)
+			r.Header.Set("Accept", tc.Accept)
+			rr := httptest.NewRecorder()
+
+			WriteError(rr, r, errProblemTestUnavailable)
+
+			if rr.Code != http.StatusServiceUnavailable {
+				t.Fatalf("Expected status %d and got %d", http.StatusServiceUnavailable, rr.Code)
+			}
+			if ct := rr.Header().Get("Content-Type"); ct != tc.ExpectedContentType {
+				t.Fatalf("Expected content type %s and got %s", tc.ExpectedContentType, ct)
+			}
+		})
+	}
+}
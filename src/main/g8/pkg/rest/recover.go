@@ -0,0 +1,133 @@
+package rest
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/Gympass/gcore/v3/gcontext"
+	"github.com/Gympass/gcore/v3/glog"
+	"github.com/gorilla/mux"
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/recorder"
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/requestid"
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ErrPanic is answered, as a 500 problem without detail, to the requests
+// whose handler panicked.
+var ErrPanic = errors.New("handler panicked")
+
+// PanicCounter counts the panics recovered by route, such as
+// *metrics.Metrics.
+type PanicCounter interface {
+	Panicked(method, route string)
+}
+
+// RecoveryConfig used by NewRecovery.
+type RecoveryConfig struct {
+	Logger glog.Logger
+	// Counter counts the recovered panics, optional.
+	Counter PanicCounter
+	// Repanic panics again once the panic is reported, so that tests
+	// fail on it instead of reading a 500.
+	Repanic bool
+}
+
+// Recovery turns the panics of handlers into 500 problems.
+type Recovery struct {
+	cfg RecoveryConfig
+}
+
+// NewRecovery creates a Recovery based on configuration properties.
+func NewRecovery(c RecoveryConfig) *Recovery {
+	if c.Logger == nil {
+		c.Logger = glog.Noop()
+	}
+
+	return &Recovery{cfg: c}
+}
+
+// Middleware recovers the panics of next, logging the panic value, its
+// stack, the route and the request id, recording them on the active span
+// and answering a 500 unless the response was already started, see
+// WriteError.
+// Add it with Router.Use, after the tracing and metrics middlewares.
+// http.ErrAbortHandler is left to net/http.
+func (rc *Recovery) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sw := recorder.New(w)
+
+		defer func() {
+			p := recover()
+			if p == nil {
+				return
+			}
+
+			value, stack := p, debug.Stack()
+			if hp, ok := p.(*handlerPanic); ok {
+				value, stack = hp.value, hp.stack
+			}
+			if value == http.ErrAbortHandler {
+				panic(value)
+			}
+
+			rc.report(r, value, stack)
+
+			if rc.cfg.Repanic {
+				panic(p)
+			}
+			if !sw.Started() {
+				WriteError(sw, r, errors.Wrapf(ErrPanic, "%v", value))
+			}
+		}()
+
+		next.ServeHTTP(sw, r)
+	})
+}
+
+func (rc *Recovery) report(r *http.Request, value interface{}, stack []byte) {
+	route := r.URL.Path
+	if cr := mux.CurrentRoute(r); cr != nil {
+		if t, err := cr.GetPathTemplate(); err == nil {
+			route = t
+		}
+	}
+
+	err := errors.Wrapf(ErrPanic, "%v", value)
+
+	span := trace.SpanFromContext(r.Context())
+	span.RecordError(err, trace.WithAttributes(
+		attribute.String("exception.type", fmt.Sprintf("%T", value)),
+		attribute.String("exception.stacktrace", string(stack)),
+	))
+	span.SetStatus(codes.Error, err.Error())
+
+	if rc.cfg.Counter != nil {
+		rc.cfg.Counter.Panicked(r.Method, route)
+	}
+
+	ctx := gcontext.NewContext(r.Context())
+	gcontext.AddError(ctx, err)
+	gcontext.AddString(ctx, "panic.value", fmt.Sprint(value))
+	gcontext.AddString(ctx, "panic.stack", string(stack))
+	gcontext.AddString(ctx, "http.method", r.Method)
+	gcontext.AddString(ctx, "http.route", route)
+	if id := requestid.FromContext(r.Context()); id != "" {
+		gcontext.AddString(ctx, requestid.Field, id)
+	}
+	rc.cfg.Logger.Error(ctx, "Handler panicked.")
+}
+
+// handlerPanic carries a panic raised on another goroutine, such as the
+// one of Timeout, along with the stack where it happened.
+type handlerPanic struct {
+	value interface{}
+	stack []byte
+}
+
+func (p *handlerPanic) String() string {
+	return fmt.Sprintf("%v\n\n%s", p.value, p.stack)
+}
@@ -0,0 +1,133 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+type panicCounter map[string]int
+
+func (c panicCounter) Panicked(method, route string) {
+	c[method+" "+route]++
+}
+
+func TestRecovery(t *testing.T) {
+	counter := panicCounter{}
+
+	router := mux.NewRouter()
+	router.Use(NewRecovery(RecoveryConfig{Counter: counter}).Middleware)
+	router.Use(Timeout(TimeoutConfig{Routes: map[string]time.Duration{"/timed/{id}": time.Second}}))
+	router.HandleFunc("/demo/{id}", func(http.ResponseWriter, *http.Request) {
+		panic("boom")
+	})
+	router.HandleFunc("/timed/{id}", func(http.ResponseWriter, *http.Request) {
+		panic("boom")
+	})
+	router.HandleFunc("/started", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		panic("boom")
+	})
+	router.HandleFunc("/fine", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	tt := []struct {
+		Name           string
+		Target         string
+		Accept         string
+		ExpectedStatus int
+		ExpectedCount  string
+	}{
+		{
+			Name:           "test panic answered with a problem",
+			Target:         "/demo/1",
+			ExpectedStatus: http.StatusInternalServerError,
+			ExpectedCount:  "GET /demo/{id}",
+		},
+		{
+			Name:           "test panic answered in the legacy format",
+			Target:         "/demo/2",
+			Accept:         "application/json",
+			ExpectedStatus: http.StatusInternalServerError,
+		},
+		{
+			Name:           "test panic on the timeout goroutine",
+			Target:         "/timed/1",
+			ExpectedStatus: http.StatusInternalServerError,
+			ExpectedCount:  "GET /timed/{id}",
+		},
+		{
+			Name:           "test panic after the response started",
+			Target:         "/started",
+			ExpectedStatus: http.StatusAccepted,
+			ExpectedCount:  "GET /started",
+		},
+		{
+			Name:           "test no panic",
+			Target:         "/fine",
+			ExpectedStatus: http.StatusNoContent,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.Target, nil)
+			req.Header.Set("Accept", tc.Accept)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tc.ExpectedStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tc.ExpectedStatus)
+			}
+			if tc.ExpectedCount != "" && counter[tc.ExpectedCount] != 1 {
+				t.Fatalf("panics = %v, want one for %s", counter, tc.ExpectedCount)
+			}
+			if rec.Code == http.StatusInternalServerError && tc.Accept == ContentTypeJSON {
+				var body struct {
+					Error string `json:"error"`
+				}
+				if err := json.NewDecoder(rec.Body).Decode(&body); err != nil || body.Error != http.StatusText(http.StatusInternalServerError) {
+					t.Fatalf("error = %q (%v), want the status text without detail", body.Error, err)
+				}
+			} else if rec.Code == http.StatusInternalServerError {
+				var p Problem
+				if err := json.NewDecoder(rec.Body).Decode(&p); err != nil || p.Type != ProblemTypeBlank || p.Detail != "" {
+					t.Fatalf("problem = %+v (%v), want a blank one without detail", p, err)
+				}
+			}
+		})
+	}
+}
+
+func TestRecoveryRepanic(t *testing.T) {
+	h := NewRecovery(RecoveryConfig{Repanic: true}).Middleware(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		panic("boom")
+	}))
+
+	defer func() {
+		if p := recover(); p != "boom" {
+			t.Fatalf("recover() = %v, want boom", p)
+		}
+	}()
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+}
+
+func TestRecoveryAbort(t *testing.T) {
+	h := NewRecovery(RecoveryConfig{}).Middleware(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+
+	defer func() {
+		if p := recover(); p != http.ErrAbortHandler {
+			t.Fatalf("recover() = %v, want http.ErrAbortHandler", p)
+		}
+	}()
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+}
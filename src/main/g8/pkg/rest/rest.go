@@ -37,8 +37,10 @@ func New(cfg Config) *Rest {
 // SendJSON is a helper function to send a JSON as an HTTP response.
 // It sets the header Content-Type as application/json.
 // If it fails to write the JSON an internal server error is generated.
+// Payloads implementing ETagger also set the ETag header.
 func SendJSON(w http.ResponseWriter, payload interface{}) error {
 	w.Header().Set("Content-Type", "application/json")
+	writeETag(w, payload)
 
 	if err := json.NewEncoder(w).Encode(payload); err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
@@ -0,0 +1,312 @@
+package rest
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Gympass/gcore/v3/gcontext"
+	"github.com/pkg/errors"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+const (
+	// ContentTypeCSV is the media type written by the CSV encoder.
+	ContentTypeCSV = "text/csv"
+	// ContentTypeMsgPack is the media type written by the MessagePack encoder.
+	ContentTypeMsgPack = "application/msgpack"
+
+	// ProblemTypeNotAcceptable identifies requests whose Accept header no
+	// registered encoder can satisfy.
+	ProblemTypeNotAcceptable = "urn:problem-type:not-acceptable"
+)
+
+var (
+	// ErrNotAcceptable is written by Send when no encoder matches the request.
+	ErrNotAcceptable = errors.New("no acceptable representation")
+	// ErrUnsupportedPayload is returned by encoders given a payload they
+	// cannot represent.
+	ErrUnsupportedPayload = errors.New("unsupported payload")
+)
+
+// Encoder writes payloads as one media type for Send.
+type Encoder interface {
+	// ContentType is the media type written by the encoder.
+	ContentType() string
+	// CanEncode reports whether the payload has a representation in this media type.
+	CanEncode(payload interface{}) bool
+	// Encode writes the payload to w.
+	Encode(w io.Writer, payload interface{}) error
+}
+
+var encoders = struct {
+	sync.RWMutex
+	list []Encoder
+}{
+	list: []Encoder{JSONEncoder{}, CSVEncoder{}, MsgPackEncoder{}},
+}
+
+func init() {
+	RegisterProblem(ErrNotAcceptable, ProblemType{
+		Type:   ProblemTypeNotAcceptable,
+		Title:  "Not acceptable",
+		Status: http.StatusNotAcceptable,
+	})
+}
+
+// RegisterEncoder makes e available to Send, replacing any encoder of the
+// same content type. When several encoders are equally acceptable, the one
+// registered first wins, so JSON stays the default.
+func RegisterEncoder(e Encoder) {
+	encoders.Lock()
+	defer encoders.Unlock()
+
+	for i := range encoders.list {
+		if encoders.list[i].ContentType() == e.ContentType() {
+			encoders.list[i] = e
+			return
+		}
+	}
+
+	encoders.list = append(encoders.list, e)
+}
+
+// Send writes payload with the given status using the encoder preferred by
+// the request Accept header among those able to represent it.
+// When none matches, a 406 is written instead, see WriteError. The payload is
+// encoded before anything is written, so an encoding failure is returned
+// with the response left untouched.
+//
+// Payloads implementing ETagger get an ETag header, and successful GETs
+// whose If-None-Match lists it are answered with 304 and no body.
+func Send(w http.ResponseWriter, r *http.Request, status int, payload interface{}) error {
+	w.Header().Add("Vary", "Accept")
+
+	etag := writeETag(w, payload)
+	if status == http.StatusOK && (r.Method == http.MethodGet || r.Method == http.MethodHead) && NotModified(r, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return nil
+	}
+
+	e := selectEncoder(r, payload)
+	if e == nil {
+		gcontext.AddError(r.Context(), ErrNotAcceptable)
+		WriteError(w, r, ErrNotAcceptable)
+		return nil
+	}
+
+	var buf bytes.Buffer
+	if err := e.Encode(&buf, payload); err != nil {
+		return errors.Wrapf(err, "encoding %s", e.ContentType())
+	}
+
+	w.Header().Set("Content-Type", e.ContentType())
+	w.WriteHeader(status)
+
+	_, err := buf.WriteTo(w)
+
+	return err
+}
+
+func selectEncoder(r *http.Request, payload interface{}) Encoder {
+	encoders.RLock()
+	defer encoders.RUnlock()
+
+	offers := make([]string, 0, len(encoders.list))
+	byType := make(map[string]Encoder, len(encoders.list))
+	for _, e := range encoders.list {
+		if e.CanEncode(payload) {
+			offers = append(offers, e.ContentType())
+			byType[e.ContentType()] = e
+		}
+	}
+
+	return byType[negotiate(r, offers...)]
+}
+
+// JSONEncoder writes payloads as application/json, like SendJSON.
+type JSONEncoder struct{}
+
+// ContentType implements Encoder.
+func (JSONEncoder) ContentType() string { return ContentTypeJSON }
+
+// CanEncode implements Encoder.
+func (JSONEncoder) CanEncode(interface{}) bool { return true }
+
+// Encode implements Encoder.
+func (JSONEncoder) Encode(w io.Writer, payload interface{}) error {
+	return json.NewEncoder(w).Encode(payload)
+}
+
+// MsgPackEncoder writes payloads as MessagePack, naming fields after their
+// json tags so both representations share the same keys.
+type MsgPackEncoder struct{}
+
+// ContentType implements Encoder.
+func (MsgPackEncoder) ContentType() string { return ContentTypeMsgPack }
+
+// CanEncode implements Encoder.
+func (MsgPackEncoder) CanEncode(interface{}) bool { return true }
+
+// Encode implements Encoder.
+func (MsgPackEncoder) Encode(w io.Writer, payload interface{}) error {
+	enc := msgpack.NewEncoder(w)
+	enc.SetCustomStructTag("json")
+
+	return enc.Encode(payload)
+}
+
+// CSVEncoder writes slices of structs as text/csv, one row per element
+// after a header row. Columns are named after the csv tag of each field,
+// or its json tag, and fields tagged "-" are skipped.
+//
+// A Page is written as the rows of its Data, since SendPage already sends
+// its links in the Link header.
+type CSVEncoder struct{}
+
+// ContentType implements Encoder.
+func (CSVEncoder) ContentType() string { return ContentTypeCSV }
+
+// CanEncode implements Encoder.
+func (CSVEncoder) CanEncode(payload interface{}) bool {
+	_, ok := csvRowType(reflect.TypeOf(csvRows(payload)))
+	return ok
+}
+
+// Encode implements Encoder.
+func (CSVEncoder) Encode(w io.Writer, payload interface{}) error {
+	payload = csvRows(payload)
+	v := reflect.Indirect(reflect.ValueOf(payload))
+	rowType, ok := csvRowType(v.Type())
+	if !ok {
+		return errors.Wrapf(ErrUnsupportedPayload, "csv needs a slice of structs, got %T", payload)
+	}
+
+	cols := csvColumns(rowType)
+	header := make([]string, len(cols))
+	for i := range cols {
+		header[i] = cols[i].name
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+
+	record := make([]string, len(cols))
+	for i := 0; i < v.Len(); i++ {
+		row := reflect.Indirect(v.Index(i))
+		for j := range cols {
+			record[j] = csvValue(row, cols[j].index)
+		}
+
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+
+	return cw.Error()
+}
+
+// csvRows returns the rows held by payload, unwrapping Page envelopes.
+func csvRows(payload interface{}) interface{} {
+	switch p := payload.(type) {
+	case Page:
+		return p.Data
+	case *Page:
+		return p.Data
+	default:
+		return payload
+	}
+}
+
+type csvColumn struct {
+	name  string
+	index []int
+}
+
+// csvRowType returns the struct type of the rows held by a slice type.
+func csvRowType(t reflect.Type) (reflect.Type, bool) {
+	if t == nil {
+		return nil, false
+	}
+
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Slice && t.Kind() != reflect.Array {
+		return nil, false
+	}
+
+	row := t.Elem()
+	if row.Kind() == reflect.Pointer {
+		row = row.Elem()
+	}
+
+	return row, row.Kind() == reflect.Struct && row != timeType
+}
+
+func csvColumns(t reflect.Type) []csvColumn {
+	fields := reflect.VisibleFields(t)
+	cols := make([]csvColumn, 0, len(fields))
+
+	for _, sf := range fields {
+		if !sf.IsExported() || (sf.Anonymous && sf.Type.Kind() == reflect.Struct) {
+			continue
+		}
+
+		name := sf.Tag.Get("csv")
+		if name == "" {
+			name, _, _ = strings.Cut(sf.Tag.Get("json"), ",")
+		}
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+
+		cols = append(cols, csvColumn{name: name, index: sf.Index})
+	}
+
+	return cols
+}
+
+func csvValue(row reflect.Value, index []int) string {
+	f, err := row.FieldByIndexErr(index)
+	if err != nil {
+		// nil embedded pointer
+		return ""
+	}
+
+	if f.Kind() == reflect.Pointer {
+		if f.IsNil() {
+			return ""
+		}
+		f = f.Elem()
+	}
+
+	switch v := f.Interface().(type) {
+	case time.Time:
+		return v.Format(time.RFC3339)
+	case encoding.TextMarshaler:
+		b, err := v.MarshalText()
+		if err != nil {
+			return ""
+		}
+		return string(b)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,175 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Gympass/gcore/v3/gtest"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+type sendTestRow struct {
+	ID        string     `json:"id"`
+	Name      string     `csv:"full_name" json:"name"`
+	Secret    string     `json:"-"`
+	CreatedAt time.Time  `json:"created_at"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+type protoTestEncoder struct{}
+
+func (protoTestEncoder) ContentType() string          { return "application/x-protobuf" }
+func (protoTestEncoder) CanEncode(p interface{}) bool { _, ok := p.(string); return ok }
+func (protoTestEncoder) Encode(w io.Writer, p interface{}) error {
+	_, err := io.WriteString(w, p.(string))
+	return err
+}
+
+func TestSend(t *testing.T) {
+	RegisterEncoder(protoTestEncoder{})
+
+	created := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+	rows := []sendTestRow{
+		{ID: "1", Name: "Jane, Doe", Secret: "s", CreatedAt: created},
+		{ID: "2", Name: "John", CreatedAt: created, DeletedAt: &created},
+	}
+
+	tt := []struct {
+		Name                string
+		Accept              string
+		Payload             interface{}
+		ExpectedStatus      int
+		ExpectedContentType string
+		Check               func(t *testing.T, body []byte)
+	}{
+		{
+			Name:                "test send json by default",
+			Payload:             rows,
+			ExpectedStatus:      http.StatusCreated,
+			ExpectedContentType: ContentTypeJSON,
+			Check: func(t *testing.T, body []byte) {
+				var got []sendTestRow
+				gtest.AssertNil(t, json.Unmarshal(body, &got))
+				if len(got) != 2 || got[0].Name != "Jane, Doe" {
+					t.Fatalf("unexpected body %s", body)
+				}
+			},
+		},
+		{
+			Name:                "test send csv for slices of structs",
+			Accept:              "text/csv",
+			Payload:             rows,
+			ExpectedStatus:      http.StatusCreated,
+			ExpectedContentType: ContentTypeCSV,
+			Check: func(t *testing.T, body []byte) {
+				expected := "id,full_name,created_at,deleted_at\n" +
+					"1,\"Jane, Doe\",2023-05-01T10:00:00Z,\n" +
+					"2,John,2023-05-01T10:00:00Z,2023-05-01T10:00:00Z\n"
+				if string(body) != expected {
+					t.Fatalf("Expected %q and got %q", expected, body)
+				}
+			},
+		},
+		{
+			Name:                "test send csv rows of a page",
+			Accept:              "text/csv",
+			Payload:             Page{Data: rows[:1], Next: "/v1/demo?cursor=abc"},
+			ExpectedStatus:      http.StatusCreated,
+			ExpectedContentType: ContentTypeCSV,
+			Check: func(t *testing.T, body []byte) {
+				expected := "id,full_name,created_at,deleted_at\n" +
+					"1,\"Jane, Doe\",2023-05-01T10:00:00Z,\n"
+				if string(body) != expected {
+					t.Fatalf("Expected %q and got %q", expected, body)
+				}
+			},
+		},
+		{
+			Name:                "test send msgpack with json field names",
+			Accept:              "application/msgpack",
+			Payload:             rows[0],
+			ExpectedStatus:      http.StatusCreated,
+			ExpectedContentType: ContentTypeMsgPack,
+			Check: func(t *testing.T, body []byte) {
+				var got map[string]interface{}
+				gtest.AssertNil(t, msgpack.Unmarshal(body, &got))
+				if got["name"] != "Jane, Doe" {
+					t.Fatalf("unexpected body %v", got)
+				}
+			},
+		},
+		{
+			Name:                "test send skips encoders unable to represent the payload",
+			Accept:              "text/csv, application/json;q=0.5",
+			Payload:             rows[0],
+			ExpectedStatus:      http.StatusCreated,
+			ExpectedContentType: ContentTypeJSON,
+		},
+		{
+			Name:                "test send with registered encoder",
+			Accept:              "application/x-protobuf",
+			Payload:             "raw",
+			ExpectedStatus:      http.StatusCreated,
+			ExpectedContentType: "application/x-protobuf",
+			Check: func(t *testing.T, body []byte) {
+				if string(body) != "raw" {
+					t.Fatalf("unexpected body %s", body)
+				}
+			},
+		},
+		{
+			Name:                "test send not acceptable",
+			Accept:              "application/xml",
+			Payload:             rows,
+			ExpectedStatus:      http.StatusNotAcceptable,
+			ExpectedContentType: ContentTypeProblemJSON,
+		},
+	}
+
+	for _, testCase := range tt {
+		t.Run(testCase.Name, func(t *testing.T) {
+			r, err := http.NewRequestWithContext(context.Background(), http.MethodGet, "/v1/demo", nil)
+			gtest.AssertNil(t, err)
+			r.Header.Set("Accept", testCase.Accept)
+			rr := httptest.NewRecorder()
+
+			gtest.AssertNil(t, Send(rr, r, http.StatusCreated, testCase.Payload))
+
+			if rr.Code != testCase.ExpectedStatus {
+				t.Fatalf("Expected status %d and got %d", testCase.ExpectedStatus, rr.Code)
+			}
+
+			if ct := rr.Header().Get("Content-Type"); ct != testCase.ExpectedContentType {
+				t.Fatalf("Expected content type %s and got %s", testCase.ExpectedContentType, ct)
+			}
+
+			if testCase.Check != nil {
+				testCase.Check(t, rr.Body.Bytes())
+			}
+		})
+	}
+}
+
+func TestParseAccept(t *testing.T) {
+	ranges := parseAccept("text/*;q=0.5, text/csv, */*;q=0.1, application/json;q=0.5")
+
+	got := make([]string, len(ranges))
+	for i := range ranges {
+		got[i] = ranges[i].typ + "/" + ranges[i].subtype
+	}
+
+	expected := "text/csv application/json text/* */*"
+	if strings.Join(got, " ") != expected {
+		t.Fatalf("Expected %s and got %s", expected, strings.Join(got, " "))
+	}
+
+	if q := weight(ranges, "text/plain"); q != 0.5 {
+		t.Fatalf("Expected text/plain weight 0.5 and got %v", q)
+	}
+}
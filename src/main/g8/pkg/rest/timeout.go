@@ -0,0 +1,199 @@
+package rest
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+)
+
+// ProblemTypeTimeout identifies requests that ran out of time.
+const ProblemTypeTimeout = "urn:problem-type:timeout"
+
+// ErrTimeout is written by Timeout for the requests still being served at
+// their deadline.
+var ErrTimeout = errors.New("request timed out")
+
+func init() {
+	RegisterProblem(ErrTimeout, ProblemType{
+		Type:   ProblemTypeTimeout,
+		Title:  "Request timed out",
+		Status: http.StatusServiceUnavailable,
+	})
+	// Handlers returning the error of a call that ran out of time, such as
+	// a query, answer 504.
+	RegisterProblem(context.DeadlineExceeded, ProblemType{
+		Type:   ProblemTypeTimeout,
+		Title:  "Request timed out",
+		Status: http.StatusGatewayTimeout,
+	})
+}
+
+// TimeoutConfig sets the time each request is given.
+type TimeoutConfig struct {
+	// Default bounds the requests of every route, none when zero.
+	Default time.Duration
+	// Routes overrides Default by mux route template, such as
+	// "/v1/demo/{uid}". Zero leaves the route unbounded.
+	Routes map[string]time.Duration
+	// Recovery reports the panics raised by handlers after their deadline,
+	// once no middleware is left to recover them. They are dropped when
+	// nil.
+	Recovery *Recovery
+}
+
+// Timeout returns a middleware giving each request a context with a
+// deadline, which handlers pass down to services and repositories. When
+// the handler is still running at the deadline the client gets a 503, see
+// WriteError, and whatever the handler writes afterwards is discarded, so
+// responses are buffered and cannot be streamed. Panics are raised again on
+// the serving goroutine, or reported by c.Recovery past the deadline. Add
+// it with Router.Use.
+func Timeout(c TimeoutConfig) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			d := c.Default
+			if cr := mux.CurrentRoute(r); cr != nil {
+				if t, err := cr.GetPathTemplate(); err == nil {
+					if rd, ok := c.Routes[t]; ok {
+						d = rd
+					}
+				}
+			}
+
+			if d <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx, cancel := context.WithTimeout(r.Context(), d)
+			defer cancel()
+			r = r.WithContext(ctx)
+
+			tw := &timeoutWriter{header: make(http.Header), status: http.StatusOK}
+			done := make(chan struct{})
+			panicked := make(chan interface{}, 1)
+
+			go func() {
+				defer func() {
+					p := recover()
+					if p == nil {
+						return
+					}
+					if p == http.ErrAbortHandler {
+						if tw.handOff() {
+							panicked <- p
+						}
+						return
+					}
+
+					hp := &handlerPanic{value: p, stack: debug.Stack()}
+					switch {
+					case tw.handOff():
+						panicked <- hp
+					case c.Recovery != nil:
+						c.Recovery.report(r, hp.value, hp.stack)
+					}
+				}()
+
+				next.ServeHTTP(tw, r)
+				close(done)
+			}()
+
+			select {
+			case p := <-panicked:
+				panic(p)
+			case <-done:
+				tw.copyTo(w)
+			case <-ctx.Done():
+				if tw.expire() {
+					// The handler panicked right at the deadline.
+					panic(<-panicked)
+				}
+
+				// A canceled context means the client is gone.
+				if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+					WriteError(w, r, ErrTimeout)
+				}
+			}
+		})
+	}
+}
+
+// timeoutWriter buffers the response of a handler until it returns, or
+// discards it once the deadline passed.
+type timeoutWriter struct {
+	mu          sync.Mutex
+	header      http.Header
+	body        bytes.Buffer
+	status      int
+	wroteHeader bool
+	timedOut    bool
+	panicked    bool
+}
+
+func (w *timeoutWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *timeoutWriter) WriteHeader(code int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.timedOut || w.wroteHeader {
+		return
+	}
+	w.status = code
+	w.wroteHeader = true
+}
+
+func (w *timeoutWriter) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	w.wroteHeader = true
+
+	return w.body.Write(b)
+}
+
+// expire discards the writes to come, and reports whether the handler
+// panicked before.
+func (w *timeoutWriter) expire() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.timedOut = true
+	return w.panicked
+}
+
+// handOff reports whether a panic of the handler is raised again on the
+// serving goroutine, which is no longer waiting past the deadline.
+func (w *timeoutWriter) handOff() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.panicked = !w.timedOut
+	return w.panicked
+}
+
+// copyTo writes the buffered response to dst.
+func (w *timeoutWriter) copyTo(dst http.ResponseWriter) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	h := dst.Header()
+	for k, v := range w.header {
+		h[k] = v
+	}
+
+	dst.WriteHeader(w.status)
+	_, _ = dst.Write(w.body.Bytes())
+}
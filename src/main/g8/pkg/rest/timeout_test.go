@@ -0,0 +1,167 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+)
+
+func TestTimeout(t *testing.T) {
+	router := mux.NewRouter()
+	router.Use(Timeout(TimeoutConfig{
+		Default: 20 * time.Millisecond,
+		Routes:  map[string]time.Duration{"/slow": time.Second, "/unbounded": 0},
+	}))
+
+	// wait sleeps for the duration of the query parameter, or until the
+	// request context is done.
+	wait := func(w http.ResponseWriter, r *http.Request) {
+		d, _ := time.ParseDuration(r.URL.Query().Get("wait"))
+
+		select {
+		case <-time.After(d):
+		case <-r.Context().Done():
+			WriteProblem(w, r, errors.Wrap(r.Context().Err(), "querying demos"))
+			return
+		}
+
+		if _, ok := r.Context().Deadline(); ok {
+			w.Header().Set("X-Deadline", "true")
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}
+	router.HandleFunc("/fast", wait)
+	router.HandleFunc("/slow", wait)
+	router.HandleFunc("/unbounded", wait)
+	router.HandleFunc("/upstream", func(w http.ResponseWriter, r *http.Request) {
+		WriteProblem(w, r, errors.Wrap(context.DeadlineExceeded, "querying demos"))
+	})
+
+	tt := []struct {
+		Name             string
+		Target           string
+		Accept           string
+		ExpectedStatus   int
+		ExpectedDeadline bool
+	}{
+		{
+			Name:             "test served in time",
+			Target:           "/fast?wait=1ms",
+			ExpectedStatus:   http.StatusAccepted,
+			ExpectedDeadline: true,
+		},
+		{
+			Name:           "test deadline passed",
+			Target:         "/fast?wait=1s",
+			ExpectedStatus: http.StatusServiceUnavailable,
+		},
+		{
+			Name:           "test deadline passed in the legacy format",
+			Target:         "/fast?wait=1s",
+			Accept:         "application/json",
+			ExpectedStatus: http.StatusServiceUnavailable,
+		},
+		{
+			Name:             "test route override",
+			Target:           "/slow?wait=50ms",
+			ExpectedStatus:   http.StatusAccepted,
+			ExpectedDeadline: true,
+		},
+		{
+			Name:           "test unbounded route",
+			Target:         "/unbounded?wait=50ms",
+			ExpectedStatus: http.StatusAccepted,
+		},
+		{
+			Name:           "test upstream deadline",
+			Target:         "/upstream",
+			ExpectedStatus: http.StatusGatewayTimeout,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.Target, nil)
+			req.Header.Set("Accept", tc.Accept)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tc.ExpectedStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tc.ExpectedStatus)
+			}
+			if got := rec.Header().Get("X-Deadline") == "true"; got != tc.ExpectedDeadline {
+				t.Fatalf("deadline set = %t, want %t", got, tc.ExpectedDeadline)
+			}
+			if rec.Code >= http.StatusInternalServerError && tc.Accept == ContentTypeJSON {
+				var body struct {
+					Error string `json:"error"`
+				}
+				if err := json.NewDecoder(rec.Body).Decode(&body); err != nil || body.Error != "Request timed out" {
+					t.Fatalf("error = %q (%v), want Request timed out", body.Error, err)
+				}
+			} else if rec.Code >= http.StatusInternalServerError {
+				var p Problem
+				if err := json.NewDecoder(rec.Body).Decode(&p); err != nil || p.Type != ProblemTypeTimeout {
+					t.Fatalf("problem = %+v (%v), want type %s", p, err, ProblemTypeTimeout)
+				}
+			}
+		})
+	}
+}
+
+func TestTimeoutPanic(t *testing.T) {
+	h := Timeout(TimeoutConfig{Default: time.Second})(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		panic("boom")
+	}))
+
+	defer func() {
+		if p := recover(); p == nil {
+			t.Fatalf("panic was not raised on the serving goroutine")
+		}
+	}()
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+}
+
+// panicReports hands the routes of the reported panics over.
+type panicReports chan string
+
+func (c panicReports) Panicked(method, route string) {
+	c <- method + " " + route
+}
+
+func TestTimeoutLatePanic(t *testing.T) {
+	reports, answered := make(panicReports, 1), make(chan struct{})
+
+	router := mux.NewRouter()
+	router.Use(Timeout(TimeoutConfig{
+		Default:  10 * time.Millisecond,
+		Recovery: NewRecovery(RecoveryConfig{Counter: reports}),
+	}))
+	router.HandleFunc("/late/{id}", func(w http.ResponseWriter, r *http.Request) {
+		<-answered
+		panic("boom")
+	})
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/late/1", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+	close(answered)
+
+	select {
+	case route := <-reports:
+		if route != "GET /late/{id}" {
+			t.Fatalf("reported route = %s, want GET /late/{id}", route)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("panic after the deadline was not reported")
+	}
+}
@@ -0,0 +1,247 @@
+package rest
+
+import (
+	"fmt"
+	"net/mail"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Gympass/gcore/v3/gerror"
+	"github.com/pkg/errors"
+)
+
+// Validation rules understood by the validate tag. RuleType is only
+// reported by Bind, for values that do not parse as the field type.
+const (
+	RuleType      = "type"
+	RuleRequired  = "required"
+	RuleOmitEmpty = "omitempty"
+	RuleMin       = "min"
+	RuleMax       = "max"
+	RuleLen       = "len"
+	RuleOneOf     = "oneof"
+	RuleEmail     = "email"
+)
+
+// Validate checks the validate tags of the struct held or pointed by v:
+//
+//	type createRequest struct {
+//		Email  string `json:"email" validate:"required,email"`
+//		Limit  int    `query:"limit,default=20" validate:"min=1,max=100"`
+//		Status string `json:"status" validate:"omitempty,oneof=active|paused"`
+//	}
+//
+// min, max and len compare numbers by value, strings by length in
+// characters and slices or maps by number of items. Every rule applies to
+// zero values too, unless the field is tagged omitempty. Nil pointers are
+// only checked by required. Nested structs and slices of structs are
+// validated as well, with their fields reported as parent.field and
+// parent[i].field.
+//
+// Every failing field is reported in a single gerror.NewBadRequest whose
+// cause is a FieldErrors. Bind already calls Validate, so handlers only
+// need it for values they decode on their own.
+func Validate(v interface{}) error {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		return errors.Wrapf(ErrBindTarget, "got %T", v)
+	}
+
+	var errs FieldErrors
+	if err := validateStruct(rv, "", &errs); err != nil {
+		return err
+	}
+
+	if len(errs) > 0 {
+		return gerror.NewBadRequest(errs).WithMessage(errs.Error())
+	}
+
+	return nil
+}
+
+// validateStruct appends the rules broken by the fields of v to errs. The
+// returned error is reserved for malformed validate tags.
+func validateStruct(v reflect.Value, prefix string, errs *FieldErrors) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		fv := v.Field(i)
+
+		// Embedded structs of unexported types still promote their
+		// exported fields.
+		if sf.Anonymous && sf.Type.Kind() == reflect.Struct {
+			if err := validateStruct(fv, prefix, errs); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if !sf.IsExported() {
+			continue
+		}
+
+		name := prefix + fieldName(sf)
+
+		if tag, ok := sf.Tag.Lookup("validate"); ok {
+			fe, err := validateField(name, fv, tag)
+			if err != nil {
+				return errors.Wrapf(err, "validating field %s", sf.Name)
+			}
+			if fe != nil {
+				*errs = append(*errs, fe)
+				continue
+			}
+		}
+
+		if err := validateNested(reflect.Indirect(fv), name, errs); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func validateNested(v reflect.Value, name string, errs *FieldErrors) error {
+	switch {
+	case v.Kind() == reflect.Struct && v.Type() != timeType:
+		return validateStruct(v, name+".", errs)
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Struct && v.Type().Elem() != timeType:
+		for i := 0; i < v.Len(); i++ {
+			if err := validateStruct(v.Index(i), fmt.Sprintf("%s[%d].", name, i), errs); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// fieldName reports a field the way the client sent it: by its parameter
+// or json name, falling back to the Go name.
+func fieldName(sf reflect.StructField) string {
+	if tag, ok := lookupParamTag(sf); ok {
+		return tag.name
+	}
+
+	if name, _, _ := strings.Cut(sf.Tag.Get("json"), ","); name != "" && name != "-" {
+		return name
+	}
+
+	return sf.Name
+}
+
+// validateField returns the first rule of tag broken by fv, if any.
+func validateField(name string, fv reflect.Value, tag string) (*FieldError, error) {
+	rules := strings.Split(tag, ",")
+
+	for _, r := range rules {
+		if r == RuleOmitEmpty && fv.IsZero() {
+			return nil, nil
+		}
+	}
+
+	for _, r := range rules {
+		rule, arg, _ := strings.Cut(strings.TrimSpace(r), "=")
+
+		if rule == RuleRequired {
+			if fv.IsZero() {
+				return ruleErr(name, rule, "%s is required", name), nil
+			}
+			continue
+		}
+
+		if rule == RuleOmitEmpty || rule == "" {
+			continue
+		}
+
+		v := fv
+		if v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				return nil, nil
+			}
+			v = v.Elem()
+		}
+
+		fe, err := checkRule(name, v, rule, arg)
+		if err != nil || fe != nil {
+			return fe, err
+		}
+	}
+
+	return nil, nil
+}
+
+func checkRule(name string, v reflect.Value, rule, arg string) (*FieldError, error) {
+	switch rule {
+	case RuleMin, RuleMax, RuleLen:
+		limit, err := strconv.ParseFloat(arg, 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid %s argument", rule)
+		}
+
+		size, unit, err := measure(v)
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case rule == RuleMin && size < limit:
+			return ruleErr(name, rule, "%s must be at least %s%s", name, arg, unit), nil
+		case rule == RuleMax && size > limit:
+			return ruleErr(name, rule, "%s must be at most %s%s", name, arg, unit), nil
+		case rule == RuleLen && size != limit:
+			return ruleErr(name, rule, "%s must be exactly %s%s", name, arg, unit), nil
+		}
+	case RuleOneOf:
+		options := strings.Split(arg, "|")
+		value := fmt.Sprint(v.Interface())
+		for _, o := range options {
+			if o == value {
+				return nil, nil
+			}
+		}
+
+		return ruleErr(name, rule, "%s must be one of %s", name, strings.Join(options, ", ")), nil
+	case RuleEmail:
+		if v.Kind() != reflect.String {
+			return nil, errors.Errorf("%s does not apply to %s", rule, v.Type())
+		}
+
+		addr, err := mail.ParseAddress(v.String())
+		if err != nil || addr.Address != v.String() {
+			return ruleErr(name, rule, "%s must be a valid email address", name), nil
+		}
+	default:
+		return nil, errors.Errorf("unknown validation rule %q", rule)
+	}
+
+	return nil, nil
+}
+
+// measure returns the value compared by min, max and len and the unit used
+// to describe it.
+func measure(v reflect.Value) (float64, string, error) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), "", nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), "", nil
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), "", nil
+	case reflect.String:
+		return float64(utf8.RuneCountInString(v.String())), " characters long", nil
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return float64(v.Len()), " items", nil
+	default:
+		return 0, "", errors.Errorf("cannot measure %s", v.Type())
+	}
+}
+
+func ruleErr(field, rule, format string, any ...interface{}) *FieldError {
+	fe := fieldErr(nil, field, format, any...)
+	fe.Rule = rule
+
+	return fe
+}
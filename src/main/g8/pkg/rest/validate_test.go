@@ -0,0 +1,141 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Gympass/gcore/v3/gtest"
+)
+
+type validateTestAddress struct {
+	City string `json:"city" validate:"required"`
+}
+
+type validateTestRequest struct {
+	Email     string                `json:"email" validate:"required,email"`
+	Limit     int                   `query:"limit,default=20" validate:"min=1,max=100"`
+	Status    string                `json:"status" validate:"omitempty,oneof=active|paused"`
+	Code      *string               `json:"code" validate:"len=3"`
+	Tags      []string              `json:"tags" validate:"max=2"`
+	Addresses []validateTestAddress `json:"addresses"`
+}
+
+type ExpectedFieldError struct {
+	Field, Rule string
+}
+
+func TestValidate(t *testing.T) {
+	code, badCode := "abc", "abcd"
+
+	tt := []struct {
+		Name     string
+		Request  validateTestRequest
+		Expected []ExpectedFieldError
+	}{
+		{
+			Name: "test validate with success",
+			Request: validateTestRequest{
+				Email:     "jane@example.com",
+				Limit:     10,
+				Status:    "paused",
+				Code:      &code,
+				Tags:      []string{"a", "b"},
+				Addresses: []validateTestAddress{{City: "Lisbon"}},
+			},
+		},
+		{
+			Name:    "test validate skips omitempty and nil pointers",
+			Request: validateTestRequest{Email: "jane@example.com", Limit: 1},
+		},
+		{
+			Name: "test validate reports one error per field",
+			Request: validateTestRequest{
+				Email:     "jane",
+				Limit:     101,
+				Status:    "deleted",
+				Code:      &badCode,
+				Tags:      []string{"a", "b", "c"},
+				Addresses: []validateTestAddress{{City: "Lisbon"}, {}},
+			},
+			Expected: []ExpectedFieldError{
+				{"email", RuleEmail},
+				{"limit", RuleMax},
+				{"status", RuleOneOf},
+				{"code", RuleLen},
+				{"tags", RuleMax},
+				{"addresses[1].city", RuleRequired},
+			},
+		},
+		{
+			Name:     "test validate checks zero values",
+			Request:  validateTestRequest{},
+			Expected: []ExpectedFieldError{{"email", RuleRequired}, {"limit", RuleMin}},
+		},
+	}
+
+	for _, testCase := range tt {
+		t.Run(testCase.Name, func(t *testing.T) {
+			err := Validate(&testCase.Request)
+			assertFieldErrors(t, err, testCase.Expected)
+		})
+	}
+}
+
+func TestBindValidates(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/demo?limit=0&status=active", nil)
+
+	var req struct {
+		Limit  int    `query:"limit" validate:"min=1"`
+		Status string `query:"status" validate:"oneof=active|paused"`
+		Page   int    `query:"page,default=x" validate:"min=1"`
+	}
+	err := Bind(r, &req)
+
+	assertFieldErrors(t, err, []ExpectedFieldError{{"page", RuleType}, {"limit", RuleMin}})
+}
+
+func TestValidateUnexportedEmbedded(t *testing.T) {
+	req := struct {
+		validateTestAddress
+		Name string `json:"name" validate:"required"`
+	}{Name: "demo"}
+
+	assertFieldErrors(t, Validate(&req), []ExpectedFieldError{{"city", RuleRequired}})
+}
+
+func TestValidateRejectsUnknownRule(t *testing.T) {
+	req := struct {
+		Name string `validate:"uppercase"`
+	}{}
+
+	var fe FieldErrors
+	if err := Validate(&req); err == nil || errors.As(err, &fe) {
+		t.Fatalf("Expected a tag error and got %v", err)
+	}
+}
+
+func assertFieldErrors(t *testing.T, err error, expected []ExpectedFieldError) {
+	t.Helper()
+
+	if len(expected) == 0 {
+		gtest.AssertNil(t, err)
+		return
+	}
+
+	var fe FieldErrors
+	if !errors.As(err, &fe) {
+		t.Fatalf("Expected FieldErrors and got %v", err)
+	}
+
+	if len(fe) != len(expected) {
+		t.Fatalf("Expected %d field errors and got %v", len(expected), fe)
+	}
+
+	for i := range fe {
+		if fe[i].Field != expected[i].Field || fe[i].Rule != expected[i].Rule {
+			t.Fatalf("Expected %s/%s and got %s/%s", expected[i].Field, expected[i].Rule, fe[i].Field, fe[i].Rule)
+		}
+	}
+}
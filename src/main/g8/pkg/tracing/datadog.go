@@ -0,0 +1,37 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/opentelemetry"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+)
+
+// datadogProvider sends the spans to the Datadog agent.
+type datadogProvider struct {
+	*opentelemetry.TracerProvider
+}
+
+func (p datadogProvider) Shutdown(context.Context) error {
+	return errors.Wrap(p.TracerProvider.Shutdown(), "shutting down datadog tracer")
+}
+
+// newDatadog starts the Datadog tracer.
+func newDatadog(c Config) Provider {
+	opts := []tracer.StartOption{
+		tracer.WithEnv(c.Environment),
+		tracer.WithService(c.Service),
+		tracer.WithServiceVersion(c.Version),
+		tracer.WithAnalytics(true),
+		tracer.WithSampler(tracer.NewRateSampler(c.SampleRatio)),
+	}
+	if c.DatadogAgent != "" {
+		opts = append(opts, tracer.WithAgentAddr(c.DatadogAgent))
+	}
+	for k, v := range c.Tags {
+		opts = append(opts, tracer.WithGlobalTag(k, v))
+	}
+
+	return datadogProvider{TracerProvider: opentelemetry.NewTracerProvider(opts...)}
+}
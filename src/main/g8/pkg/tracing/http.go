@@ -0,0 +1,99 @@
+package tracing
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/recorder"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentation = "github.com/gympass/$name;format="lower,hyphen"$/pkg/tracing"
+
+// Middleware starts a server span for each request, child of the trace
+// context of its traceparent header. Spans are named after the method and
+// the template of the mux route, and tagged with the request id; add it
+// with Router.Use.
+func Middleware(next http.Handler) http.Handler {
+	tracer := otel.Tracer(instrumentation)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+
+		route := r.URL.Path
+		if cr := mux.CurrentRoute(r); cr != nil {
+			if t, err := cr.GetPathTemplate(); err == nil {
+				route = t
+			}
+		}
+
+		ctx, span := tracer.Start(ctx, r.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPMethod(r.Method),
+				semconv.HTTPRoute(route),
+				semconv.HTTPTarget(r.URL.RequestURI()),
+			),
+		)
+		defer span.End()
+		tagRequestID(ctx, span)
+
+		sw := recorder.New(w)
+		next.ServeHTTP(sw, r.WithContext(ctx))
+
+		span.SetAttributes(semconv.HTTPStatusCode(sw.Status()))
+		if sw.Status() >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(sw.Status()))
+		}
+	})
+}
+
+// Transport returns a RoundTripper starting a client span for each
+// request sent through base, or http.DefaultTransport when nil, and
+// writing its traceparent header.
+func Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	return &transport{base: base, tracer: otel.Tracer(instrumentation)}
+}
+
+type transport struct {
+	base   http.RoundTripper
+	tracer trace.Tracer
+}
+
+func (t *transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	ctx, span := t.tracer.Start(r.Context(), r.Method+" "+r.URL.Host,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.HTTPMethod(r.Method),
+			semconv.HTTPURL(r.URL.Redacted()),
+			semconv.NetPeerName(r.URL.Hostname()),
+		),
+	)
+	tagRequestID(ctx, span)
+
+	// RoundTrip must not change the request, the headers are copied.
+	r = r.Clone(ctx)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(r.Header))
+
+	res, err := t.base.RoundTrip(r)
+	if err != nil {
+		End(span, err)
+		return nil, err
+	}
+
+	span.SetAttributes(semconv.HTTPStatusCode(res.StatusCode))
+	if res.StatusCode >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, http.StatusText(res.StatusCode))
+	}
+	span.End()
+
+	return res, nil
+}
@@ -0,0 +1,66 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/requestid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// StartPublish starts the producer span of a message sent to topic. Its
+// parent is the span of ctx or, without one, the trace context held by
+// headers, which is replaced by the one of the new span. The request id
+// is taken the same way, written to headers and set on the span.
+func StartPublish(ctx context.Context, topic string, headers map[string]string) (context.Context, trace.Span) {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		ctx = Extract(ctx, headers)
+	}
+	if requestid.FromContext(ctx) == "" {
+		ctx = requestid.Extract(ctx, headers)
+	}
+
+	ctx, span := otel.Tracer(instrumentation).Start(ctx, topic+" publish",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			semconv.MessagingSystem("kafka"),
+			semconv.MessagingDestinationName(topic),
+			semconv.MessagingOperationPublish,
+		),
+	)
+	tagRequestID(ctx, span)
+	Inject(ctx, headers)
+	requestid.Inject(ctx, headers)
+
+	return ctx, span
+}
+
+// StartProcess starts the consumer span of a message read from topic,
+// child of the trace context held by its headers. The returned context
+// carries the request id of the message, also set on the span.
+func StartProcess(ctx context.Context, topic string, headers map[string]string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	attrs = append([]attribute.KeyValue{
+		semconv.MessagingSystem("kafka"),
+		semconv.MessagingSourceName(topic),
+		semconv.MessagingOperationProcess,
+	}, attrs...)
+
+	ctx = requestid.Extract(ctx, headers)
+	if id := requestid.FromContext(ctx); id != "" {
+		attrs = append(attrs, attribute.String(requestid.Field, id))
+	}
+
+	return otel.Tracer(instrumentation).Start(Extract(ctx, headers), topic+" process",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(attrs...),
+	)
+}
+
+// tagRequestID sets the request id of ctx, if any, on span.
+func tagRequestID(ctx context.Context, span trace.Span) {
+	if id := requestid.FromContext(ctx); id != "" {
+		span.SetAttributes(attribute.String(requestid.Field, id))
+	}
+}
@@ -0,0 +1,97 @@
+package tracing
+
+import (
+	"context"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+)
+
+// otelProvider is the OpenTelemetry SDK, closing the file written by its
+// exporter on shutdown.
+type otelProvider struct {
+	*sdktrace.TracerProvider
+	closer io.Closer
+}
+
+func (p otelProvider) Shutdown(ctx context.Context) error {
+	err := p.TracerProvider.Shutdown(ctx)
+	if p.closer != nil {
+		if cerr := p.closer.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return errors.Wrap(err, "shutting down tracer provider")
+}
+
+func newOTel(c Config) (Provider, error) {
+	exporter, closer, err := newExporter(c)
+	if err != nil {
+		return nil, err
+	}
+
+	attrs := []attribute.KeyValue{
+		semconv.ServiceName(c.Service),
+		semconv.ServiceVersion(c.Version),
+		semconv.DeploymentEnvironment(c.Environment),
+	}
+	for k, v := range c.Tags {
+		attrs = append(attrs, attribute.String(k, v))
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL, attrs...))
+	if err != nil {
+		return nil, errors.Wrap(err, "creating tracing resource")
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(c.SampleRatio))),
+	)
+
+	return otelProvider{TracerProvider: tp, closer: closer}, nil
+}
+
+func newExporter(c Config) (sdktrace.SpanExporter, io.Closer, error) {
+	switch strings.ToLower(c.Exporter) {
+	case ExporterOTLP, "":
+		var opts []otlptracehttp.Option
+		if c.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpoint(c.Endpoint))
+		}
+		if c.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+
+		// The client connects when spans are exported, so New does not
+		// wait for the collector.
+		exporter, err := otlptracehttp.New(context.Background(), opts...)
+		return exporter, nil, errors.Wrap(err, "creating otlp exporter")
+	case ExporterStdout:
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+		return exporter, nil, errors.Wrap(err, "creating stdout exporter")
+	case ExporterFile:
+		f, err := os.OpenFile(c.File, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "opening trace file")
+		}
+
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(f))
+		if err != nil {
+			_ = f.Close()
+			return nil, nil, errors.Wrap(err, "creating file exporter")
+		}
+		return exporter, f, nil
+	default:
+		return nil, nil, errors.Wrapf(ErrUnknownProvider, "exporter %q", c.Exporter)
+	}
+}
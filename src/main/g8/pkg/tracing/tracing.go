@@ -0,0 +1,137 @@
+// Package tracing sets up the tracing backend selected in configuration
+// and helps instrumented packages propagate W3C trace context. Packages
+// create spans through the OpenTelemetry API, which the Provider backs
+// with OpenTelemetry exporters or the Datadog tracer.
+package tracing
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Providers
+const (
+	ProviderNone    = "none"
+	ProviderOTel    = "otel"
+	ProviderDatadog = "datadog"
+)
+
+// Exporters of the OpenTelemetry provider
+const (
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+	ExporterFile   = "file"
+)
+
+// ErrUnknownProvider is returned for providers and exporters not listed above.
+var ErrUnknownProvider = errors.New("unknown tracing provider")
+
+// Provider is a tracing backend.
+type Provider interface {
+	trace.TracerProvider
+	// Shutdown flushes the spans not exported yet and stops the backend.
+	Shutdown(ctx context.Context) error
+}
+
+// Config used by tracing package
+type Config struct {
+	// Provider is one of ProviderNone, ProviderOTel or ProviderDatadog,
+	// defaults to ProviderNone.
+	Provider string
+	// Exporter of ProviderOTel, one of ExporterOTLP, ExporterStdout or
+	// ExporterFile, defaults to ExporterOTLP.
+	Exporter string
+	// Endpoint is the host:port of the OTLP/HTTP collector, defaults to
+	// the OTEL_EXPORTER_OTLP_ENDPOINT environment variable.
+	Endpoint string
+	// Insecure sends spans to the collector over plain HTTP.
+	Insecure bool
+	// File written by ExporterFile.
+	File string
+	// SampleRatio is the fraction of the traces started by the service
+	// that are recorded, the others follow the decision of their parent.
+	// Defaults to 1.
+	SampleRatio float64
+
+	Service     string
+	Version     string
+	Environment string
+	// Tags are added to every span, such as the build time.
+	Tags map[string]string
+
+	// DatadogAgent is the host:port of the agent of ProviderDatadog.
+	DatadogAgent string
+}
+
+// New creates the Provider based on configuration properties and makes it
+// the global one, along with the W3C trace context and baggage propagators.
+func New(c Config) (Provider, error) {
+	if c.SampleRatio <= 0 {
+		c.SampleRatio = 1
+	}
+
+	var (
+		p   Provider
+		err error
+	)
+	switch strings.ToLower(c.Provider) {
+	case ProviderNone, "":
+		p = noopProvider{TracerProvider: trace.NewNoopTracerProvider()}
+	case ProviderOTel:
+		p, err = newOTel(c)
+	case ProviderDatadog:
+		p = newDatadog(c)
+	default:
+		err = errors.Wrapf(ErrUnknownProvider, "%q", c.Provider)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	otel.SetTracerProvider(p)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	return p, nil
+}
+
+type noopProvider struct {
+	trace.TracerProvider
+}
+
+func (noopProvider) Shutdown(context.Context) error { return nil }
+
+// Tracer returns the tracer of the instrumented package called name, backed
+// by the global Provider.
+func Tracer(name string) trace.Tracer {
+	return otel.Tracer(name)
+}
+
+// End records err on span, unless nil, and ends it.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Inject writes the trace context of ctx into headers, such as the
+// headers of a message.
+func Inject(ctx context.Context, headers map[string]string) {
+	otel.GetTextMapPropagator().Inject(ctx, propagation.MapCarrier(headers))
+}
+
+// Extract returns ctx carrying the trace context written into headers by
+// Inject.
+func Extract(ctx context.Context, headers map[string]string) context.Context {
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(headers))
+}
@@ -0,0 +1,269 @@
+package tracing_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/requestid"
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/tracing"
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	traceID     = "4bf92f3577b34da6a3ce929d0e0e4736"
+)
+
+// record makes a span recorder back the global tracer provider.
+func record(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+
+	sr := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { _ = tp.Shutdown(context.Background()) })
+
+	return sr
+}
+
+func TestMiddleware(t *testing.T) {
+	tt := []struct {
+		Name           string
+		Path           string
+		Traceparent    string
+		ExpectedName   string
+		ExpectedStatus codes.Code
+		ExpectedParent bool
+	}{
+		{
+			Name:         "test span named after the route template",
+			Path:         "/demos/1",
+			ExpectedName: "GET /demos/{id}",
+		},
+		{
+			Name:           "test span continuing the incoming trace",
+			Path:           "/demos/1",
+			Traceparent:    traceparent,
+			ExpectedName:   "GET /demos/{id}",
+			ExpectedParent: true,
+		},
+		{
+			Name:           "test server errors recorded",
+			Path:           "/demos/broken",
+			ExpectedName:   "GET /demos/{id}",
+			ExpectedStatus: codes.Error,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			sr := record(t)
+
+			var handlerSpan trace.SpanContext
+			router := mux.NewRouter()
+			router.Use(tracing.Middleware)
+			router.Path("/demos/{id}").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				handlerSpan = trace.SpanContextFromContext(r.Context())
+				if mux.Vars(r)["id"] == "broken" {
+					w.WriteHeader(http.StatusInternalServerError)
+				}
+			})
+
+			req := httptest.NewRequest(http.MethodGet, tc.Path, nil)
+			if tc.Traceparent != "" {
+				req.Header.Set("traceparent", tc.Traceparent)
+			}
+			router.ServeHTTP(httptest.NewRecorder(), req)
+
+			spans := sr.Ended()
+			if len(spans) != 1 {
+				t.Fatalf("got %d spans, want 1", len(spans))
+			}
+
+			s := spans[0]
+			if s.Name() != tc.ExpectedName || s.SpanKind() != trace.SpanKindServer {
+				t.Fatalf("span is %s %s, want server %s", s.SpanKind(), s.Name(), tc.ExpectedName)
+			}
+			if s.Status().Code != tc.ExpectedStatus {
+				t.Fatalf("span status = %v, want %v", s.Status().Code, tc.ExpectedStatus)
+			}
+			if (s.SpanContext().TraceID().String() == traceID) != tc.ExpectedParent {
+				t.Fatalf("span trace = %s, want parent %v", s.SpanContext().TraceID(), tc.ExpectedParent)
+			}
+			if handlerSpan.SpanID() != s.SpanContext().SpanID() {
+				t.Fatalf("handler span = %s, want %s", handlerSpan.SpanID(), s.SpanContext().SpanID())
+			}
+		})
+	}
+}
+
+func TestTransport(t *testing.T) {
+	sr := record(t)
+
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get("traceparent")
+	}))
+	defer srv.Close()
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "parent")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	if err != nil {
+		t.Fatalf("NewRequest() error = %v", err)
+	}
+
+	client := &http.Client{Transport: tracing.Transport(nil)}
+	res, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	_ = res.Body.Close()
+	parent.End()
+
+	if req.Header.Get("traceparent") != "" {
+		t.Fatalf("request headers changed: %v", req.Header)
+	}
+
+	spans := sr.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(spans))
+	}
+
+	span := spans[0]
+	if span.SpanKind() != trace.SpanKindClient || span.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Fatalf("span %s is not a client child of the request span", span.Name())
+	}
+	if !strings.Contains(got, span.SpanContext().SpanID().String()) {
+		t.Fatalf("traceparent = %q, want span %s", got, span.SpanContext().SpanID())
+	}
+}
+
+func TestMessaging(t *testing.T) {
+	sr := record(t)
+
+	// The trace of the change writing an event is continued by its
+	// publication, then by the processing of the message, along with the
+	// request id.
+	headers := map[string]string{"traceparent": traceparent}
+	ctx := requestid.NewContext(context.Background(), "req-1")
+
+	_, publish := tracing.StartPublish(ctx, "demo-events", headers)
+	tracing.End(publish, nil)
+
+	if headers["traceparent"] == traceparent {
+		t.Fatalf("traceparent not replaced by the publish span")
+	}
+	if headers[requestid.Header] != "req-1" {
+		t.Fatalf("headers = %v, want the request id", headers)
+	}
+
+	pctx, process := tracing.StartProcess(context.Background(), "demo-events", headers)
+	tracing.End(process, errors.New("handler failed"))
+
+	if id := requestid.FromContext(pctx); id != "req-1" {
+		t.Fatalf("process request id = %q, want req-1", id)
+	}
+
+	spans := sr.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(spans))
+	}
+
+	pub, proc := spans[0], spans[1]
+	if pub.SpanContext().TraceID().String() != traceID || proc.SpanContext().TraceID().String() != traceID {
+		t.Fatalf("spans left trace %s", traceID)
+	}
+	if proc.Parent().SpanID() != pub.SpanContext().SpanID() {
+		t.Fatalf("process span parent = %s, want %s", proc.Parent().SpanID(), pub.SpanContext().SpanID())
+	}
+	if proc.Status().Code != codes.Error || len(proc.Events()) != 1 {
+		t.Fatalf("process span error not recorded: %v", proc.Status())
+	}
+	for _, s := range spans {
+		if !hasAttribute(s, requestid.Field, "req-1") {
+			t.Fatalf("span %s attributes = %v, want the request id", s.Name(), s.Attributes())
+		}
+	}
+}
+
+func hasAttribute(s sdktrace.ReadOnlySpan, key, value string) bool {
+	for _, kv := range s.Attributes() {
+		if string(kv.Key) == key && kv.Value.AsString() == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+func TestNew(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "spans.json")
+
+	tt := []struct {
+		Name          string
+		Config        tracing.Config
+		ExpectedError error
+	}{
+		{
+			Name:   "test no provider",
+			Config: tracing.Config{},
+		},
+		{
+			Name:   "test otel file exporter",
+			Config: tracing.Config{Provider: tracing.ProviderOTel, Exporter: tracing.ExporterFile, File: file, Service: "demo"},
+		},
+		{
+			Name:          "test unknown provider",
+			Config:        tracing.Config{Provider: "zipkin"},
+			ExpectedError: tracing.ErrUnknownProvider,
+		},
+		{
+			Name:          "test unknown exporter",
+			Config:        tracing.Config{Provider: tracing.ProviderOTel, Exporter: "jaeger"},
+			ExpectedError: tracing.ErrUnknownProvider,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			tp, err := tracing.New(tc.Config)
+			if tc.ExpectedError != nil {
+				if !errors.Is(err, tc.ExpectedError) {
+					t.Fatalf("New() error = %v, want %v", err, tc.ExpectedError)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+
+			_, span := tracing.Tracer("test").Start(context.Background(), "work")
+			span.End()
+
+			if err := tp.Shutdown(context.Background()); err != nil {
+				t.Fatalf("Shutdown() error = %v", err)
+			}
+		})
+	}
+
+	b, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("reading spans: %v", err)
+	}
+	if !strings.Contains(string(b), `"Name":"work"`) || !strings.Contains(string(b), `"Value":"demo"`) {
+		t.Fatalf("file exporter wrote %s", b)
+	}
+}
@@ -0,0 +1,10 @@
+// Package schemas embeds the versioned schemas of the messages exchanged
+// by the service, one directory per subject, see codec.LoadFS.
+package schemas
+
+import "embed"
+
+// FS holds the schema files.
+//
+//go:embed */*
+var FS embed.FS
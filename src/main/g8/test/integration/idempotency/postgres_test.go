@@ -0,0 +1,15 @@
+package idempotency_test
+
+import (
+	"testing"
+
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/idempotency"
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/idempotency/storetest"
+	"github.com/gympass/$name;format="lower,hyphen"$/test/testutils"
+)
+
+func TestPostgresStore(t *testing.T) {
+	db := testutils.OpenDB(t)
+
+	storetest.Run(t, idempotency.NewPostgresStore(db.DB))
+}
@@ -0,0 +1,30 @@
+package micro_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gympass/$name;format="lower,hyphen"$/internal/micro"
+	"github.com/gympass/$name;format="lower,hyphen"$/internal/micro/storetest"
+	"github.com/gympass/$name;format="lower,hyphen"$/test/testutils"
+)
+
+func TestRepository(t *testing.T) {
+	db := testutils.OpenDB(t)
+
+	storetest.Run(t, func(t *testing.T, seed ...micro.Demo) micro.Store {
+		ctx := context.Background()
+		if _, err := db.ExecContext(ctx, `DELETE FROM demo`); err != nil {
+			t.Fatalf("emptying demo: %v", err)
+		}
+
+		repo := micro.NewRepository(db.DB)
+		for _, d := range seed {
+			if err := repo.CreateDemo(ctx, d); err != nil {
+				t.Fatalf("seeding demo %s: %v", d.ID, err)
+			}
+		}
+
+		return repo
+	})
+}
@@ -0,0 +1,125 @@
+package outbox_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Gympass/gcore/v3/glog"
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/outbox"
+	"github.com/gympass/$name;format="lower,hyphen"$/test/testutils"
+)
+
+func TestPostgresStore(t *testing.T) {
+	ctx := context.Background()
+	db := testutils.OpenDB(t)
+
+	if _, err := db.ExecContext(ctx, `DELETE FROM outbox`); err != nil {
+		t.Fatalf("cleaning outbox: %v", err)
+	}
+
+	var events []outbox.Event
+	for _, key := range []string{"a", "b", "c"} {
+		e, err := outbox.NewEvent("demo-events", key, "demo.created", map[string]string{"id": key})
+		if err != nil {
+			t.Fatalf("NewEvent() error = %v", err)
+		}
+		e.Headers = map[string]string{"source": "test"}
+		events = append(events, e)
+	}
+
+	if err := outbox.Enqueue(ctx, db, events...); err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+
+	store := outbox.NewPostgresStore(db.DB)
+
+	st, err := store.Stats(ctx)
+	if err != nil {
+		t.Fatalf("Stats() error = %v", err)
+	}
+	if st.Pending != len(events) || st.OldestPending.IsZero() {
+		t.Fatalf("Stats() = %+v, want %d pending", st, len(events))
+	}
+
+	pub := outbox.NewMemoryPublisher()
+	relay := outbox.NewRelay(outbox.Config{
+		Store:     store,
+		Publisher: pub,
+		Logger:    glog.Noop(),
+	})
+
+	n, err := relay.Flush(ctx)
+	if err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	if n != len(events) {
+		t.Fatalf("Flush() sent %d events, want %d", n, len(events))
+	}
+
+	for i, e := range pub.Events() {
+		if e.Key != events[i].Key || e.Headers["source"] != "test" {
+			t.Fatalf("event %d is %+v, want key %s with headers", i, e, events[i].Key)
+		}
+	}
+
+	if st, err = store.Stats(ctx); err != nil {
+		t.Fatalf("Stats() error = %v", err)
+	}
+	if st.Pending != 0 || !st.OldestPending.IsZero() {
+		t.Fatalf("%d events left in the outbox", st.Pending)
+	}
+}
+
+func TestPostgresStoreLease(t *testing.T) {
+	ctx := context.Background()
+	db := testutils.OpenDB(t)
+
+	if _, err := db.ExecContext(ctx, `DELETE FROM outbox`); err != nil {
+		t.Fatalf("cleaning outbox: %v", err)
+	}
+	e, err := outbox.NewEvent("demo-events", "a", "demo.created", map[string]string{"id": "a"})
+	if err != nil {
+		t.Fatalf("NewEvent() error = %v", err)
+	}
+	if err := outbox.Enqueue(ctx, db, e); err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+
+	store := outbox.NewPostgresStore(db.DB)
+
+	first, err := store.Claim(ctx, 10)
+	if err != nil {
+		t.Fatalf("Claim() error = %v", err)
+	}
+	if len(first.Events()) != 1 {
+		t.Fatalf("Claim() returned %d events, want 1", len(first.Events()))
+	}
+
+	// The claim is committed, other relays see the lease without waiting.
+	second, err := store.Claim(ctx, 10)
+	if err != nil {
+		t.Fatalf("Claim() error = %v", err)
+	}
+	if len(second.Events()) != 0 {
+		t.Fatalf("Claim() returned %d events during the lease, want none", len(second.Events()))
+	}
+	if err := second.Close(ctx); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	if err := first.Sent(ctx, first.Events()[0]); err != nil {
+		t.Fatalf("Sent() error = %v", err)
+	}
+	if err := first.Close(ctx); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	third, err := store.Claim(ctx, 10)
+	if err != nil {
+		t.Fatalf("Claim() error = %v", err)
+	}
+	defer third.Close(ctx)
+	if len(third.Events()) != 0 {
+		t.Fatalf("Claim() returned %d events once sent, want none", len(third.Events()))
+	}
+}
@@ -0,0 +1,48 @@
+// Package testutils holds helpers shared by the integration tests.
+package testutils
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gympass/$name;format="lower,hyphen"$/pkg/postgres"
+)
+
+// OpenDB connects to the test database started by `make test-infra-up`.
+// The DATABASE_* variables override the docker-compose defaults, and the
+// test is skipped when the server cannot be reached.
+func OpenDB(t *testing.T) *postgres.DB {
+	t.Helper()
+
+	db, err := postgres.Open(postgres.Config{
+		Host:           env("DATABASE_HOST", "localhost"),
+		Port:           env("DATABASE_PORT", "5432"),
+		User:           env("DATABASE_USER", "postgres"),
+		Pass:           env("DATABASE_PASS", "docker"),
+		Database:       env("DATABASE_NAME", "testdb"),
+		ConnectTimeout: 5 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("postgres.Open() error = %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := db.PingContext(ctx); err != nil {
+		t.Skipf("postgres is not reachable, run make test-infra-up: %v", err)
+	}
+
+	return db
+}
+
+func env(key, fallback string) string {
+	if v, ok := os.LookupEnv(key); ok {
+		return v
+	}
+
+	return fallback
+}